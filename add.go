@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"geminivectortest/ragbot"
+)
+
+// Read the pairs to add from a JSON file holding one {"input", "output"} object or an array of them
+func readAddFile(filename string) ([]ragbot.InputOutputPair, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", filename, err)
+	}
+	var one struct {
+		Input  string          `json:"input"`
+		Output json.RawMessage `json:"output"`
+	}
+	if err := json.Unmarshal(data, &one); err == nil {
+		outputs, err := parseOutputs(one.Output)
+		if err != nil {
+			return nil, fmt.Errorf("%s: field \"output\" %v", filename, err)
+		}
+		pair := ragbot.InputOutputPair{Input: one.Input, Output: outputs[0]}
+		if len(outputs) > 1 {
+			pair.Outputs = outputs
+		}
+		return []ragbot.InputOutputPair{pair}, nil
+	}
+	return parsePairs(filename, data)
+}
+
+// Embed and upload one or a few new pairs without re-processing the whole set
+func runAdd(args []string) {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	input := fs.String("input", "", "input of the pair to add")
+	output := fs.String("output", "", "output of the pair to add")
+	file := fs.String("json", "", `JSON file with a {"input": ..., "output": ...} object or an array of them`)
+	replace := fs.Bool("replace", false, "overwrite the outputs already stored for an input instead of adding to them")
+	parseFlags(fs, args)
+
+	var pairs []ragbot.InputOutputPair
+	switch {
+	case *file != "" && (*input != "" || *output != ""):
+		fmt.Fprintln(os.Stderr, "use either --json or --input/--output, not both")
+		os.Exit(2)
+	case *file != "":
+		var err error
+		if pairs, err = readAddFile(*file); err != nil {
+			logger.Error("add failed", "err", err)
+			os.Exit(1)
+		}
+	case *input != "" && *output != "":
+		pairs = []ragbot.InputOutputPair{{Input: *input, Output: *output}}
+	default:
+		fmt.Fprintln(os.Stderr, "usage: chatbot add [--replace] --input <text> --output <text> | --json <file>")
+		os.Exit(2)
+	}
+
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	add := bot.Add
+	if *replace {
+		add = bot.Replace
+	}
+	failed := false
+	for _, pair := range pairs {
+		results, err := add(ctx, pair)
+		for _, r := range results {
+			switch {
+			case r.Existed:
+				logger.Info("⏭️ Already indexed", "dim", r.Dimension, "id", r.ID, "input", pair.Input)
+			case r.Merged:
+				logger.Info("🔗 Added to the stored answers", "dim", r.Dimension, "id", r.ID, "input", pair.Input)
+			case r.Replaced:
+				logger.Info("✏️ Replaced the answer", "dim", r.Dimension, "id", r.ID, "input", pair.Input)
+			default:
+				logger.Info("➕ Added pair", "dim", r.Dimension, "id", r.ID, "input", pair.Input)
+			}
+		}
+		if err != nil {
+			logger.Error("failed to add pair", "input", pair.Input, "err", err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// Exit codes of ask
+const (
+	askNotConfident = 1 // the fallback or clarifying answer was printed
+	askFailed       = 3 // no answer: the search failed or the config is invalid
+)
+
+// Print only the answer to one query, for scripts: the chosen output when a match is
+// confident, otherwise the fallback with exit code 1. Logs below warn are off unless
+// --log-level asks for them, and go to stderr either way.
+func runAsk(args []string) {
+	fs := flag.NewFlagSet("ask", flag.ExitOnError)
+	fs.Func("var", "template variable for the answer as name=value, e.g. driverName=Asha (repeatable)", parseAnswerVar)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `usage: chatbot ask [flags] "<query>"`)
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, append([]string{"-log-level=warn"}, args...))
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if !loadConfig() {
+		os.Exit(askFailed)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	resp, err := bot.AnswerWith(ctx, query, answerVars)
+	if err != nil {
+		logger.Error("ask failed", "err", err)
+		os.Exit(askFailed)
+	}
+	fmt.Println(resp.Answer)
+	if !resp.Confident {
+		os.Exit(askNotConfident)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Run newline-delimited queries through the search pipeline, streaming one JSON result
+// per line (JSONL) to w as each query completes. Every line is flushed before the next
+// query starts, so consumers can follow the run and nothing accumulates in memory.
+// A query that fails gets a line with an "error" field. Blank lines and lines starting
+// with # are skipped.
+func runBatch(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+	count, failed := 0, 0
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		query := strings.TrimSpace(scanner.Text())
+		if query == "" || strings.HasPrefix(query, "#") {
+			continue
+		}
+		result, err := jsonResponse(ctx, query)
+		if result.Error != "" {
+			logger.Warn("query failed", "query", query, "err", err)
+			failed++
+		}
+		if err := enc.Encode(result); err != nil {
+			return fmt.Errorf("failed to write result: %v", err)
+		}
+		if err := out.Flush(); err != nil {
+			return fmt.Errorf("failed to write result: %v", err)
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read queries: %v", err)
+	}
+
+	logger.Info("batch complete", "queries", count, "failed", failed)
+	return nil
+}
+
+// Open the batch query source: the named file, or stdin when the name is empty or "-"
+func openQueries(filename string) (io.ReadCloser, error) {
+	if filename == "" || filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queries file: %v", err)
+	}
+	return f, nil
+}
+
+// Open the batch result destination: the named file, or stdout when the name is empty or "-".
+// The file is written line by line rather than atomically so it can be followed while the run goes on.
+func createBatchOutput(filename string) (io.WriteCloser, error) {
+	if filename == "" || filename == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %v", err)
+	}
+	return f, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
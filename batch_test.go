@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// bytes.Buffer noting each Write call, to check what reached it with every flush
+type recordingWriter struct {
+	bytes.Buffer
+	writes []string
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return w.Buffer.Write(p)
+}
+
+func TestRunBatchWritesOneLinePerQuery(t *testing.T) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	useServices(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			if bytes.Contains(body, []byte("explode")) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"message":"invalid input"}}`))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fakeGemini(w, r)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"matches":[{"id":"cab","score":0.9,"metadata":{"input":"Where is my cab","output":"On its way"}}]}`))
+		})
+
+	out := &recordingWriter{}
+	queries := "where is my cab\n\n# a comment\nexplode\ncancel my ride\n"
+	if err := runBatch(context.Background(), strings.NewReader(queries), out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.SplitAfter(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || len(out.writes) != 3 {
+		t.Fatalf("%d lines in %d writes %q, want one flushed line per query", len(lines), len(out.writes), out.writes)
+	}
+	for i, want := range []struct {
+		query string
+		fails bool
+	}{{"where is my cab", false}, {"explode", true}, {"cancel my ride", false}} {
+		if !strings.HasSuffix(out.writes[i], "\n") || strings.Count(out.writes[i], "\n") != 1 {
+			t.Errorf("write %d is not one whole line: %q", i, out.writes[i])
+		}
+		var result JSONQueryResult
+		if err := json.Unmarshal([]byte(lines[i]), &result); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if result.Query != want.query || (result.Error != "") != want.fails {
+			t.Errorf("line %d: query %q, error %q; want %q failing %v", i, result.Query, result.Error, want.query, want.fails)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"geminivectortest/ragbot"
+)
+
+// Latencies measured for one dimension
+type benchTimings struct {
+	Embed []time.Duration
+	Query []time.Duration
+	Total []time.Duration
+}
+
+// Nearest-rank percentile of durations; sorts them in place
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	rank := int(math.Ceil(p/100*float64(len(durations)))) - 1
+	return durations[max(rank, 0)]
+}
+
+// Non-empty lines of a queries file
+func readQueryLines(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open queries file: %v", err)
+	}
+	defer f.Close()
+
+	var queries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			queries = append(queries, line)
+		}
+	}
+	return queries, scanner.Err()
+}
+
+// Run every query runs times at one dimension, timing the embedding and the Pinecone
+// query separately. Failed attempts are counted and left out of the timings.
+func benchDimension(ctx context.Context, queries []string, dim, runs, topK int) (*benchTimings, int) {
+	t := &benchTimings{}
+	failed := 0
+	for run := 0; run < runs && ctx.Err() == nil; run++ {
+		for _, q := range queries {
+			start := time.Now()
+			vector, err := bot.Embed(ctx, q, dim, ragbot.TaskRetrievalQuery)
+			embedded := time.Now()
+			if err != nil {
+				logger.Warn("embedding failed", "dim", dim, "query", q, "err", err)
+				failed++
+				continue
+			}
+			if _, err := bot.QueryVector(ctx, vector, dim, topK); err != nil {
+				logger.Warn("query failed", "dim", dim, "query", q, "err", err)
+				failed++
+				continue
+			}
+			done := time.Now()
+			t.Embed = append(t.Embed, embedded.Sub(start))
+			t.Query = append(t.Query, done.Sub(embedded))
+			t.Total = append(t.Total, done.Sub(start))
+		}
+	}
+	return t, failed
+}
+
+func printBenchTable(w io.Writer, dims []int, timings map[int]*benchTimings, failures map[int]int) {
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond)) }
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "dim\truns\tfailed\tembed p50\tembed p95\tquery p50\tquery p95\ttotal p50\ttotal p95\t")
+	for _, dim := range dims {
+		t := timings[dim]
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t\n", dim, len(t.Total), failures[dim],
+			ms(percentile(t.Embed, 50)), ms(percentile(t.Embed, 95)),
+			ms(percentile(t.Query, 50)), ms(percentile(t.Query, 95)),
+			ms(percentile(t.Total, 50)), ms(percentile(t.Total, 95)))
+	}
+	tw.Flush()
+}
+
+// Compare end-to-end retrieval latency across dimensions
+func runBench(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	runs := fs.Int("runs", 5, "times to run each query against each dimension")
+	queriesFile := fs.String("queries-file", "", "file with one query per line (default: the canned test queries)")
+	dimension := fs.Int("dim", 0, "only benchmark this dimension (default: query_dimensions from the config)")
+	parseFlags(fs, args)
+
+	if *runs < 1 {
+		logger.Error("--runs must be at least 1", "runs", *runs)
+		os.Exit(2)
+	}
+
+	// Every call must reach Gemini, or cached runs would hide the embedding latency
+	configOverrides = append(configOverrides, func(c *ragbot.Config) {
+		c.EmbeddingCacheDir = ""
+		if *dimension != 0 {
+			c.QueryDimensions = []int{*dimension}
+		}
+	})
+	if !loadConfig() {
+		return
+	}
+
+	queries := sampleQueries
+	if *queriesFile != "" {
+		var err error
+		if queries, err = readQueryLines(*queriesFile); err != nil {
+			logger.Error("bench failed", "err", err)
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Queries run one at a time so each timing is a single uncontended call
+	dims := cfg.SearchDimensions()
+	timings := map[int]*benchTimings{}
+	failures := map[int]int{}
+	for _, dim := range dims {
+		logger.Info("⏱️ Benchmarking dimension", "dim", dim, "queries", len(queries), "runs", *runs)
+		timings[dim], failures[dim] = benchDimension(ctx, queries, dim, *runs, cfg.TopK)
+	}
+
+	fmt.Println()
+	printBenchTable(os.Stdout, dims, timings, failures)
+	if ctx.Err() != nil {
+		os.Exit(130)
+	}
+}
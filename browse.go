@@ -0,0 +1,324 @@
+//go:build tui
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"geminivectortest/ragbot"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// One stored pair in the browser and what the user wants done with it
+type browseItem struct {
+	vector  ragbot.Vector
+	deleted bool
+	output  string // edited output, "" when unchanged
+}
+
+func (it *browseItem) pair() ragbot.InputOutputPair {
+	return it.vector.Pair()
+}
+
+// Terminal UI over one index: a searchable list of pairs, the selected pair's details,
+// and pending deletes and edits that are only applied after confirmation
+type browser struct {
+	app    *tview.Application
+	pages  *tview.Pages
+	search *tview.InputField
+	table  *tview.Table
+	detail *tview.TextView
+	status *tview.TextView
+
+	items []*browseItem
+	shown []*browseItem // items matching the search, in table order
+	apply bool          // set when the user confirmed the pending changes
+}
+
+func newBrowser(items []*browseItem) *browser {
+	br := &browser{
+		app:    tview.NewApplication(),
+		pages:  tview.NewPages(),
+		search: tview.NewInputField().SetLabel("Search: "),
+		table:  tview.NewTable().SetSelectable(true, false),
+		detail: tview.NewTextView().SetWrap(true).SetDynamicColors(false),
+		status: tview.NewTextView(),
+		items:  items,
+	}
+	br.table.SetBorder(true).SetTitle(" Pairs ")
+	br.detail.SetBorder(true).SetTitle(" Pair ")
+
+	br.search.SetChangedFunc(func(string) { br.refresh() })
+	br.search.SetDoneFunc(func(tcell.Key) { br.app.SetFocus(br.table) })
+	br.table.SetSelectionChangedFunc(func(row, _ int) { br.showDetail(row) })
+	br.table.SetInputCapture(br.handleKey)
+
+	body := tview.NewFlex().
+		AddItem(br.table, 0, 1, true).
+		AddItem(br.detail, 0, 1, false)
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(br.search, 1, 0, false).
+		AddItem(body, 0, 1, true).
+		AddItem(br.status, 1, 0, false)
+	br.pages.AddPage("main", layout, true, true)
+	br.app.SetRoot(br.pages, true).SetFocus(br.table)
+	br.refresh()
+	return br
+}
+
+// Rebuild the table from the items matching the search text
+func (br *browser) refresh() {
+	needle := strings.ToLower(strings.TrimSpace(br.search.GetText()))
+	br.shown = br.shown[:0]
+	for _, it := range br.items {
+		p := it.pair()
+		if needle == "" || strings.Contains(strings.ToLower(p.Input), needle) || strings.Contains(strings.ToLower(p.Output), needle) {
+			br.shown = append(br.shown, it)
+		}
+	}
+
+	br.table.Clear()
+	for row, it := range br.shown {
+		mark := " "
+		switch {
+		case it.deleted:
+			mark = "D"
+		case it.output != "":
+			mark = "E"
+		}
+		br.table.SetCell(row, 0, tview.NewTableCell(mark))
+		br.table.SetCell(row, 1, tview.NewTableCell(it.pair().Input).SetExpansion(1))
+	}
+	if len(br.shown) > 0 {
+		row, _ := br.table.GetSelection()
+		br.table.Select(min(row, len(br.shown)-1), 0)
+		br.showDetail(min(row, len(br.shown)-1))
+	} else {
+		br.detail.SetText("")
+	}
+	br.updateStatus()
+}
+
+func (br *browser) showDetail(row int) {
+	if row < 0 || row >= len(br.shown) {
+		return
+	}
+	it := br.shown[row]
+	m := it.vector.Metadata
+	text := fmt.Sprintf("ID: %s\nIntent: %s\nPair: %d\n\nInput:\n%s\n\nOutput:\n%s\n", it.vector.ID, m.Intent, m.PairID, m.Input, m.Output)
+	if it.output != "" {
+		text += "\nEdited output:\n" + it.output + "\n"
+	}
+	if it.deleted {
+		text += "\nMarked for deletion\n"
+	}
+	br.detail.SetText(text).ScrollToBeginning()
+}
+
+func (br *browser) updateStatus() {
+	deletes, edits := br.pending()
+	br.status.SetText(fmt.Sprintf(" %d/%d pairs | %d to delete, %d edited | / search  d delete  e edit  a apply  q quit",
+		len(br.shown), len(br.items), deletes, edits))
+}
+
+func (br *browser) pending() (deletes, edits int) {
+	for _, it := range br.items {
+		if it.deleted {
+			deletes++
+		} else if it.output != "" {
+			edits++
+		}
+	}
+	return deletes, edits
+}
+
+func (br *browser) selected() *browseItem {
+	row, _ := br.table.GetSelection()
+	if row < 0 || row >= len(br.shown) {
+		return nil
+	}
+	return br.shown[row]
+}
+
+func (br *browser) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case '/':
+		br.app.SetFocus(br.search)
+		return nil
+	case 'd':
+		if it := br.selected(); it != nil {
+			it.deleted = !it.deleted
+			br.refresh()
+		}
+		return nil
+	case 'e':
+		if it := br.selected(); it != nil {
+			br.edit(it)
+		}
+		return nil
+	case 'a':
+		br.confirm("Apply %d deletes and %d edits to every index?", "Apply", func() {
+			br.apply = true
+			br.app.Stop()
+		})
+		return nil
+	case 'q':
+		if deletes, edits := br.pending(); deletes+edits > 0 {
+			br.confirm("Quit and discard %d deletes and %d edits?", "Discard", br.app.Stop)
+			return nil
+		}
+		br.app.Stop()
+		return nil
+	}
+	return event
+}
+
+// Modal asking to confirm the pending changes; format gets the delete and edit counts
+func (br *browser) confirm(format, action string, then func()) {
+	deletes, edits := br.pending()
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf(format, deletes, edits)).
+		AddButtons([]string{action, "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			br.pages.RemovePage("confirm")
+			br.app.SetFocus(br.table)
+			if label == action {
+				then()
+			}
+		})
+	br.pages.AddPage("confirm", modal, true, true)
+}
+
+// Form editing the output of a pair
+func (br *browser) edit(it *browseItem) {
+	current := it.output
+	if current == "" {
+		current = it.pair().Output
+	}
+	form := tview.NewForm().AddTextArea("Output", current, 0, 8, 0, nil)
+	back := func() {
+		br.pages.RemovePage("edit")
+		br.app.SetFocus(br.table)
+	}
+	form.AddButton("Save", func() {
+		text := strings.TrimSpace(form.GetFormItemByLabel("Output").(*tview.TextArea).GetText())
+		if text == it.pair().Output {
+			text = ""
+		}
+		it.output = text
+		back()
+		br.refresh()
+	})
+	form.AddButton("Cancel", back)
+	form.SetCancelFunc(back)
+	form.SetBorder(true).SetTitle(" Edit output: " + it.pair().Input + " ")
+	br.pages.AddPage("edit", form, true, true)
+}
+
+// IDs of the pair's vectors per dimension. Content-based IDs are derived for every index;
+// a vector under any other ID scheme is only known in the browsed index.
+func vectorIDsByDimension(v ragbot.Vector, dim int) map[int]string {
+	if v.ID != ragbot.VectorID(v.Pair(), dim) {
+		return map[int]string{dim: v.ID}
+	}
+	ids := map[int]string{}
+	for _, d := range cfg.Dimensions() {
+		ids[d] = ragbot.VectorID(v.Pair(), d)
+	}
+	return ids
+}
+
+// Delete marked pairs and replace edited ones in every index. An edited pair is written
+// before any old vectors under other IDs are deleted, so a failure never leaves it missing.
+func applyBrowseChanges(ctx context.Context, dim int, items []*browseItem) error {
+	byDim := map[int][]string{}
+	for _, it := range items {
+		if !it.deleted && it.output == "" {
+			continue
+		}
+		if !it.deleted {
+			edited := ragbot.InputOutputPair{Input: it.pair().Input, Output: it.output}
+			if outputs := it.pair().Outputs; len(outputs) > 1 {
+				// Only the first response is edited; keep the others
+				edited.Outputs = append([]string{it.output}, outputs[1:]...)
+			}
+			if _, err := bot.Replace(ctx, edited); err != nil {
+				return fmt.Errorf("failed to replace edited pair %q: %v", edited.Input, err)
+			}
+		}
+		for d, id := range vectorIDsByDimension(it.vector, dim) {
+			// The edit was written under the input's ID, so only an old ID is deleted
+			if it.deleted || id != ragbot.VectorID(it.pair(), d) {
+				byDim[d] = append(byDim[d], id)
+			}
+		}
+	}
+
+	for d, ids := range byDim {
+		if err := bot.Delete(ctx, ids, d); err != nil {
+			return fmt.Errorf("dimension %d: failed to delete vectors: %v", d, err)
+		}
+		logger.Info("🧹 Deleted vectors", "dim", d, "count", len(ids))
+	}
+	return nil
+}
+
+// Browse, search and edit the pairs in one index in a terminal UI
+func runBrowse(args []string) {
+	fs := flag.NewFlagSet("browse", flag.ExitOnError)
+	dimFlag := fs.Int("dim", 0, "dimension of the index to browse (default: the largest configured)")
+	parseFlags(fs, args)
+
+	if !loadConfig() {
+		return
+	}
+	dims := cfg.Dimensions()
+	dim := dims[len(dims)-1]
+	if *dimFlag != 0 {
+		if _, ok := cfg.Indexes[*dimFlag]; !ok {
+			logger.Error("no index configured for dimension", "dim", *dimFlag, "indexes", cfg.Indexes)
+			os.Exit(2)
+		}
+		dim = *dimFlag
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var items []*browseItem
+	err := bot.Scan(ctx, dim, func(page []ragbot.Vector) error {
+		for _, v := range page {
+			v.Values = nil // only metadata is shown; don't hold every vector in memory
+			items = append(items, &browseItem{vector: v})
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error("failed to read pairs", "index", cfg.Indexes[dim], "err", err)
+		os.Exit(1)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].pair().Input < items[j].pair().Input })
+
+	br := newBrowser(items)
+	if err := br.app.Run(); err != nil {
+		logger.Error("browse failed", "err", err)
+		os.Exit(1)
+	}
+	if !br.apply {
+		return
+	}
+	if err := applyBrowseChanges(ctx, dim, items); err != nil {
+		logger.Error("failed to apply changes", "err", err)
+		os.Exit(1)
+	}
+	deletes, edits := br.pending()
+	logger.Info("✅ Changes applied", "deleted", deletes, "edited", edits)
+}
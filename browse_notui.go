@@ -0,0 +1,14 @@
+//go:build !tui
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// The terminal UI pulls in tview, so it is only built with -tags tui
+func runBrowse(args []string) {
+	fmt.Fprintln(os.Stderr, "browse needs the terminal UI: rebuild with go build -tags tui")
+	os.Exit(2)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Where an interrupted upload stopped. Pairs are processed in file order, so
+// PartialDimension holds vectors for pairs [0, NextPair) and none after.
+type Checkpoint struct {
+	Timestamp           time.Time `json:"timestamp"`
+	SourceFile          string    `json:"source_file"`
+	TotalPairs          int       `json:"total_pairs"`
+	CompletedDimensions []int     `json:"completed_dimensions"`
+	PartialDimension    int       `json:"partial_dimension,omitempty"`
+	NextPair            int       `json:"next_pair,omitempty"`
+	PartialUploaded     int       `json:"partial_uploaded,omitempty"`
+}
+
+// Write the checkpoint to output_logs/checkpoint_<ts>.json and return its path
+func writeCheckpoint(cp Checkpoint) (string, error) {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+
+	filename := fmt.Sprintf("output_logs/checkpoint_%d.json", cp.Timestamp.Unix())
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", filename, err)
+	}
+	return filename, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Chat with the bot line by line until "quit" or EOF, answering with the best match
+func runConversation(ctx context.Context, dimension, topK int) {
+	session := bot.NewConversation(dimension, topK)
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Println("💬 Chatbot Conversation Mode")
+	fmt.Println("Type your messages ('reset' to clear context, 'quit' to exit):")
+
+	for {
+		fmt.Print("\n> ")
+		if !scanner.Scan() {
+			break
+		}
+		input := strings.TrimSpace(scanner.Text())
+		switch input {
+		case "":
+			continue
+		case "quit":
+			fmt.Println("👋 Goodbye!")
+			return
+		case "reset":
+			session.Reset()
+			fmt.Println("🔄 Context cleared")
+			continue
+		}
+
+		result, err := session.Ask(ctx, input)
+		if err != nil {
+			fmt.Printf("❌ Error: %v\n", err)
+			continue
+		}
+		if len(result.Matches) == 0 || !cfg.MeetsThreshold(dimension, result.Matches[0].Score) {
+			fmt.Printf("🤖 %s\n", cfg.FallbackAnswer)
+			continue
+		}
+
+		best := result.Matches[0]
+		fmt.Printf("🤖 %s\n", best.Metadata.Output)
+		fmt.Printf("   (score %.3f, matched %q)\n", best.Score, best.Metadata.Input)
+	}
+
+	fmt.Println("👋 Goodbye!")
+}
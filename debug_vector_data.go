@@ -1,128 +1,182 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"math"
 	"os"
+	"os/signal"
+	"strings"
 
-	"github.com/joho/godotenv"
+	"geminivectortest/ragbot"
 )
 
-var (
-	geminiAPIKey   = os.Getenv("GEMINI_API_KEY")
-	pineconeAPIKey = os.Getenv("PINECONE_API_KEY")
-	pineconeEnv1   = map[string]string{
-		"chatbot-embeddings-384-2x9jann":  "aped-4627-b74a",
-		"chatbot-embeddings-512-2x9jann":  "aped-4627-b74a",
-		"chatbot-embeddings-1024-2x9jann": "aped-4627-b74a",
-	}
-	indexes = map[int]string{
-		384:  "chatbot-embeddings-384-2x9jann",
-		512:  "chatbot-embeddings-512-2x9jann",
-		1024: "chatbot-embeddings-1024-2x9jann",
-	}
-)
+// Number of vectors logged individually per index; the rest are only checked
+const diagnoseShowVectors = 20
 
-type QueryResult struct {
-	Matches []struct {
-		ID       string  `json:"id"`
-		Score    float32 `json:"score"`
-		Metadata struct {
-			Input     string `json:"input"`
-			Output    string `json:"output"`
-			Dimension int    `json:"dimension"`
-		} `json:"metadata"`
-	} `json:"matches"`
+// Problem categories diagnose can filter on, with the CheckPair error for each
+var diagnoseCategories = map[string]error{
+	"missing-metadata":       ragbot.ErrMissingField,
+	"identical-input-output": ragbot.ErrSameInputOutput,
+	"corrupted":              ragbot.ErrConcatenatedPair,
 }
 
-func diagnoseIndex(dimension int) error {
-	indexName := indexes[dimension]
-	pineconeEnv := pineconeEnv1[indexName]
-	url := fmt.Sprintf("https://%s.svc.%s.pinecone.io/query", indexName, pineconeEnv)
-
-	fmt.Printf("\n🔍 Checking index: %s (%dD)\n", indexName, dimension)
-	fmt.Println("----------------------------------------------------------")
+// Which vectors diagnose shows. With no category, score or time filter the first
+// diagnoseShowVectors vectors are shown; otherwise every vector that passes.
+type diagnoseFilter struct {
+	Categories map[string]bool // empty shows all categories, including clean vectors
+	Probe      []float32       // query embedding scored against each vector, nil without --query
+	MinScore   float64
+	MaxScore   float64
+	Created    ragbot.TimeRange // only vectors created within; counts cover these vectors too
+}
 
-	// Send a zero-vector to retrieve everything
-	zeroVector := make([]float32, dimension)
+func (f diagnoseFilter) active() bool {
+	return len(f.Categories) > 0 || f.Probe != nil || !f.Created.IsZero()
+}
 
-	payload := map[string]interface{}{
-		"vector":          zeroVector,
-		"topK":            100,
-		"includeMetadata": true,
-		"namespace":       "chatbot-training-data",
+// Category of a vector's metadata problem, "" when it looks fine
+func diagnoseCategory(pair ragbot.InputOutputPair) (string, error) {
+	err := ragbot.CheckPair(pair)
+	if err == nil {
+		return "", nil
 	}
+	for name, target := range diagnoseCategories {
+		if errors.Is(err, target) {
+			return name, err
+		}
+	}
+	return "corrupted", err
+}
 
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
-	req.Header.Set("Api-Key", pineconeAPIKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	res, err := http.DefaultClient.Do(req)
+// Scan every stored vector of one index, flag suspicious metadata and report the true total
+// and the count of each problem category
+func diagnoseIndex(ctx context.Context, dimension int, filter diagnoseFilter) error {
+	indexName := cfg.Indexes[dimension]
+	logger.Info("🔍 Checking index", "index", indexName, "dim", dimension)
+
+	total, shown := 0, 0
+	counts := map[string]int{}
+	err := bot.ScanRange(ctx, dimension, filter.Created, func(page []ragbot.Vector) error {
+		for _, v := range page {
+			total++
+			pair := v.Pair()
+			category, problem := diagnoseCategory(pair)
+			if category != "" {
+				counts[category]++
+			}
+
+			if !filter.active() {
+				if total <= diagnoseShowVectors {
+					logger.Info("vector", "n", total, "id", v.ID, "input", pair.Input)
+				}
+				if problem != nil {
+					logger.Warn("corrupted metadata", "id", v.ID, "err", problem)
+				}
+				continue
+			}
+
+			if len(filter.Categories) > 0 && !filter.Categories[category] {
+				continue
+			}
+			attrs := []any{"id", v.ID, "input", pair.Input}
+			if filter.Probe != nil {
+				score, err := ragbot.CosineSimilarity(filter.Probe, v.Values)
+				if err != nil || float64(score) < filter.MinScore || float64(score) > filter.MaxScore {
+					continue
+				}
+				attrs = append(attrs, "score", score)
+			}
+			if category != "" {
+				attrs = append(attrs, "category", category, "err", problem)
+			}
+			shown++
+			logger.Info("vector", attrs...)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("❌ failed to query index: %v", err)
+		return fmt.Errorf("failed to scan index: %v", err)
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		return fmt.Errorf("❌ Pinecone API returned status %d", res.StatusCode)
+	if total == 0 {
+		if !filter.Created.IsZero() {
+			logger.Warn("no vectors created in the time range", "index", indexName, "created", filter.Created)
+			return nil
+		}
+		logger.Warn("no vectors found", "index", indexName, "namespace", cfg.NamespaceFor(dimension))
+		return nil
 	}
-
-	var result QueryResult
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return fmt.Errorf("❌ failed to decode response: %v", err)
+	if !filter.active() && total > diagnoseShowVectors {
+		logger.Info("only showed the first vectors", "shown", diagnoseShowVectors)
 	}
-
-	if len(result.Matches) == 0 {
-		fmt.Println("⚠️ No vectors found.")
-		return nil
+	flagged := 0
+	for _, n := range counts {
+		flagged += n
 	}
-
-	for i, m := range result.Matches {
-		fmt.Printf("%2d. Score: %.3f | Input: %q\n", i+1, m.Score, m.Metadata.Input)
-		if m.Metadata.Output == "" || m.Metadata.Input == "" {
-			fmt.Println("   ⚠️ WARNING: Missing input/output in metadata")
-		}
-		if m.Metadata.Input == m.Metadata.Output {
-			fmt.Println("   ⚠️ Suspicious: Input and Output are same")
-		}
-		if m.Metadata.Input == "Similar Input: System Response:" {
-			fmt.Println("   ❌ Corrupted: Looks like concatenated string")
-		}
-		if i >= 20 {
-			fmt.Printf("   ...only showing first 20 of %d vectors\n", len(result.Matches))
-			break
-		}
+	logger.Info("📊 Index checked", "index", indexName, "total", total, "flagged", flagged,
+		"missing_metadata", counts["missing-metadata"], "identical_input_output", counts["identical-input-output"],
+		"corrupted", counts["corrupted"])
+	if filter.active() {
+		logger.Info("🔎 Vectors matching the filters", "index", indexName, "shown", shown)
 	}
-
 	return nil
 }
 
-func main() {
-	err := godotenv.Load()
+// Check every index for missing or corrupted metadata
+func runDiagnose(args []string) {
+	fs := flag.NewFlagSet("diagnose", flag.ExitOnError)
+	only := fs.String("only", "", "comma-separated categories to show: missing-metadata, identical-input-output, corrupted")
+	query := fs.String("query", "", "score every vector against this query's embedding, for --min-score and --max-score")
+	minScore := fs.Float64("min-score", math.Inf(-1), "only show vectors scoring at least this against --query")
+	maxScore := fs.Float64("max-score", math.Inf(1), "only show vectors scoring at most this against --query")
+	window := addTimeRangeFlags(fs)
+	parseFlags(fs, args)
+
+	created, err := window.resolve()
 	if err != nil {
-		log.Fatalf("Error loading .env file")
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	geminiAPIKey = os.Getenv("GEMINI_API_KEY")
-	pineconeAPIKey = os.Getenv("PINECONE_API_KEY")
-	if geminiAPIKey == "" {
-		fmt.Println("❌ GEMINI_API_KEY not set")
-		return
+	filter := diagnoseFilter{Categories: map[string]bool{}, MinScore: *minScore, MaxScore: *maxScore, Created: created}
+	for _, name := range strings.Split(*only, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if _, ok := diagnoseCategories[name]; !ok {
+			fmt.Fprintf(os.Stderr, "unknown category %q (want missing-metadata, identical-input-output or corrupted)\n", name)
+			os.Exit(2)
+		}
+		filter.Categories[name] = true
+	}
+	scoreSet := !math.IsInf(*minScore, -1) || !math.IsInf(*maxScore, 1)
+	if scoreSet && *query == "" {
+		fmt.Fprintln(os.Stderr, "--min-score and --max-score need --query to score against")
+		os.Exit(2)
 	}
-	if pineconeAPIKey == "" {
-		fmt.Println("❌ PINECONE_API_KEY not set")
+
+	if !loadConfig() {
 		return
 	}
 
-	fmt.Println("🧠 Debugging Pinecone Vector Data for Issues")
-	fmt.Println("============================================")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.Info("🧠 Debugging Pinecone Vector Data for Issues")
 
-	for _, dim := range []int{384, 512, 1024} {
-		if err := diagnoseIndex(dim); err != nil {
-			fmt.Printf("❌ Error with %dD index: %v\n", dim, err)
+	for _, dim := range cfg.Dimensions() {
+		if *query != "" {
+			probe, err := bot.Embed(ctx, *query, dim, ragbot.TaskRetrievalQuery)
+			if err != nil {
+				logger.Error("diagnose failed", "dim", dim, "err", err)
+				continue
+			}
+			filter.Probe = probe
+		}
+		if err := diagnoseIndex(ctx, dim, filter); err != nil {
+			logger.Error("diagnose failed", "dim", dim, "err", err)
 		}
 	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// Delete every vector matching a metadata filter, e.g. all pairs of a retired intent.
+// Without --yes only the number of matching vectors is shown.
+func runDelete(args []string) {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	filterJSON := fs.String("filter", "", `Pinecone metadata filter as JSON, e.g. '{"intent":{"$in":["refund","billing"]}}'`)
+	intent := fs.String("intent", "", "delete the pairs of this intent; shorthand for --filter '{\"intent\":\"<name>\"}'")
+	dim := fs.Int("dim", 0, "only this dimension's index (default: every index)")
+	yes := fs.Bool("yes", false, "really delete; without it, only count the matching vectors")
+	parseFlags(fs, args)
+
+	var filter map[string]interface{}
+	switch {
+	case *filterJSON != "" && *intent != "":
+		fmt.Fprintln(os.Stderr, "use either --filter or --intent")
+		os.Exit(2)
+	case *filterJSON != "":
+		if err := json.Unmarshal([]byte(*filterJSON), &filter); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --filter: %v\n", err)
+			os.Exit(2)
+		}
+	case *intent != "":
+		filter = map[string]interface{}{"intent": *intent}
+	}
+	if len(filter) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: chatbot delete --filter <json> | --intent <name> [--dim N] [--yes]")
+		os.Exit(2)
+	}
+
+	if !loadConfig() {
+		return
+	}
+	dims := cfg.Dimensions()
+	if *dim != 0 {
+		if _, ok := cfg.Indexes[*dim]; !ok {
+			logger.Error("no index configured for dimension", "dim", *dim, "indexes", cfg.Indexes)
+			os.Exit(2)
+		}
+		dims = []int{*dim}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := false
+	for _, d := range dims {
+		if !*yes {
+			ids, err := bot.MatchFilter(ctx, filter, d)
+			if err != nil {
+				logger.Error("failed to count matching vectors", "dim", d, "err", err)
+				failed = true
+				continue
+			}
+			logger.Info("🔎 Vectors matching the filter", "index", cfg.Indexes[d], "count", len(ids))
+			continue
+		}
+
+		deleted, err := bot.DeleteByFilter(ctx, filter, d)
+		if err != nil {
+			logger.Error("failed to delete by filter", "dim", d, "err", err)
+			failed = true
+			continue
+		}
+		if deleted < 0 {
+			logger.Info("🧹 Deleted vectors matching the filter", "index", cfg.Indexes[d])
+		} else {
+			logger.Info("🧹 Deleted vectors matching the filter", "index", cfg.Indexes[d], "count", deleted)
+		}
+	}
+
+	if !*yes && !failed {
+		logger.Info("💡 Nothing deleted; re-run with --yes to delete these vectors")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"geminivectortest/ragbot"
+)
+
+// Compare the pairs in two indexes or namespaces, e.g. before and after a reindex.
+// Exits 1 when they differ, so migrations can be checked in scripts.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fromDim := fs.Int("from-dim", 1024, "dimension of the index to compare from")
+	toDim := fs.Int("to-dim", 0, "dimension of the index to compare to (default: --from-dim)")
+	fromNS := fs.String("from-ns", "", "namespace to compare from (default: namespace from the config)")
+	toNS := fs.String("to-ns", "", "namespace to compare to (default: namespace from the config)")
+	by := fs.String("by", ragbot.DiffByHash, "match vectors by content \"hash\" or \"pair_id\"")
+	fs.BoolVar(&jsonOutput, "json", false, "print the differences as JSON")
+	parseFlags(fs, args)
+
+	if !loadConfig() {
+		return
+	}
+
+	// An empty namespace is Pinecone's default one, so only unset flags fall back to the config
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	from := ragbot.DiffTarget{Dimension: *fromDim}
+	to := ragbot.DiffTarget{Dimension: *fromDim}
+	if *toDim != 0 {
+		to.Dimension = *toDim
+	}
+	from.Namespace, to.Namespace = cfg.NamespaceFor(from.Dimension), cfg.NamespaceFor(to.Dimension)
+	if set["from-ns"] {
+		from.Namespace = *fromNS
+	}
+	if set["to-ns"] {
+		to.Namespace = *toNS
+	}
+	for _, dim := range []int{from.Dimension, to.Dimension} {
+		if _, ok := cfg.Indexes[dim]; !ok {
+			logger.Error("no index configured for dimension", "dim", dim, "indexes", cfg.Indexes)
+			os.Exit(2)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.Info("🔀 Comparing", "from", from.String(), "to", to.String(), "by", *by)
+	report, err := bot.Diff(ctx, from, to, *by)
+	if err != nil {
+		logger.Error("diff failed", "err", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		printDiff(report)
+	}
+	if !report.Empty() {
+		os.Exit(1)
+	}
+}
+
+func printDiff(r *ragbot.DiffReport) {
+	for _, p := range r.Removed {
+		fmt.Printf("- %s\n    → %s\n", p.Input, p.Output)
+	}
+	for _, p := range r.Added {
+		fmt.Printf("+ %s\n    → %s\n", p.Input, p.Output)
+	}
+	for _, c := range r.Changed {
+		fmt.Printf("~ %s\n    - %s\n    + %s\n", c.Input, c.OldOutput, c.NewOutput)
+	}
+	if r.Empty() {
+		fmt.Printf("✅ No differences (%d pairs)\n", r.Unchanged)
+		return
+	}
+	fmt.Printf("\n%d added, %d removed, %d changed, %d unchanged\n", len(r.Added), len(r.Removed), len(r.Changed), r.Unchanged)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"geminivectortest/ragbot"
+)
+
+// Doctor verdicts, worst last
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+)
+
+// Health of one dimension's index
+type DoctorCheck struct {
+	Dimension       int
+	Index           string
+	Reachable       bool
+	Vectors         int // total across namespaces
+	NamespaceCount  int // vectors in the configured namespace
+	Corrupted       int
+	OtherNamespaces []string // namespaces holding data when the configured one is empty
+	Status          string
+	Problem         string
+}
+
+// Check one index: reachability, vector counts, namespace and metadata health
+func checkIndex(ctx context.Context, dim int) DoctorCheck {
+	c := DoctorCheck{Dimension: dim, Index: cfg.Indexes[dim], Status: doctorPass}
+
+	stats, err := bot.DescribeIndexStats(ctx, dim)
+	if err != nil {
+		c.Status, c.Problem = doctorFail, err.Error()
+		return c
+	}
+	c.Reachable = true
+	c.Vectors = stats.TotalVectorCount
+	if err := bot.CheckMetric(dim, stats); err != nil {
+		c.Status, c.Problem = doctorFail, err.Error()
+		return c
+	}
+	if err := bot.CheckDimension(dim, stats); err != nil {
+		c.Status, c.Problem = doctorFail, err.Error()
+		return c
+	}
+	c.NamespaceCount = stats.Namespaces[cfg.NamespaceFor(dim)].VectorCount
+
+	if c.NamespaceCount == 0 {
+		for ns, s := range stats.Namespaces {
+			if s.VectorCount > 0 {
+				c.OtherNamespaces = append(c.OtherNamespaces, ns)
+			}
+		}
+		sort.Strings(c.OtherNamespaces)
+		c.Status = doctorFail
+		if len(c.OtherNamespaces) > 0 {
+			c.Problem = fmt.Sprintf("namespace %q is empty; data found in %s", cfg.NamespaceFor(dim), strings.Join(c.OtherNamespaces, ", "))
+		} else {
+			c.Problem = "index is empty"
+		}
+		return c
+	}
+
+	err = bot.Scan(ctx, dim, func(page []ragbot.Vector) error {
+		for _, v := range page {
+			if ragbot.CheckPair(v.Pair()) != nil {
+				c.Corrupted++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.Status, c.Problem = doctorWarn, fmt.Sprintf("metadata scan failed: %v", err)
+		return c
+	}
+	if c.Corrupted > 0 {
+		c.Status, c.Problem = doctorWarn, fmt.Sprintf("%d vectors with missing or suspicious metadata (run diagnose)", c.Corrupted)
+	}
+	return c
+}
+
+// Print the checks as a table, followed by each problem
+func printDoctorTable(w io.Writer, checks []DoctorCheck) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "status\tdim\tindex\treachable\tvectors\tin namespace\tcorrupted\t")
+	for _, c := range checks {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%t\t%d\t%d\t%d\t\n",
+			c.Status, c.Dimension, c.Index, c.Reachable, c.Vectors, c.NamespaceCount, c.Corrupted)
+	}
+	tw.Flush()
+
+	for _, c := range checks {
+		if c.Problem != "" {
+			fmt.Fprintf(w, "%s %d: %s\n", c.Status, c.Dimension, c.Problem)
+		}
+	}
+}
+
+// Summarize the health of every index in one table. Exits 1 if any check fails.
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	parseFlags(fs, args)
+
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("🩺 Checking %d indexes, namespace %q\n\n", len(cfg.Indexes), cfg.Namespace)
+
+	var checks []DoctorCheck
+	failed := false
+	for _, dim := range cfg.Dimensions() {
+		c := checkIndex(ctx, dim)
+		failed = failed || c.Status == doctorFail
+		checks = append(checks, c)
+	}
+	printDoctorTable(os.Stdout, checks)
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"geminivectortest/ragbot"
+)
+
+// One duplicate cluster in --json output
+type JSONDuplicateCluster struct {
+	MaxSimilarity      float32                  `json:"max_similarity"`
+	MinSimilarity      float32                  `json:"min_similarity"`
+	ConflictingOutputs bool                     `json:"conflicting_outputs"`
+	Pairs              []map[string]interface{} `json:"pairs"`
+}
+
+// Report clusters of training pairs with near-identical inputs so the dataset can be cleaned up
+func runFindDuplicates(args []string) {
+	fs := flag.NewFlagSet("find-duplicates", flag.ExitOnError)
+	file := fs.String("file", "test_embedding.json", "pairs file to check")
+	dim := fs.Int("dim", 768, "embedding dimension used for the comparison")
+	threshold := fs.Float64("threshold", 0.95, "minimum cosine similarity for two inputs to count as duplicates")
+	fs.BoolVar(&jsonOutput, "json", false, "print the clusters as JSON")
+	fs.StringVar(&inputField, "input-field", inputField, "JSON key or CSV column holding each pair's input")
+	fs.StringVar(&outputField, "output-field", outputField, "JSON key or CSV column holding each pair's output")
+	parseFlags(fs, args)
+
+	if !loadConfig() {
+		return
+	}
+
+	pairs, err := extractInputOutputPairs(*file)
+	if err != nil {
+		logger.Error("failed to load pairs", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	bar := newProgress(len(pairs))
+	bot.SetProgress(bar.Increment)
+	clusters, err := bot.FindDuplicates(ctx, pairs, *dim, float32(*threshold))
+	if err != nil {
+		logger.Error("find-duplicates failed", "err", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		out := make([]JSONDuplicateCluster, len(clusters))
+		for i, c := range clusters {
+			out[i] = JSONDuplicateCluster{MaxSimilarity: c.MaxSimilarity, MinSimilarity: c.MinSimilarity, ConflictingOutputs: c.ConflictingOutputs}
+			for _, p := range c.Pairs {
+				out[i].Pairs = append(out[i].Pairs, map[string]interface{}{
+					"pair_id": p, "input": pairs[p].Input, "output": pairs[p].Output,
+				})
+			}
+		}
+		data, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	printDuplicateClusters(pairs, clusters)
+	logger.Info("🧹 Duplicate check complete", "pairs", len(pairs), "clusters", len(clusters), "threshold", *threshold)
+}
+
+func printDuplicateClusters(pairs []ragbot.InputOutputPair, clusters []ragbot.DuplicateCluster) {
+	if len(clusters) == 0 {
+		fmt.Println("✅ No near-duplicate inputs found")
+		return
+	}
+	for i, c := range clusters {
+		fmt.Printf("\n%d. %d pairs, similarity %.3f-%.3f\n", i+1, len(c.Pairs), c.MinSimilarity, c.MaxSimilarity)
+		if c.ConflictingOutputs {
+			fmt.Println("   ⚠️  Outputs differ: similar questions get different answers")
+		}
+		for _, p := range c.Pairs {
+			fmt.Printf("   [%d] %s\n       → %s\n", p, pairs[p].Input, pairs[p].Output)
+		}
+	}
+}
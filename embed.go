@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"geminivectortest/ragbot"
+)
+
+// Embed one piece of text and print the vector as JSON, for checking what Gemini returns
+func runEmbed(args []string) {
+	fs := flag.NewFlagSet("embed", flag.ExitOnError)
+	dim := fs.Int("dim", 384, "output dimensionality")
+	taskType := fs.String("task-type", ragbot.TaskRetrievalDocument, "Gemini task type: "+ragbot.TaskRetrievalDocument+" or "+ragbot.TaskRetrievalQuery)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: chatbot embed [flags] <text>")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	values, err := bot.Embed(ctx, fs.Arg(0), *dim, *taskType)
+	if err != nil {
+		logger.Error("embedding failed", "err", err)
+		os.Exit(1)
+	}
+
+	data, _ := json.Marshal(values)
+	fmt.Println(string(data))
+}
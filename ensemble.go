@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// One ensemble match in --json output
+type JSONEnsembleMatch struct {
+	Input  string             `json:"input"`
+	Output string             `json:"output"`
+	Score  float64            `json:"score"`
+	Scores map[string]float32 `json:"scores"`
+	Model  string             `json:"model"`
+}
+
+// Search with every ensemble model at one dimension and print the merged ranking,
+// showing each model's score and which model carried the winning match
+func printEnsembleResponse(ctx context.Context, userInput string, dimension int) {
+	result, err := bot.EnsembleSearch(ctx, userInput, dimension, cfg.TopK)
+
+	if jsonOutput {
+		out := map[string]interface{}{"query": userInput, "dimension": dimension}
+		if result != nil {
+			matches := make([]JSONEnsembleMatch, len(result.Matches))
+			for i, m := range result.Matches {
+				matches[i] = JSONEnsembleMatch{Input: m.Input, Output: m.Output, Score: m.Score, Scores: m.Scores, Model: m.Model}
+			}
+			out["matches"] = matches
+			if len(result.Errors) > 0 {
+				errs := map[string]string{}
+				for name, e := range result.Errors {
+					errs[name] = e.Error()
+				}
+				out["errors"] = errs
+			}
+		} else if err != nil {
+			out["errors"] = map[string]string{"ensemble": err.Error()}
+		}
+		data, _ := json.Marshal(out)
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("\n🔍 Ensemble search for: %s (dimension %d)\n", userInput, dimension)
+	fmt.Println(strings.Repeat("=", 50))
+	if result == nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+
+	names := make([]string, 0, len(result.Errors))
+	for name := range result.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("❌ %s: %v\n", name, result.Errors[name])
+	}
+
+	if len(result.Matches) == 0 {
+		fmt.Println("No matches found")
+		return
+	}
+	for i, m := range result.Matches {
+		fmt.Printf("%d. Score: %.3f (from %s)\n", i+1, m.Score, m.Model)
+		fmt.Printf("   Similar Input: %s\n", m.Input)
+		fmt.Printf("   Response: %s\n", m.Output)
+		for _, model := range cfg.Ensemble {
+			if score, ok := m.Scores[model.Name]; ok {
+				fmt.Printf("   %s: %.3f\n", model.Name, score)
+			} else {
+				fmt.Printf("   %s: -\n", model.Name)
+			}
+		}
+		fmt.Println()
+	}
+
+	best := result.Matches[0]
+	fmt.Printf("🏆 Winning match contributed mostly by %s\n", best.Model)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"geminivectortest/ragbot"
+)
+
+// One labeled query for the evaluation harness
+type EvalCase struct {
+	Query          string `json:"query"`
+	ExpectedOutput string `json:"expectedOutput"`
+}
+
+// Retrieval accuracy for one dimension
+type EvalResult struct {
+	Dimension int
+	Cases     int
+	Errors    int
+	Top1      int
+	Top3      int
+	ScoreSum  float64 // sum of the top match score over cases that returned matches
+	Scored    int
+
+	Top1Hits []bool // per case, in file order: whether the expected output ranked first
+}
+
+func (r EvalResult) Top1Accuracy() float64 { return ratio(r.Top1, r.Cases-r.Errors) }
+func (r EvalResult) Top3Accuracy() float64 { return ratio(r.Top3, r.Cases-r.Errors) }
+func (r EvalResult) AvgTopScore() float64  { return ratio64(r.ScoreSum, r.Scored) }
+
+func ratio(n, d int) float64 {
+	return ratio64(float64(n), d)
+}
+
+func ratio64(n float64, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return n / float64(d)
+}
+
+// Load a JSON array of {query, expectedOutput} cases
+func loadEvalCases(filename string) ([]EvalCase, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read eval file: %v", err)
+	}
+	var cases []EvalCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("failed to parse eval file %s: %v", filename, err)
+	}
+	return cases, nil
+}
+
+// Outputs match when equal after trimming whitespace and ignoring case
+func sameOutput(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// Whether a vector answers with want, as its first output or any other one
+func holdsOutput(m ragbot.Metadata, want string) bool {
+	if sameOutput(m.Output, want) {
+		return true
+	}
+	for _, out := range m.Outputs {
+		if sameOutput(out, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run every case against each dimension and score where the expected output ranked.
+// With leaveOneOut, a case whose query and expected output are themselves an uploaded
+// pair has that pair's vector excluded, so it can't trivially match itself.
+func evaluate(ctx context.Context, cases []EvalCase, dimensions []int, leaveOneOut bool) []EvalResult {
+	results := make([]EvalResult, 0, len(dimensions))
+
+	for _, dim := range dimensions {
+		r := EvalResult{Dimension: dim, Cases: len(cases), Top1Hits: make([]bool, len(cases))}
+		for i, c := range cases {
+			var exclude []string
+			if leaveOneOut {
+				exclude = []string{ragbot.VectorID(ragbot.InputOutputPair{Input: c.Query, Output: c.ExpectedOutput}, dim)}
+			}
+			found, err := bot.SearchExcluding(ctx, c.Query, dim, 3, exclude)
+			if err != nil {
+				logger.Warn("eval query failed", "dim", dim, "query", c.Query, "err", err)
+				r.Errors++
+				continue
+			}
+			if len(found.Matches) == 0 {
+				continue
+			}
+
+			r.ScoreSum += float64(found.Matches[0].Score)
+			r.Scored++
+			for rank, m := range found.Matches {
+				if holdsOutput(m.Metadata, c.ExpectedOutput) {
+					if rank == 0 {
+						r.Top1++
+						r.Top1Hits[i] = true
+					}
+					r.Top3++
+					break
+				}
+			}
+		}
+		results = append(results, r)
+	}
+
+	return results
+}
+
+// Print the per-dimension accuracy table
+func printEvalTable(w io.Writer, results []EvalResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "dim\tcases\terrors\ttop-1\ttop-3\tavg top score\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.1f%%\t%.1f%%\t%.3f\t\n",
+			r.Dimension, r.Cases, r.Errors, r.Top1Accuracy()*100, r.Top3Accuracy()*100, r.AvgTopScore())
+	}
+	tw.Flush()
+}
+
+// Query length buckets used to suggest auto_dimension rules: [min, max] words, 0 max is open
+var evalWordBuckets = [][2]int{{1, 3}, {4, 7}, {8, 0}}
+
+// Suggest auto_dimension rules from eval results: per query length bucket, the dimension
+// with the most top-1 hits. Buckets where dimensions tie are left to search every dimension.
+func suggestDimensionRules(cases []EvalCase, results []EvalResult) []ragbot.DimensionRule {
+	var rules []ragbot.DimensionRule
+	for _, bucket := range evalWordBuckets {
+		bestDim, bestHits, tied := 0, -1, false
+		for _, r := range results {
+			hits := 0
+			for i, c := range cases {
+				words := ragbot.AnalyzeQuery(c.Query).Words
+				if words >= bucket[0] && (bucket[1] == 0 || words <= bucket[1]) && r.Top1Hits[i] {
+					hits++
+				}
+			}
+			switch {
+			case hits > bestHits:
+				bestDim, bestHits, tied = r.Dimension, hits, false
+			case hits == bestHits:
+				tied = true
+			}
+		}
+		if bestHits > 0 && !tied {
+			rules = append(rules, ragbot.DimensionRule{MinWords: bucket[0], MaxWords: bucket[1], Dimension: bestDim})
+		}
+	}
+	return rules
+}
+
+// Print suggested rules as an auto_dimension block for config.yaml
+func printDimensionRules(w io.Writer, rules []ragbot.DimensionRule) {
+	if len(rules) == 0 {
+		fmt.Fprintln(w, "# no dimension wins clearly for any query length; keep searching every dimension")
+		return
+	}
+	fmt.Fprintln(w, "auto_dimension:")
+	fmt.Fprintln(w, "  enabled: true")
+	fmt.Fprintln(w, "  rules:")
+	for _, r := range rules {
+		fmt.Fprintf(w, "    - {min_words: %d, max_words: %d, dimension: %d}\n", r.MinWords, r.MaxWords, r.Dimension)
+	}
+}
+
+// Measure top-1/top-3 retrieval accuracy per dimension against a labeled file
+func runEval(args []string) {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	leaveOneOut := fs.Bool("leave-one-out", false, "exclude the uploaded pair each case was taken from, when cases come from the pairs file")
+	suggest := fs.Bool("suggest-rules", false, "also print auto_dimension rules picking the most accurate dimension per query length")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: chatbot eval [flags] <cases.json>")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cases, err := loadEvalCases(fs.Arg(0))
+	if err != nil {
+		logger.Error("eval failed", "err", err)
+		os.Exit(1)
+	}
+
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.Info("📐 Evaluating retrieval accuracy", "cases", len(cases))
+	results := evaluate(ctx, cases, cfg.Dimensions(), *leaveOneOut)
+	printEvalTable(os.Stdout, results)
+	if *suggest {
+		fmt.Println()
+		printDimensionRules(os.Stdout, suggestDimensionRules(cases, results))
+	}
+}
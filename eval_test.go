@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestEvaluateMatchesAnyOutput(t *testing.T) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	useServices(t, fakeGemini, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"matches":[
+			{"id":"a","score":0.9,"metadata":{"input":"cancel my ride","output":"Done","outputs":["Done","Your ride is cancelled"]}},
+			{"id":"b","score":0.8,"metadata":{"input":"where is my cab","output":"On its way"}}]}`))
+	})
+
+	cases := []EvalCase{
+		{Query: "cancel my ride", ExpectedOutput: "your ride is cancelled"},
+		{Query: "where is my cab", ExpectedOutput: "On its way"},
+		{Query: "book a ride", ExpectedOutput: "What time?"},
+	}
+	results := evaluate(context.Background(), cases, []int{384}, false)
+	if len(results) != 1 {
+		t.Fatalf("%d results, want 1", len(results))
+	}
+	r := results[0]
+	if r.Errors != 0 || r.Top1 != 1 || r.Top3 != 2 {
+		t.Errorf("errors %d, top-1 %d, top-3 %d, want 0, 1 and 2", r.Errors, r.Top1, r.Top3)
+	}
+	if !r.Top1Hits[0] || r.Top1Hits[1] || r.Top1Hits[2] {
+		t.Errorf("top-1 hits %v, want only the case answered by a later output", r.Top1Hits)
+	}
+}
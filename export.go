@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+
+	"geminivectortest/ragbot"
+)
+
+// Read every pair stored in one dimension's index, or only those created within created.
+// Each record holds the vector's full metadata, so "input" and "output" sit alongside
+// the other stored fields.
+func exportPairs(ctx context.Context, dimension int, created ragbot.TimeRange) ([]ragbot.Metadata, error) {
+	var records []ragbot.Metadata
+	err := bot.ScanRange(ctx, dimension, created, func(page []ragbot.Vector) error {
+		for _, v := range page {
+			if err := ragbot.CheckPair(v.Pair()); err != nil {
+				logger.Warn("skipping vector with corrupted metadata", "id", v.ID, "err", err)
+				continue
+			}
+			records = append(records, v.Metadata)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Restore the original file order, with pairs added later by "add" at the end
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if (a.PairID == ragbot.AddedPairID) != (b.PairID == ragbot.AddedPairID) {
+			return b.PairID == ragbot.AddedPairID
+		}
+		if a.PairID == ragbot.AddedPairID {
+			return a.CreatedAt < b.CreatedAt
+		}
+		return a.PairID < b.PairID
+	})
+	return records, nil
+}
+
+// Dump the knowledge base in one index to a JSON file that upload can read back
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dim := fs.Int("dim", 1024, "dimension of the index to export")
+	out := fs.String("out", "", "output file (default: output_logs/export_<dim>.json)")
+	window := addTimeRangeFlags(fs)
+	parseFlags(fs, args)
+
+	created, err := window.resolve()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if !loadConfig() {
+		return
+	}
+	if _, ok := cfg.Indexes[*dim]; !ok {
+		logger.Error("no index configured for dimension", "dim", *dim, "indexes", cfg.Indexes)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	records, err := exportPairs(ctx, *dim, created)
+	if err != nil {
+		logger.Error("export failed", "err", err)
+		os.Exit(1)
+	}
+
+	filename := *out
+	if filename == "" {
+		os.MkdirAll("output_logs", 0755)
+		filename = fmt.Sprintf("output_logs/export_%d.json", *dim)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode export", "err", err)
+		os.Exit(1)
+	}
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		logger.Error("failed to write export", "file", filename, "err", err)
+		os.Exit(1)
+	}
+
+	attrs := []any{"count", len(records), "index", cfg.Indexes[*dim], "file", filename}
+	if !created.IsZero() {
+		attrs = append(attrs, "created", created)
+	}
+	logger.Info("📦 Exported pairs", attrs...)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"geminivectortest/ragbot"
+)
+
+// Pairs an upload could not embed, written so they can be re-processed with --retry-failures
+type FailuresFile struct {
+	Timestamp  time.Time            `json:"timestamp"`
+	SourceFile string               `json:"source_file"`
+	Failures   []ragbot.PairFailure `json:"failures"`
+}
+
+// Write the failures to output_logs/failures_<ts>.json and return its path
+func writeFailures(ff FailuresFile) (string, error) {
+	data, err := json.MarshalIndent(ff, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode failures: %v", err)
+	}
+
+	filename := fmt.Sprintf("output_logs/failures_%d.json", ff.Timestamp.Unix())
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", filename, err)
+	}
+	return filename, nil
+}
+
+// Read a failures file back as the distinct failed pairs and their pair IDs, in pair order
+func readFailures(filename string) ([]ragbot.InputOutputPair, []int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read failures: %v", err)
+	}
+	var ff FailuresFile
+	if err := json.Unmarshal(data, &ff); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse failures %s: %v", filename, err)
+	}
+
+	// A pair that failed at several dimensions is listed once per dimension
+	byID := map[int]ragbot.InputOutputPair{}
+	for _, f := range ff.Failures {
+		byID[f.PairID] = ragbot.InputOutputPair{Input: f.Input, Output: f.Output, Outputs: f.Outputs}
+	}
+	ids := make([]int, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	pairs := make([]ragbot.InputOutputPair, len(ids))
+	for i, id := range ids {
+		pairs[i] = byID[id]
+	}
+	return pairs, ids, nil
+}
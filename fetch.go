@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"geminivectortest/ragbot"
+)
+
+// Print one stored vector by ID, for checking exactly what a pair was indexed with
+func runFetch(args []string) {
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	dim := fs.Int("dim", 1024, "dimension of the index holding the vector")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: chatbot fetch [flags] <id>")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if !loadConfig() {
+		return
+	}
+	if _, ok := cfg.Indexes[*dim]; !ok {
+		logger.Error("no index configured for dimension", "dim", *dim, "indexes", cfg.Indexes)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	v, err := bot.FetchVector(ctx, fs.Arg(0), *dim)
+	if errors.Is(err, ragbot.ErrVectorNotFound) {
+		logger.Warn("🔍 No vector with that ID", "id", fs.Arg(0), "index", cfg.Indexes[*dim], "namespace", cfg.NamespaceFor(*dim))
+		os.Exit(1)
+	}
+	if err != nil {
+		logger.Error("fetch failed", "err", err)
+		os.Exit(1)
+	}
+
+	printVector(v)
+}
+
+// Metadata and a short summary of the values, followed by the full vector as JSON
+func printVector(v *ragbot.Vector) {
+	fmt.Printf("ID:        %s\n", v.ID)
+	fmt.Printf("Input:     %s\n", v.Metadata.Input)
+	fmt.Printf("Output:    %s\n", v.Metadata.Output)
+	fmt.Printf("Dimension: %d (%d values)\n", v.Metadata.Dimension, len(v.Values))
+	fmt.Printf("Pair ID:   %d\n", v.Metadata.PairID)
+	if v.Metadata.ContentHash != "" {
+		fmt.Printf("Hash:      %s (%s)\n", v.Metadata.ContentHash, v.Metadata.IDScheme)
+	}
+	if v.Metadata.Intent != "" {
+		fmt.Printf("Intent:    %s\n", v.Metadata.Intent)
+	}
+
+	var sumSq float64
+	zeros := 0
+	for _, x := range v.Values {
+		sumSq += float64(x) * float64(x)
+		if x == 0 {
+			zeros++
+		}
+	}
+	fmt.Printf("Norm²:     %.4f, %d zero values\n", sumSq, zeros)
+	if v.SparseValues != nil {
+		fmt.Printf("Sparse:    %d indices\n", len(v.SparseValues.Indices))
+	}
+
+	data, _ := json.MarshalIndent(v, "", "  ")
+	fmt.Println(string(data))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ANSI codes wrapped around tokens shared with the query
+const (
+	highlightStart = "\x1b[1;32m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// Highlight words shared between the query and matched inputs, from --highlight
+var highlight bool
+
+// Whether highlighting is on: requested, stdout is a terminal and NO_COLOR is unset
+func highlightEnabled() bool {
+	return highlight && !jsonOutput && isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+}
+
+// Lowercased letter/digit words of text
+func highlightTokens(text string) map[string]bool {
+	tokens := map[string]bool{}
+	for _, tok := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tokens[tok] = true
+	}
+	return tokens
+}
+
+// highlightOverlap when highlighting is enabled, otherwise text unchanged
+func maybeHighlight(query, text string) string {
+	if !highlightEnabled() {
+		return text
+	}
+	return highlightOverlap(query, text)
+}
+
+// text with every word that also appears in query wrapped in ANSI color
+func highlightOverlap(query, text string) string {
+	shared := highlightTokens(query)
+
+	var out, word strings.Builder
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		if shared[strings.ToLower(w)] {
+			out.WriteString(highlightStart + w + highlightEnd)
+		} else {
+			out.WriteString(w)
+		}
+		word.Reset()
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		out.WriteRune(r)
+	}
+	flush()
+	return out.String()
+}
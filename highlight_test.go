@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestHighlightOverlap(t *testing.T) {
+	for _, tc := range []struct {
+		query, text, want string
+	}{
+		{"where is my cab", "Where is my ride?", highlightStart + "Where" + highlightEnd + " " + highlightStart + "is" + highlightEnd + " " + highlightStart + "my" + highlightEnd + " ride?"},
+		{"Cab KA01AB1234", "cab (ka01ab1234)", highlightStart + "cab" + highlightEnd + " (" + highlightStart + "ka01ab1234" + highlightEnd + ")"},
+		{"refund", "cancel my ride", "cancel my ride"},
+		{"", "", ""},
+	} {
+		if got := highlightOverlap(tc.query, tc.text); got != tc.want {
+			t.Errorf("highlightOverlap(%q, %q) = %q, want %q", tc.query, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestMaybeHighlightOffWithoutTerminal(t *testing.T) {
+	highlight = true
+	t.Cleanup(func() { highlight = false })
+	// Test output is not a terminal
+	if got := maybeHighlight("my cab", "where is my cab"); got != "where is my cab" {
+		t.Errorf("maybeHighlight = %q, want the text unchanged", got)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"geminivectortest/ragbot"
+)
+
+// Shared logger, configured from --log-level and --log-format
+var logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// Configure the shared logger. Logs go to stderr so command output on stdout stays clean.
+func setupLogger(level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q (want debug, info, warn or error)", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case "text":
+		logger = slog.New(slog.NewTextHandler(os.Stderr, opts))
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	default:
+		return fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+
+	// The ragbot library logs through the default logger
+	slog.SetDefault(logger)
+	return nil
+}
+
+// Config file path from --config
+var configPath = defaultConfigPath
+
+// Log raw embeddings and request payloads, from --verbose
+var verbose bool
+
+// Register the shared flags, parse args and configure the logger, exiting on bad input
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.StringVar(&configPath, "config", defaultConfigPath, "YAML config file (optional when using the default path)")
+	backend := fs.String("backend", "", "pinecone, or local to answer from local_pairs_file without Pinecone (default: backend from the config)")
+	fs.BoolVar(&verbose, "verbose", false, "log embedding values and request payloads (credentials redacted)")
+	level := fs.String("log-level", "info", "log level: debug, info, warn or error")
+	format := fs.String("log-format", "text", "log format: text or json")
+	seed := fs.Int64("seed", 0, "seed for retry jitter, to reproduce a run's timing (default: random_seed from the config, else the clock)")
+	fs.Parse(args)
+
+	if *backend != "" {
+		configOverrides = append(configOverrides, func(c *ragbot.Config) { c.Backend = *backend })
+	}
+	if *seed != 0 {
+		configOverrides = append(configOverrides, func(c *ragbot.Config) { c.RandomSeed = *seed })
+	}
+
+	if err := setupLogger(*level, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+}
@@ -2,301 +2,487 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"geminivectortest/ragbot"
+
 	"github.com/joho/godotenv"
 )
 
-// Configuration
+// Settings and library client shared by every subcommand, set by loadConfig
 var (
-	geminiAPIKey   = os.Getenv("GEMINI_API_KEY")
-	pineconeAPIKey = os.Getenv("PINECONE_API_KEY")
-	pineconeEnv1   = map[string]string{
-		"chatbot-embeddings-384-2x9jann":  "aped-4627-b74a",
-		"chatbot-embeddings-512-2x9jann":  "aped-4627-b74a",
-		"chatbot-embeddings-1024-2x9jann": "aped-4627-b74a",
-	}
-
-	// Three different indexes for different embedding dimensions
-	indexes = map[int]string{
-		384:  "chatbot-embeddings-384-2x9jann",
-		512:  "chatbot-embeddings-512-2x9jann",
-		1024: "chatbot-embeddings-1024-2x9jann",
-	}
+	cfg *ragbot.Config
+	bot *ragbot.Bot
 )
 
-type InputOutputPair struct {
-	Input  string
-	Output string
-}
+// Default --config path. Unlike an explicit --config, it may be missing.
+const defaultConfigPath = "config.yaml"
 
-type Vector struct {
-	ID       string                 `json:"id"`
-	Values   []float32              `json:"values"`
-	Metadata map[string]interface{} `json:"metadata"`
-}
+// Default upload --pairs path. Unlike an explicit --pairs, it may be missing.
+const defaultPairsFile = "test_embedding.json"
 
-type EmbeddingResponse struct {
-	Embedding struct {
-		Values []float32 `json:"values"`
-	} `json:"embedding"`
+// Load the pairs in a pairs file or a directory of them
+func loadPairs(filename string) ([]ragbot.InputOutputPair, error) {
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return loadPairsDir(filename)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pairs: %v", err)
+	}
+	pairs, err := loadPairsFile(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("📁 Loaded pairs", "count", len(pairs), "file", filename)
+	return pairs, nil
 }
 
-// Extract input-output pairs from the documentation
-func extractInputOutputPairs(filename string) ([]InputOutputPair, error) {
+// Extract input-output pairs from the documentation: a pairs file, a directory of them,
+// or the built-in examples when filename is empty or doesn't exist
+func extractInputOutputPairs(filename string) ([]ragbot.InputOutputPair, error) {
 	if filename != "" {
-		if data, err := os.ReadFile(filename); err == nil {
-			var pairs []InputOutputPair
-			if err := json.Unmarshal(data, &pairs); err != nil {
-				return nil, fmt.Errorf("failed to parse JSON file %s: %v", filename, err)
-			}
-			fmt.Printf("📁 Loaded %d pairs from %s\n", len(pairs), filename)
-			return pairs, nil
+		if _, err := os.Stat(filename); err == nil {
+			return loadPairs(filename)
 		}
 	}
 
-	pairs := []InputOutputPair{
+	pairs := []ragbot.InputOutputPair{
 		// Booking scenarios
-		{"Book transport for tomorrow at 8 AM", "Got it! You're scheduling a pickup for tomorrow at 8 AM. Can you confirm your drop location is your office?"},
-		{"I want pickup from home at 7:30 AM on Monday", "Perfect! I'm booking your pickup for Monday at 7:30 AM from your home address. Your roster is confirmed! You will receive driver details 30 minutes before the trip."},
-		{"Schedule my pickup for 6 PM today", "I've scheduled your pickup for today at 6 PM. Your booking is confirmed and you'll receive driver details shortly."},
-		{"Add me to the transport list for tomorrow's night shift", "I've added you to the transport roster for tomorrow's night shift. You'll receive confirmation with driver details 30 minutes before your trip."},
+		{Input: "Book transport for tomorrow at 8 AM", Output: "Got it! You're scheduling a pickup for tomorrow at 8 AM. Can you confirm your drop location is your office?"},
+		{Input: "I want pickup from home at 7:30 AM on Monday", Output: "Perfect! I'm booking your pickup for Monday at 7:30 AM from your home address. Your roster is confirmed! You will receive driver details 30 minutes before the trip."},
+		{Input: "Schedule my pickup for 6 PM today", Output: "I've scheduled your pickup for today at 6 PM. Your booking is confirmed and you'll receive driver details shortly."},
+		{Input: "Add me to the transport list for tomorrow's night shift", Output: "I've added you to the transport roster for tomorrow's night shift. You'll receive confirmation with driver details 30 minutes before your trip."},
 
 		// Viewing schedule scenarios
-		{"Show me my roster for this week", "Here's your upcoming roster:\n• Tomorrow - Pickup at 7:30 AM, Drop at 6 PM\n• Wednesday - Pickup at 8 AM\n• Friday - No Roster"},
-		{"Do I have a trip scheduled for tomorrow?", "You have a pickup scheduled tomorrow at 8 AM from your home address."},
-		{"What time is my pickup today?", "You have a pickup scheduled today at 6 PM from your home address."},
-		{"Show my upcoming transport schedule", "Here are your upcoming trips:\n• Today - Drop at 6 PM\n• Tomorrow - Pickup at 8 AM\n• Thursday - Pickup at 7:30 AM, Drop at 6:30 PM"},
+		{Input: "Show me my roster for this week", Output: "Here's your upcoming roster:\n• Tomorrow - Pickup at 7:30 AM, Drop at 6 PM\n• Wednesday - Pickup at 8 AM\n• Friday - No Roster"},
+		{Input: "Do I have a trip scheduled for tomorrow?", Output: "You have a pickup scheduled tomorrow at 8 AM from your home address."},
+		{Input: "What time is my pickup today?", Output: "You have a pickup scheduled today at 6 PM from your home address."},
+		{Input: "Show my upcoming transport schedule", Output: "Here are your upcoming trips:\n• Today - Drop at 6 PM\n• Tomorrow - Pickup at 8 AM\n• Thursday - Pickup at 7:30 AM, Drop at 6:30 PM"},
 
 		// Modification scenarios
-		{"Change my pickup time to 9 AM tomorrow", "I found your roster for tomorrow at 8 AM. I've updated your pickup time to 9 AM. You'll receive updated trip details shortly."},
-		{"Reschedule my drop to 7 PM instead of 6", "Your drop-off time has been updated to 7 PM. Updated trip details will be shared with you."},
-		{"Edit the time for Monday roster", "You have a roster for Monday at 6 PM. What time would you like to change it to?"},
+		{Input: "Change my pickup time to 9 AM tomorrow", Output: "I found your roster for tomorrow at 8 AM. I've updated your pickup time to 9 AM. You'll receive updated trip details shortly."},
+		{Input: "Reschedule my drop to 7 PM instead of 6", Output: "Your drop-off time has been updated to 7 PM. Updated trip details will be shared with you."},
+		{Input: "Edit the time for Monday roster", Output: "You have a roster for Monday at 6 PM. What time would you like to change it to?"},
 
 		// Cancellation scenarios
-		{"Cancel my transport for tomorrow", "I found a roster for tomorrow at 8 AM. Your transport for tomorrow has been cancelled successfully."},
-		{"I won't need pickup on Friday", "Your pickup for Friday has been cancelled."},
-		{"I'm working from home tomorrow, cancel the ride", "Done! Your ride for tomorrow has been successfully cancelled."},
+		{Input: "Cancel my transport for tomorrow", Output: "I found a roster for tomorrow at 8 AM. Your transport for tomorrow has been cancelled successfully."},
+		{Input: "I won't need pickup on Friday", Output: "Your pickup for Friday has been cancelled."},
+		{Input: "I'm working from home tomorrow, cancel the ride", Output: "Done! Your ride for tomorrow has been successfully cancelled."},
 
 		// Help and guidance
-		{"How do I book a trip?", "I can help you book a transport! Just tell me the date and time. For example: 'Book a pickup for tomorrow at 9 AM' and I'll handle the rest."},
-		{"Can I cancel a roster?", "Yes, you can cancel a roster anytime! Just tell me which trip you want to cancel, like 'Cancel my ride for tomorrow' and I'll take care of it."},
-		{"What is a roster?", "A roster is your scheduled ride for a specific shift or date. It includes pickup and drop-off times and locations."},
+		{Input: "How do I book a trip?", Output: "I can help you book a transport! Just tell me the date and time. For example: 'Book a pickup for tomorrow at 9 AM' and I'll handle the rest."},
+		{Input: "Can I cancel a roster?", Output: "Yes, you can cancel a roster anytime! Just tell me which trip you want to cancel, like 'Cancel my ride for tomorrow' and I'll take care of it."},
+		{Input: "What is a roster?", Output: "A roster is your scheduled ride for a specific shift or date. It includes pickup and drop-off times and locations."},
 
 		// Available shifts queries
-		{"What are the available login shifts for tomorrow?", "Your login shifts for tomorrow are every 30 minutes starting from 7 AM."},
-		{"Show me logout shifts for today", "Logout shifts for today are every 30 minutes starting from 6 PM."},
-		{"What shifts are available after 8 AM tomorrow?", "Login shifts are available every 30 minutes starting from 8:10 AM tomorrow."},
+		{Input: "What are the available login shifts for tomorrow?", Output: "Your login shifts for tomorrow are every 30 minutes starting from 7 AM."},
+		{Input: "Show me logout shifts for today", Output: "Logout shifts for today are every 30 minutes starting from 6 PM."},
+		{Input: "What shifts are available after 8 AM tomorrow?", Output: "Login shifts are available every 30 minutes starting from 8:10 AM tomorrow."},
 
 		// Multi-day booking
-		{"Book my rides for the entire week", "I can help you book rides for multiple days. What are the start and end dates for your weekly booking?"},
-		{"I need transport from Monday to Friday", "I'll book your transport from Monday to Friday. What time would you prefer for your daily rides?"},
+		{Input: "Book my rides for the entire week", Output: "I can help you book rides for multiple days. What are the start and end dates for your weekly booking?"},
+		{Input: "I need transport from Monday to Friday", Output: "I'll book your transport from Monday to Friday. What time would you prefer for your daily rides?"},
 
 		// Error handling scenarios
-		{"Book", "Can you please specify the date and time for your trip?"},
-		{"I need it", "Did you mean to book a ride? Please provide the date and time, like 'Pickup at 7 AM tomorrow'."},
-		{"Next week", "I'd be happy to help with next week's transport. Could you specify the exact dates and times you need?"},
+		{Input: "Book", Output: "Can you please specify the date and time for your trip?"},
+		{Input: "I need it", Output: "Did you mean to book a ride? Please provide the date and time, like 'Pickup at 7 AM tomorrow'."},
+		{Input: "Next week", Output: "I'd be happy to help with next week's transport. Could you specify the exact dates and times you need?"},
 
 		// Real-time updates
-		{"Where is my cab?", "Your cab (KA01AB1234) is currently 5 minutes away from your pickup location. Driver Ramesh will contact you when he arrives."},
-		{"Is my driver here?", "Your cab (KA01AB1234) has arrived at your location. Driver Ramesh is waiting at the pickup point."},
+		{Input: "Where is my cab?", Output: "Your cab (KA01AB1234) is currently 5 minutes away from your pickup location. Driver Ramesh will contact you when he arrives."},
+		{Input: "Is my driver here?", Output: "Your cab (KA01AB1234) has arrived at your location. Driver Ramesh is waiting at the pickup point."},
 
 		// System prompt derived patterns
-		{"book my ride for tomorrow", "What time would you like your ride for tomorrow?"},
-		{"yes", "What time works for you?"},
-		{"cancel my ride for tomorrow", "Your ride for tomorrow has been cancelled successfully."},
-		{"did I book a ride for Monday?", "Let me check your bookings for Monday..."},
-		{"book multiple days", "What are the start and end dates for your multi-day booking?"},
+		{Input: "book my ride for tomorrow", Output: "What time would you like your ride for tomorrow?"},
+		{Input: "yes", Output: "What time works for you?"},
+		{Input: "cancel my ride for tomorrow", Output: "Your ride for tomorrow has been cancelled successfully."},
+		{Input: "did I book a ride for Monday?", Output: "Let me check your bookings for Monday..."},
+		{Input: "book multiple days", Output: "What are the start and end dates for your multi-day booking?"},
 	}
 
 	return pairs, nil
 }
 
-// Get embedding from Gemini API
-func getEmbedding(text string, dimension int) ([]float32, error) {
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-embedding-001:embedContent?key=" + geminiAPIKey
+// Index the training pairs with a progress bar, returning embedding usage for the run.
+// If ctx is cancelled, the library flushes what it already embedded for the current
+// dimension and a checkpoint is written describing where the upload stopped.
+// With retryFile set, only the pairs listed in that failures file are processed.
+// An empty sourceFile reads defaultPairsFile, or the built-in examples if it is missing.
+// Pairs whose embedding fails are written to a new failures file.
+func processAndUpload(ctx context.Context, sourceFile string, maxPairs int, retryFile string) *ragbot.RunMetrics {
+	var pairs []ragbot.InputOutputPair
+	var pairIDs []int
+	var err error
+	if retryFile != "" {
+		pairs, pairIDs, err = readFailures(retryFile)
+		logger.Info("🔁 Retrying failed pairs", "file", retryFile, "pairs", len(pairs))
+	} else if sourceFile == "" {
+		pairs, err = extractInputOutputPairs(defaultPairsFile)
+	} else {
+		pairs, err = loadPairs(sourceFile)
+	}
+	if err != nil {
+		logger.Error("failed to load pairs", "err", err)
+		os.Exit(1)
+	}
+	if maxPairs > 0 && len(pairs) > maxPairs {
+		logger.Warn("✂️ Truncating pairs for this run", "loaded", len(pairs), "max_pairs", maxPairs)
+		pairs = pairs[:maxPairs]
+		if pairIDs != nil {
+			pairIDs = pairIDs[:maxPairs]
+		}
+	}
+	bar := newProgress(len(pairs) * len(cfg.Dimensions()))
+	bot.SetProgress(bar.Increment)
 
-	payload := map[string]interface{}{
-		"content": map[string]interface{}{
-			"parts": []map[string]string{
-				{"text": text},
-			},
-		},
-		"taskType":             "RETRIEVAL_DOCUMENT", // Use RETRIEVAL_QUERY for semantic search
-		"outputDimensionality": dimension,
+	report, err := bot.IndexPairs(ctx, pairs, pairIDs)
+	if err != nil {
+		logger.Error("some dimensions failed to upload", "err", err)
 	}
 
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	if len(report.Failures) > 0 {
+		ff := FailuresFile{Timestamp: time.Now(), SourceFile: sourceFile, Failures: report.Failures}
+		if retryFile != "" {
+			ff.SourceFile = retryFile
+		}
+		if filename, err := writeFailures(ff); err != nil {
+			logger.Error("failed to write failures", "err", err)
+		} else {
+			logger.Warn("⚠️ Some pairs could not be embedded and are missing from the indexes",
+				"failures", len(report.Failures), "file", filename, "retry", "upload --retry-failures "+filename)
+		}
+	}
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("API request failed: %v", err)
+	if len(report.Rejected) > 0 {
+		logger.Warn("corrupted pairs were not uploaded", "count", len(report.Rejected), "pairs", report.Rejected)
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("API returned status %d", res.StatusCode)
+	if report.Interrupted {
+		checkpoint := Checkpoint{
+			Timestamp:           time.Now(),
+			SourceFile:          sourceFile,
+			TotalPairs:          len(pairs),
+			CompletedDimensions: report.CompletedDimensions,
+			PartialDimension:    report.PartialDimension,
+			NextPair:            report.NextPair,
+			PartialUploaded:     report.PartialUploaded,
+		}
+		if filename, err := writeCheckpoint(checkpoint); err != nil {
+			logger.Error("failed to write checkpoint", "err", err)
+		} else {
+			logger.Info("💾 Checkpoint saved", "file", filename)
+		}
+		logger.Warn("upload interrupted", "completed_dimensions", report.CompletedDimensions,
+			"partial_dimension", report.PartialDimension, "pairs_processed", report.NextPair, "vectors_uploaded", report.PartialUploaded)
 	}
 
-	var resp EmbeddingResponse
-	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	metrics := report.Metrics
+	for _, dim := range metrics.SortedDimensions() {
+		d := metrics.Dimension(dim)
+		logger.Info("💰 Embedding usage", "dim", dim, "calls", d.EmbeddingCalls, "failed", d.FailedCalls,
+			"chars", d.CharsEmbedded, "est_tokens", d.EstimatedTokens(), "elapsed", d.Elapsed.Round(time.Millisecond))
 	}
+	total := metrics.Totals()
+	logger.Info("💰 Embedding usage total", "calls", total.EmbeddingCalls, "failed", total.FailedCalls,
+		"chars", total.CharsEmbedded, "est_tokens", total.EstimatedTokens(), "est_cost_usd", total.EstimatedCost())
 
-	return resp.Embedding.Values, nil
+	return metrics
 }
 
-// Upload vectors to specific Pinecone index
-func upsertToPinecone(vectors []Vector, dimension int) error {
-	indexName := indexes[dimension]
-	pineconeEnv := pineconeEnv1[indexName]
-	url := fmt.Sprintf("https://%s.svc.%s.pinecone.io/vectors/upsert", indexName, pineconeEnv)
+// Canonical machine-readable record of a processing run
+type ProcessingLog struct {
+	Timestamp      time.Time                       `json:"timestamp"`
+	Started        time.Time                       `json:"started,omitempty"` // when the upload began, for --since last-run
+	Dimensions     []int                           `json:"dimensions"`
+	PairCount      int                             `json:"pair_count"`
+	EmbeddingUsage map[int]ragbot.DimensionMetrics `json:"embedding_usage,omitempty"`
+	Pairs          []ragbot.InputOutputPair        `json:"pairs"`
+}
 
-	payload := map[string]interface{}{
-		"vectors":   vectors,
-		"namespace": "chatbot-training-data-test-semantic",
+// Utility function to save logs, including the embedding usage summary when available.
+// The .json file is the canonical record; the .txt file is a human-readable copy.
+func saveProcessingLogs(pairs []ragbot.InputOutputPair, metrics *ragbot.RunMetrics) {
+	now := time.Now()
+	if err := saveProcessingLogJSON(now, pairs, metrics); err != nil {
+		logger.Error("failed to write JSON processing log", "err", err)
 	}
-	data, _ := json.Marshal(payload)
 
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(data))
-	req.Header.Add("Api-Key", pineconeAPIKey)
-	req.Header.Add("Content-Type", "application/json")
+	var f bytes.Buffer
+	f.WriteString(fmt.Sprintf("Processing Log - %s\n", now.Format("2006-01-02 15:04:05")))
+	f.WriteString(fmt.Sprintf("Total pairs processed: %d\n", len(pairs)))
+	f.WriteString(fmt.Sprintf("Dimensions: %v\n\n", cfg.Dimensions()))
 
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to upload to Pinecone: %v", err)
+	if metrics != nil {
+		f.WriteString("Embedding usage:\n")
+		f.WriteString(metrics.Summary())
+		f.WriteString("\n")
 	}
-	defer res.Body.Close()
 
-	fmt.Printf("✅ Pinecone upload to %s (dim %d): %s\n", indexName, dimension, res.Status)
+	for i, pair := range pairs {
+		f.WriteString(fmt.Sprintf("Pair %d:\n", i+1))
+		f.WriteString(fmt.Sprintf("Input: %s\n", pair.Input))
+		f.WriteString(fmt.Sprintf("Output: %s\n\n", pair.Output))
+	}
 
-	if res.StatusCode >= 400 {
-		var errBody bytes.Buffer
-		errBody.ReadFrom(res.Body)
-		return fmt.Errorf("Pinecone error %d: %s", res.StatusCode, errBody.String())
+	filename := fmt.Sprintf("output_logs/processing_log_%d.txt", now.Unix())
+	if err := writeFileAtomic(filename, f.Bytes(), 0644); err != nil {
+		logger.Error("failed to write log file", "file", filename, "err", err)
+		return
 	}
 
-	return nil
+	logger.Info("📄 Processing log saved", "file", filename)
 }
 
-// Process and upload data for all dimensions
-func processAndUpload() {
-	pairs, _ := extractInputOutputPairs("test_embedding.json")
-	dimensions := []int{384, 512, 1024}
-
-	fmt.Printf("📊 Processing %d input-output pairs for %d different dimensions...\n", len(pairs), len(dimensions))
-
-	for _, dim := range dimensions {
-		fmt.Printf("\n🔄 Processing dimension %d...\n", dim)
-		var vectors []Vector
-
-		for i, pair := range pairs {
-			// Get embedding for the input
-			embedding, err := getEmbedding(pair.Input, dim)
-			if err != nil {
-				fmt.Printf("❌ Error getting embedding for pair %d: %v\n", i, err)
-				continue
-			}
-
-			// Create vector with rich metadata
-			vector := Vector{
-				ID:     fmt.Sprintf("pair_%d_dim_%d", i, dim),
-				Values: embedding,
-				Metadata: map[string]interface{}{
-					"input":      pair.Input,
-					"output":     pair.Output,
-					"dimension":  dim,
-					"pair_id":    i,
-					"created_at": time.Now().Unix(),
-					"input_len":  len(pair.Input),
-					"output_len": len(pair.Output),
-				},
-			}
-
-			vectors = append(vectors, vector)
-
-			// Rate limiting - Gemini has rate limits
-			time.Sleep(100 * time.Millisecond)
-			if (i+1)%10 == 0 {
-				fmt.Printf("   📝 Processed %d/%d pairs for dim %d\n", i+1, len(pairs), dim)
-			}
+// Write the processing log as output_logs/processing_log_<ts>.json
+func saveProcessingLogJSON(now time.Time, pairs []ragbot.InputOutputPair, metrics *ragbot.RunMetrics) error {
+	record := ProcessingLog{
+		Timestamp:  now,
+		Dimensions: cfg.Dimensions(),
+		PairCount:  len(pairs),
+		Pairs:      pairs,
+	}
+	if metrics != nil {
+		record.Started = metrics.Started
+		record.EmbeddingUsage = map[int]ragbot.DimensionMetrics{}
+		for dim, d := range metrics.Dimensions {
+			record.EmbeddingUsage[dim] = *d
 		}
+	}
 
-		// Upload to Pinecone
-		if len(vectors) > 0 {
-			err := upsertToPinecone(vectors, dim)
-			if err != nil {
-				fmt.Printf("❌ Failed to upload dim %d: %v\n", dim, err)
-			} else {
-				fmt.Printf("✅ Successfully uploaded %d vectors for dimension %d\n", len(vectors), dim)
-			}
-		}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode processing log: %v", err)
+	}
 
-		// Small delay between dimensions
-		time.Sleep(500 * time.Millisecond)
+	filename := fmt.Sprintf("output_logs/processing_log_%d.json", now.Unix())
+	if err := writeFileAtomic(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", filename, err)
 	}
+
+	logger.Info("📄 Processing log saved", "file", filename)
+	return nil
 }
 
-// Utility function to save logs
-func saveProcessingLogs(pairs []InputOutputPair) {
-	filename := fmt.Sprintf("output_logs/processing_log_%d.txt", time.Now().Unix())
-	f, err := os.Create(filename)
+// Time allowed at startup for learning the dimensions of the configured indexes
+const dimensionCheckTimeout = 5 * time.Second
+
+// Config overrides from command-line flags, applied by loadConfig before the bot is created
+var configOverrides []func(c *ragbot.Config)
+
+// Load .env (if present) and the config file into cfg and create the bot, reporting any problems
+func loadConfig() bool {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	c, err := ragbot.LoadConfig(configPath, configPath != defaultConfigPath)
 	if err != nil {
-		fmt.Printf("Failed to create log file: %v\n", err)
-		return
+		logger.Error("failed to load config", "err", err)
+		return false
+	}
+	for _, override := range configOverrides {
+		override(c)
 	}
-	defer f.Close()
 
-	f.WriteString(fmt.Sprintf("Processing Log - %s\n", time.Now().Format("2006-01-02 15:04:05")))
-	f.WriteString(fmt.Sprintf("Total pairs processed: %d\n", len(pairs)))
-	f.WriteString(fmt.Sprintf("Dimensions: 384, 512, 1024\n\n"))
+	b, err := ragbot.New(c)
+	if err != nil {
+		logger.Error(err.Error())
+		return false
+	}
 
-	for i, pair := range pairs {
-		f.WriteString(fmt.Sprintf("Pair %d:\n", i+1))
-		f.WriteString(fmt.Sprintf("Input: %s\n", pair.Input))
-		f.WriteString(fmt.Sprintf("Output: %s\n\n", pair.Output))
+	cfg, bot = c, b
+	bot.SetVerbose(verbose)
+
+	if cfg.Backend != ragbot.BackendLocal && !cfg.SkipDimensionCheck {
+		ctx, cancel := context.WithTimeout(context.Background(), dimensionCheckTimeout)
+		bot.CheckIndexDimensions(ctx) // mismatches are logged as warnings
+		cancel()
 	}
 
-	fmt.Printf("📄 Processing log saved to: %s\n", filename)
+	if cfg.Backend == ragbot.BackendLocal && cfg.LocalVectorsFile != "" {
+		f, err := os.Open(cfg.LocalVectorsFile)
+		if err != nil {
+			logger.Error("failed to open vectors for the local backend", "err", err)
+			return false
+		}
+		defer f.Close()
+		n, err := bot.LoadSnapshot(f)
+		if err != nil {
+			logger.Error("failed to load vectors for the local backend", "file", cfg.LocalVectorsFile, "err", err)
+			return false
+		}
+		logger.Debug("loaded vector snapshot", "file", cfg.LocalVectorsFile, "vectors", n)
+	} else if cfg.Backend == ragbot.BackendLocal {
+		pairs, err := extractInputOutputPairs(cfg.LocalPairsFile)
+		if err != nil {
+			logger.Error("failed to load pairs for the local backend", "err", err)
+			return false
+		}
+		bot.Index(context.Background(), pairs)
+	}
+	return true
 }
 
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
+// Embed the training pairs and upload them to every index
+func runUpload(args []string) {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	skipPreflight := fs.Bool("skip-preflight", false, "skip the startup credential check against Gemini and Pinecone")
+	maxPairs := fs.Int("max-pairs", 0, "only embed and upload the first N pairs (0 uploads all)")
+	fs.StringVar(&inputField, "input-field", inputField, "JSON key or CSV column holding each pair's input")
+	fs.BoolVar(&strictPairs, "strict", false, "warn about unexpected fields in the pairs file and fail on unset --expand-env variables")
+	fs.BoolVar(&expandEnvPairs, "expand-env", false, "replace ${VAR} in inputs and outputs with environment variables")
+	fs.StringVar(&outputField, "output-field", outputField, "JSON key or CSV column holding each pair's output")
+	hybrid := fs.Bool("hybrid", false, "also upload BM25-style sparse values for hybrid search (requires a dotproduct index)")
+	pairsFile := fs.String("pairs", defaultPairsFile, "pairs file (.json, .csv or Markdown FAQ .md) or a directory of them")
+	retryFailures := fs.String("retry-failures", "", "re-process only the pairs in this failures file, at every dimension")
+	rejectLong := fs.Bool("reject-long-inputs", false, "fail pairs whose input exceeds max_input_tokens instead of truncating them")
+	snapshotFile := fs.String("snapshot", "", "also write every embedding with its metadata to this gzip snapshot, for restore or local_vectors_file")
+	parseFlags(fs, args)
+	if *hybrid {
+		configOverrides = append(configOverrides, func(c *ragbot.Config) { c.Hybrid = true })
 	}
-	geminiAPIKey = os.Getenv("GEMINI_API_KEY")
-	pineconeAPIKey = os.Getenv("PINECONE_API_KEY")
-	if geminiAPIKey == "" {
-		fmt.Println("❌ GEMINI_API_KEY not set")
-		return
+	if *rejectLong {
+		configOverrides = append(configOverrides, func(c *ragbot.Config) { c.InputOverflow = ragbot.OverflowReject })
 	}
-	if pineconeAPIKey == "" {
-		fmt.Println("❌ PINECONE_API_KEY not set")
+
+	if !loadConfig() {
 		return
 	}
 
-	fmt.Println("🚀 Starting Chatbot Vector Database Setup...")
-	fmt.Printf("📋 Target indexes: %v\n", indexes)
+	// Only the default pairs file falls back to the built-in examples; an explicit --pairs must load
+	source := ""
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "pairs" {
+			source = *pairsFile
+		}
+	})
+
+	// Ctrl-C cancels ctx so the upload can flush and checkpoint; a second Ctrl-C kills immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if !*skipPreflight {
+		logger.Info("🔑 Running preflight credential check")
+		if err := bot.Preflight(ctx); err != nil {
+			logger.Error("preflight failed", "err", err)
+			os.Exit(1)
+		}
+		logger.Info("✅ Credentials OK")
+	}
+
+	logger.Info("🚀 Starting Chatbot Vector Database Setup")
+	logger.Info("📋 Target indexes", "indexes", cfg.Indexes, "namespace", cfg.Namespace)
 
 	// Create output directory
 	os.MkdirAll("output_logs", 0755)
 
+	var snapshot *ragbot.SnapshotWriter
+	if *snapshotFile != "" {
+		f, err := os.Create(*snapshotFile)
+		if err != nil {
+			logger.Error("failed to create snapshot", "err", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if snapshot, err = ragbot.NewSnapshotWriter(f, cfg.EmbeddingModel); err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		bot.SetSnapshot(snapshot)
+	}
+
+	// Process and upload all data
+	metrics := processAndUpload(ctx, source, *maxPairs, *retryFailures)
+
+	if snapshot != nil {
+		if err := snapshot.Close(); err != nil {
+			logger.Error("failed to finish snapshot", "err", err)
+		} else {
+			logger.Info("💾 Snapshot saved", "file", *snapshotFile, "vectors", snapshot.Count())
+		}
+	}
+
 	// Extract and save processing logs
 	pairs, _ := extractInputOutputPairs("extracted_input_output_pairs.json")
-	saveProcessingLogs(pairs)
+	saveProcessingLogs(pairs, metrics)
 
-	// Process and upload all data
-	processAndUpload()
+	if ctx.Err() != nil {
+		logger.Warn("stopped early on interrupt; see the checkpoint for what was completed")
+		os.Exit(130)
+	}
 
-	fmt.Println("\n🎉 Vector database setup complete!")
-	fmt.Println("💡 Your chatbot now has enhanced context from input-output pairs stored in Pinecone.")
+	logger.Info("🎉 Vector database setup complete")
+	logger.Info("💡 Your chatbot now has enhanced context from input-output pairs stored in Pinecone")
+}
 
+// Usage: chatbot [upload|query|eval|embed|diagnose|migrate-ids|reindex|serve|export|doctor|fetch|bench|find-duplicates|add|diff|suggest|delete|replay|browse|ask|restore] [flags]. Upload is the default command.
+func main() {
+	cmd := "upload"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "upload":
+		runUpload(args)
+	case "query":
+		runQuery(args)
+	case "diagnose":
+		runDiagnose(args)
+	case "eval":
+		runEval(args)
+	case "embed":
+		runEmbed(args)
+	case "migrate-ids":
+		runMigrateIDs(args)
+	case "reindex":
+		runReindex(args)
+	case "serve":
+		runServe(args)
+	case "export":
+		runExport(args)
+	case "doctor":
+		runDoctor(args)
+	case "fetch":
+		runFetch(args)
+	case "bench":
+		runBench(args)
+	case "find-duplicates":
+		runFindDuplicates(args)
+	case "add":
+		runAdd(args)
+	case "diff":
+		runDiff(args)
+	case "suggest":
+		runSuggest(args)
+	case "delete":
+		runDelete(args)
+	case "replay":
+		runReplay(args)
+	case "browse":
+		runBrowse(args)
+	case "ask":
+		runAsk(args)
+	case "restore":
+		runRestore(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q (want upload, query, eval, embed, diagnose, migrate-ids, reindex, serve, export, doctor, fetch, bench, find-duplicates, add, diff, suggest, delete, replay, browse, ask or restore)\n", cmd)
+		os.Exit(2)
+	}
 }
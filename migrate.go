@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+)
+
+// Move vectors stored under the earlier position-based "pair_<i>_dim_<dim>" IDs to the
+// input-based IDs uploads now use
+func runMigrateIDs(args []string) {
+	fs := flag.NewFlagSet("migrate-ids", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report what would be moved and deleted")
+	parseFlags(fs, args)
+
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	failed := false
+	for _, dim := range cfg.Dimensions() {
+		report, err := bot.MigrateIDs(ctx, dim, *dryRun)
+		if err != nil {
+			logger.Error("failed to migrate IDs", "dim", dim, "err", err)
+			failed = true
+			continue
+		}
+		logger.Info("🧹 Migrated IDs", "dim", dim, "scanned", report.Scanned, "moved", report.Moved, "deleted", report.Deleted, "dry_run", *dryRun)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
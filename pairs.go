@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"geminivectortest/ragbot"
+)
+
+// Field names read into InputOutputPair, from --input-field and --output-field.
+// Matching is case-insensitive, so the defaults also accept "Input" and "Output"; an exact
+// key wins, and a record with several other spellings of one field is rejected.
+var (
+	inputField  = "input"
+	outputField = "output"
+)
+
+// Warn about fields other than the input and output ones, and fail on unset
+// environment variables when expanding them, from --strict
+var strictPairs bool
+
+// Expand ${VAR} references in inputs and outputs from the environment, from --expand-env
+var expandEnvPairs bool
+
+// A ${VAR} reference; bare $VAR is left alone so prices like "$5" survive
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Replace ${VAR} references in every input and output with the variable's value.
+// Unset variables fail in strict mode; otherwise they are logged and left as written.
+func expandPairsEnv(pairs []ragbot.InputOutputPair, strict bool) ([]ragbot.InputOutputPair, error) {
+	warned := map[string]bool{}
+	expand := func(i int, text string) (string, error) {
+		var missing string
+		out := envReference.ReplaceAllStringFunc(text, func(ref string) string {
+			name := envReference.FindStringSubmatch(ref)[1]
+			if value, ok := os.LookupEnv(name); ok {
+				return value
+			}
+			if missing == "" {
+				missing = name
+			}
+			return ref
+		})
+		if missing != "" {
+			if strict {
+				return "", fmt.Errorf("record %d: environment variable %s is not set", i, missing)
+			}
+			if !warned[missing] {
+				warned[missing] = true
+				logger.Warn("environment variable referenced by pairs file is not set, leaving it as written", "var", missing, "record", i)
+			}
+		}
+		return out, nil
+	}
+
+	expanded := make([]ragbot.InputOutputPair, len(pairs))
+	for i, p := range pairs {
+		var err error
+		if expanded[i].Input, err = expand(i, p.Input); err != nil {
+			return nil, err
+		}
+		if expanded[i].Output, err = expand(i, p.Output); err != nil {
+			return nil, err
+		}
+		for _, o := range p.Outputs {
+			out, err := expand(i, o)
+			if err != nil {
+				return nil, err
+			}
+			expanded[i].Outputs = append(expanded[i].Outputs, out)
+		}
+	}
+	return expanded, nil
+}
+
+// Parse a pairs file by extension: .csv with a header row, .md/.markdown as a FAQ,
+// anything else as a JSON array of objects
+func parsePairs(filename string, data []byte) ([]ragbot.InputOutputPair, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return parsePairsCSV(data)
+	case ".md", ".markdown":
+		return parsePairsMarkdown(data)
+	}
+	return parsePairsJSON(data)
+}
+
+// Parse a pairs file and expand environment variables in it when --expand-env is set
+func loadPairsFile(filename string, data []byte) ([]ragbot.InputOutputPair, error) {
+	pairs, err := parsePairs(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pairs file %s: %v", filename, err)
+	}
+	if expandEnvPairs {
+		if pairs, err = expandPairsEnv(pairs, strictPairs); err != nil {
+			return nil, fmt.Errorf("failed to expand pairs file %s: %v", filename, err)
+		}
+	}
+	return pairs, nil
+}
+
+// Extensions of the files loadPairsDir reads
+var pairsExtensions = map[string]bool{".json": true, ".csv": true, ".md": true, ".markdown": true}
+
+// Load every pairs file under dir, recursively and in path order
+func loadPairsDir(dir string) ([]ragbot.InputOutputPair, error) {
+	var pairs []ragbot.InputOutputPair
+	files := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !pairsExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read pairs file: %v", err)
+		}
+		loaded, err := loadPairsFile(path, data)
+		if err != nil {
+			return err
+		}
+		logger.Debug("loaded pairs file", "file", path, "count", len(loaded))
+		pairs = append(pairs, loaded...)
+		files++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if files == 0 {
+		return nil, fmt.Errorf("no .json, .csv or .md pairs files in %s", dir)
+	}
+	logger.Info("📁 Loaded pairs", "count", len(pairs), "dir", dir, "files", files)
+	return pairs, nil
+}
+
+// Parse a Markdown FAQ: each "## " heading is an input and the text under it, up to the
+// next level 1 or 2 heading, is its output. Text before the first question, such as a
+// "# Title", is ignored, as are headings inside fenced code blocks.
+func parsePairsMarkdown(data []byte) ([]ragbot.InputOutputPair, error) {
+	var pairs []ragbot.InputOutputPair
+	var question string
+	var answer []string
+	inFence := false
+	flush := func() {
+		if question == "" {
+			return
+		}
+		output := strings.TrimSpace(strings.Join(answer, "\n"))
+		if output == "" {
+			logger.Warn("skipping FAQ question without an answer", "question", question)
+		} else {
+			pairs = append(pairs, ragbot.InputOutputPair{Input: question, Output: output})
+		}
+		question, answer = "", nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && (strings.HasPrefix(line, "## ") || strings.HasPrefix(line, "# ")) {
+			flush()
+			if strings.HasPrefix(line, "## ") {
+				question = strings.TrimSpace(strings.TrimRight(strings.TrimPrefix(line, "## "), "# "))
+			}
+			continue
+		}
+		if question != "" {
+			answer = append(answer, strings.TrimRight(line, " \t"))
+		}
+	}
+	flush()
+
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf(`no "## Question" headings with answers found`)
+	}
+	return pairs, nil
+}
+
+// Value of a field in a JSON object, preferring an exact key match over a case-insensitive
+// one. Keys that match only case-insensitively and differ among themselves are ambiguous.
+func lookupField(record map[string]json.RawMessage, field string) (json.RawMessage, bool, error) {
+	if v, ok := record[field]; ok {
+		return v, true, nil
+	}
+	var keys []string
+	for k := range record {
+		if strings.EqualFold(k, field) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 1 {
+		sort.Strings(keys)
+		return nil, false, fmt.Errorf("field %q is ambiguous: keys %q differ only in case", field, keys)
+	}
+	if len(keys) == 0 {
+		return nil, false, nil
+	}
+	return record[keys[0]], true, nil
+}
+
+// Byte offset in data as a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	offset = min(max(offset, 0), int64(len(data)))
+	line, col := 1, 1
+	for _, c := range data[:offset] {
+		if c == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
+
+// Describe a decoding error with its line and column, falling back to the decoder position
+func locateJSONError(data []byte, err error, fallback int64) error {
+	offset := fallback
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	line, col := lineColumn(data, offset)
+	return fmt.Errorf("line %d, column %d: %v", line, col, err)
+}
+
+// Parse a JSON array of pair objects record by record, so a malformed file reports
+// the line, column and record where it went wrong. In strict mode unexpected fields are logged.
+func parsePairsJSON(data []byte) ([]ragbot.InputOutputPair, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, locateJSONError(data, err, dec.InputOffset())
+	} else if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		line, col := lineColumn(data, int64(len(data)-len(bytes.TrimLeft(data, " \t\r\n"))))
+		return nil, fmt.Errorf("line %d, column %d: expected a JSON array of pair objects", line, col)
+	}
+
+	var pairs []ragbot.InputOutputPair
+	for i := 0; dec.More(); i++ {
+		// The decoder sits just after the previous record; skip to where this one begins
+		start := dec.InputOffset()
+		for start < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[start])) {
+			start++
+		}
+		var record map[string]json.RawMessage
+		if err := dec.Decode(&record); err != nil {
+			// Fields decode as raw JSON, so the only type error is a record that isn't an object
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				line, col := lineColumn(data, start)
+				return nil, fmt.Errorf("record %d: line %d, column %d: expected a pair object, got %s", i, line, col, typeErr.Value)
+			}
+			return nil, fmt.Errorf("record %d: %v", i, locateJSONError(data, err, start))
+		}
+		line, _ := lineColumn(data, start)
+
+		var pair ragbot.InputOutputPair
+		for _, name := range []string{inputField, outputField, "outputs"} {
+			_, ok, err := lookupField(record, name)
+			if err != nil {
+				return nil, fmt.Errorf("record %d (line %d): %v", i, line, err)
+			}
+			if !ok && name != "outputs" {
+				return nil, fmt.Errorf("record %d (line %d): missing field %q", i, line, name)
+			}
+		}
+		raw, _, _ := lookupField(record, inputField)
+		if err := json.Unmarshal(raw, &pair.Input); err != nil {
+			return nil, fmt.Errorf("record %d (line %d): field %q is not a string", i, line, inputField)
+		}
+		raw, _, _ = lookupField(record, outputField)
+		outputs, err := parseOutputs(raw)
+		if err != nil {
+			return nil, fmt.Errorf("record %d (line %d): field %q %v", i, line, outputField, err)
+		}
+		// Exported records carry every response in "outputs" next to the first in "output"
+		if raw, ok, _ := lookupField(record, "outputs"); ok && len(outputs) == 1 {
+			if all, err := parseOutputs(raw); err == nil && len(all) > 1 && all[0] == outputs[0] {
+				outputs = all
+			}
+		}
+		pair.Output = outputs[0]
+		if len(outputs) > 1 {
+			pair.Outputs = outputs
+		}
+
+		if strictPairs {
+			for key := range record {
+				if !strings.EqualFold(key, inputField) && !strings.EqualFold(key, outputField) && !strings.EqualFold(key, "outputs") {
+					logger.Warn("unexpected field in pairs file", "record", i, "line", line, "field", key)
+				}
+			}
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, locateJSONError(data, err, dec.InputOffset())
+	}
+	return pairs, nil
+}
+
+// An output field holding one response as a string, or several as an array of strings
+// for a one-to-many pair
+func parseOutputs(raw json.RawMessage) ([]string, error) {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+	var several []string
+	if err := json.Unmarshal(raw, &several); err != nil {
+		return nil, fmt.Errorf("is not a string or an array of strings")
+	}
+	if len(several) == 0 {
+		return nil, fmt.Errorf("is an empty array")
+	}
+	return several, nil
+}
+
+func parsePairsCSV(data []byte) ([]ragbot.InputOutputPair, error) {
+	rows, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	column := func(field string) (int, error) {
+		for i, name := range rows[0] {
+			if strings.EqualFold(strings.TrimSpace(name), field) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("header has no %q column", field)
+	}
+	in, err := column(inputField)
+	if err != nil {
+		return nil, err
+	}
+	out, err := column(outputField)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]ragbot.InputOutputPair, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		pairs = append(pairs, ragbot.InputOutputPair{Input: row[in], Output: row[out]})
+	}
+	return pairs, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"geminivectortest/ragbot"
+)
+
+func TestParsePairsJSONCaseInsensitiveFields(t *testing.T) {
+	pairs, err := parsePairsJSON([]byte(`[{"input": "hi", "Input": "ignored", "Output": "Hello!"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pairs[0].Input != "hi" || pairs[0].Output != "Hello!" {
+		t.Errorf("parsed %+v, want the exact input key and the case-insensitive output key", pairs[0])
+	}
+
+	for i := 0; i < 20; i++ {
+		_, err := parsePairsJSON([]byte(`[{"Input": "a", "INPUT": "b", "output": "x"}]`))
+		if err == nil || !strings.Contains(err.Error(), `field "input" is ambiguous: keys ["INPUT" "Input"]`) {
+			t.Fatalf("parsePairsJSON = %v, want an ambiguous field error", err)
+		}
+	}
+}
+
+func TestParsePairsJSONErrorsPointAtTheProblem(t *testing.T) {
+	for _, tc := range []struct {
+		name, data, want string
+	}{
+		{"not an array", "\n  {\"input\": \"a\"}", "line 2, column 3: expected a JSON array of pair objects"},
+		{"syntax error", "[\n  {\"input\": \"a\", \"output\": \"b\"},\n  {\"input\": \"c\" \"output\": \"d\"}\n]", "record 1: line 3, column 18: invalid character"},
+		{"record not an object", "[\n  {\"input\": \"a\", \"output\": \"b\"},\n  [\"c\", \"d\"]\n]", "record 1: line 3, column 3: expected a pair object, got array"},
+		{"input not a string", "[\n  {\"input\": 5, \"output\": \"b\"}\n]", `record 0 (line 2): field "input" is not a string`},
+		{"output of the wrong type", "[\n  {\"input\": \"a\", \"output\": 5}\n]", `record 0 (line 2): field "output" is not a string or an array of strings`},
+		{"missing field", "[\n  {\"input\": \"a\", \"output\": \"b\"},\n\n  {\"input\": \"a\"}\n]", `record 1 (line 4): missing field "output"`},
+		{"unterminated", "[\n  {\"input\": \"a\", \"output\": \"b\"}\n", "line 3, column 1: unexpected end of JSON input"},
+	} {
+		_, err := parsePairsJSON([]byte(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: parsePairsJSON = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	data := []byte("ab\ncd\n")
+	for _, tc := range []struct {
+		offset       int64
+		line, column int
+	}{
+		{0, 1, 1},
+		{2, 1, 3},
+		{3, 2, 1},
+		{4, 2, 2},
+		{-5, 1, 1},
+		{100, 3, 1},
+	} {
+		if line, col := lineColumn(data, tc.offset); line != tc.line || col != tc.column {
+			t.Errorf("lineColumn(%d) = %d:%d, want %d:%d", tc.offset, line, col, tc.line, tc.column)
+		}
+	}
+}
+
+func TestExpandPairsEnv(t *testing.T) {
+	t.Setenv("SUPPORT_PHONE", "555-0100")
+	os.Unsetenv("NOT_SET_FOR_TEST")
+	pairs := []ragbot.InputOutputPair{
+		{Input: "support number", Output: "Call ${SUPPORT_PHONE}", Outputs: []string{"Call ${SUPPORT_PHONE}", "Dial ${SUPPORT_PHONE} now"}},
+		{Input: "cancellation fee", Output: "It costs $5, see ${NOT_SET_FOR_TEST}"},
+	}
+
+	got, err := expandPairsEnv(pairs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ragbot.InputOutputPair{
+		{Input: "support number", Output: "Call 555-0100", Outputs: []string{"Call 555-0100", "Dial 555-0100 now"}},
+		{Input: "cancellation fee", Output: "It costs $5, see ${NOT_SET_FOR_TEST}"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expanded %+v, want %+v", got, want)
+	}
+
+	if _, err := expandPairsEnv(pairs, true); err == nil || !strings.Contains(err.Error(), "record 1: environment variable NOT_SET_FOR_TEST is not set") {
+		t.Errorf("strict expansion = %v, want the unset variable reported", err)
+	}
+	if _, err := expandPairsEnv(pairs[:1], true); err != nil {
+		t.Errorf("strict expansion with every variable set = %v", err)
+	}
+}
+
+func TestParsePairsMarkdown(t *testing.T) {
+	data := "# Rider FAQ\nIntro text.\n\n" +
+		"## How do I cancel? ##\nOpen the trip and tap Cancel.\n\n### Fees\nFree within 2 minutes.\n\n" +
+		"## Where is my driver?\n\n" +
+		"## Can I pay by card?\nYes:\n```\n## not a question\n```\nAny card works.\n" +
+		"# Drivers\nDriver notes are not answers.\n"
+
+	pairs, err := parsePairsMarkdown([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ragbot.InputOutputPair{
+		{Input: "How do I cancel?", Output: "Open the trip and tap Cancel.\n\n### Fees\nFree within 2 minutes."},
+		{Input: "Can I pay by card?", Output: "Yes:\n```\n## not a question\n```\nAny card works."},
+	}
+	if !reflect.DeepEqual(pairs, want) {
+		t.Errorf("parsed %q, want %q", pairs, want)
+	}
+
+	if _, err := parsePairsMarkdown([]byte("# Title\n## Empty\n")); err == nil {
+		t.Error("a FAQ without answers should fail")
+	}
+}
+
+func TestLoadPairsDirReadsInPathOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"b.json":      `[{"input": "from b", "output": "json"}]`,
+		"a.csv":       "input,output\nfrom a,csv\n",
+		"a/z.md":      "## from a/z\nmarkdown\n",
+		"notes.txt":   "ignored",
+		"empty/.keep": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pairs, err := loadPairsDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inputs []string
+	for _, p := range pairs {
+		inputs = append(inputs, p.Input)
+	}
+	if want := []string{"from a/z", "from a", "from b"}; !reflect.DeepEqual(inputs, want) {
+		t.Errorf("inputs %q, want %q", inputs, want)
+	}
+
+	if _, err := loadPairsDir(filepath.Join(dir, "empty")); err == nil || !strings.Contains(err.Error(), "no .json, .csv or .md pairs files") {
+		t.Errorf("loadPairsDir on a directory without pairs files = %v", err)
+	}
+}
+
+func TestLoadPairsFailsOnMissingFile(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "typo.json")
+	if pairs, err := loadPairs(missing); err == nil {
+		t.Errorf("loadPairs on a missing file = %d pairs, want an error", len(pairs))
+	}
+	pairs, err := extractInputOutputPairs(missing)
+	if err != nil || len(pairs) == 0 {
+		t.Errorf("extractInputOutputPairs on a missing default = %d pairs, %v, want the built-in examples", len(pairs), err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Pairs between progress lines when stderr is not a terminal
+const progressLineEvery = 10
+
+// Progress indicator for the bulk upload across all dimensions combined.
+// On a terminal it redraws one line in place; otherwise it logs a line every few pairs.
+type progress struct {
+	total   int
+	done    int
+	started time.Time
+	tty     bool
+}
+
+func newProgress(total int) *progress {
+	return &progress{total: total, started: time.Now(), tty: isTerminal(os.Stderr)}
+}
+
+// Whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Estimated time left, from the average time per pair so far
+func (p *progress) eta() time.Duration {
+	if p.done == 0 {
+		return 0
+	}
+	perPair := time.Since(p.started) / time.Duration(p.done)
+	return (perPair * time.Duration(p.total-p.done)).Round(time.Second)
+}
+
+// Mark one more pair as processed for the given dimension
+func (p *progress) Increment(dim int) {
+	p.done++
+	percent := float64(p.done) / float64(p.total) * 100
+
+	if p.tty {
+		const width = 30
+		filled := p.done * width / p.total
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+		fmt.Fprintf(os.Stderr, "\r%s %d/%d (%.0f%%) dim %d, ETA %s   ", bar, p.done, p.total, percent, dim, p.eta())
+		if p.done == p.total {
+			fmt.Fprintln(os.Stderr)
+		}
+		return
+	}
+
+	if p.done%progressLineEvery == 0 || p.done == p.total {
+		logger.Info("progress", "done", p.done, "total", p.total, "percent", fmt.Sprintf("%.0f", percent), "dim", dim, "eta", p.eta())
+	}
+}
@@ -1,197 +1,398 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
-	"net/http"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 
-	"github.com/joho/godotenv"
+	"geminivectortest/ragbot"
 )
 
-// Pinecone API key and environment(remove when running both together)
-var (
-	geminiAPIKey   = os.Getenv("GEMINI_API_KEY")
-	pineconeAPIKey = os.Getenv("PINECONE_API_KEY")
-	pineconeEnv1   = map[string]string{
-		"chatbot-embeddings-384-2x9jann":  "aped-4627-b74a",
-		"chatbot-embeddings-512-2x9jann":  "aped-4627-b74a",
-		"chatbot-embeddings-1024-2x9jann": "aped-4627-b74a",
-	}
+// Print one JSON object per query instead of the human-readable report
+var jsonOutput bool
+
+// Template variables for answers, from repeated --var name=value flags
+var answerVars = map[string]string{}
 
-	// Three different indexes for different embedding dimensions
-	indexes = map[int]string{
-		384:  "chatbot-embeddings-384-2x9jann",
-		512:  "chatbot-embeddings-512-2x9jann",
-		1024: "chatbot-embeddings-1024-2x9jann",
+// Parse one --var name=value flag into answerVars
+func parseAnswerVar(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
 	}
-)
+	answerVars[name] = value
+	return nil
+}
 
-type EmbeddingResponse struct {
-	Embedding struct {
-		Values []float32 `json:"values"`
-	} `json:"embedding"`
+// Machine-readable form of a single match, used by --json
+type JSONMatch struct {
+	ID            string  `json:"id"`
+	Score         float32 `json:"score"`
+	WeightedScore float64 `json:"weighted_score"`
+	Confidence    float64 `json:"confidence"` // 0-100, from the calibration config
+	Input         string  `json:"input"`
+	Output        string  `json:"output"`
+	Dimension     int     `json:"dimension"`
+	Namespace     string  `json:"namespace,omitempty"`
+
+	Values []float32 `json:"values,omitempty"` // stored vector, with --include-values
 }
 
-func getEmbedding(text string, dimension int) ([]float32, error) {
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-embedding-001:embedContent?key=" + geminiAPIKey
+// Machine-readable result for one query across all dimensions.
+// Matches are ranked across dimensions by weighted score, best first.
+type JSONQueryResult struct {
+	Query        string              `json:"query"`
+	Answer       string              `json:"answer"`
+	Matches      []JSONMatch         `json:"matches"`
+	Alternatives []JSONMatch         `json:"alternatives,omitempty"`
+	ScoreStats   ragbot.ScoreSummary `json:"score_stats"`
+	Errors       map[int]string      `json:"errors,omitempty"`
+	Error        string              `json:"error,omitempty"` // set when the query failed
+}
 
-	payload := map[string]interface{}{
-		"content": map[string]interface{}{
-			"parts": []map[string]string{
-				{"text": text},
-			},
-		},
-		"taskType":             "RETRIEVAL_QUERY", // Use RETRIEVAL_QUERY for semantic search
-		"outputDimensionality": dimension,
+// Machine-readable form of ranked matches, never nil
+func jsonMatches(matches []ragbot.RankedMatch) []JSONMatch {
+	out := []JSONMatch{}
+	for _, match := range matches {
+		out = append(out, JSONMatch{
+			ID:            match.ID,
+			Score:         match.Score,
+			WeightedScore: match.WeightedScore,
+			Confidence:    match.Confidence,
+			Input:         match.Input,
+			Output:        match.Output,
+			Dimension:     match.Dimension,
+			Namespace:     match.Namespace,
+			Values:        match.Values,
+		})
 	}
+	return out
+}
 
-	body, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+// Error messages per dimension, nil when there are none
+func jsonErrors(errs map[int]error) map[int]string {
+	var out map[int]string
+	for dim, err := range errs {
+		if out == nil {
+			out = map[int]string{}
+		}
+		out[dim] = err.Error()
+	}
+	return out
+}
+
+// Query embedding from --vector-file; when set, queries search with it instead of embedding text
+var queryVector []float32
 
-	res, err := http.DefaultClient.Do(req)
+// Read a JSON array of floats, such as the output of the embed command
+func readVectorFile(filename string) ([]float32, error) {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("API request failed: %v", err)
+		return nil, fmt.Errorf("failed to read vector file: %v", err)
 	}
-	defer res.Body.Close()
-
-	if res.StatusCode != 200 {
-		return nil, fmt.Errorf("API returned status %d", res.StatusCode)
+	var vector []float32
+	if err := json.Unmarshal(data, &vector); err != nil {
+		return nil, fmt.Errorf("vector file %s is not a JSON array of numbers: %v", filename, err)
 	}
-
-	var resp EmbeddingResponse
-	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+	if len(vector) == 0 {
+		return nil, fmt.Errorf("vector file %s is empty", filename)
 	}
-
-	return resp.Embedding.Values, nil
+	return vector, nil
 }
 
-// Query interface to search similar inputs and get appropriate responses
-type QueryResult struct {
-	Matches []struct {
-		ID       string  `json:"id"`
-		Score    float32 `json:"score"`
-		Metadata struct {
-			Input     string `json:"input"`
-			Output    string `json:"output"`
-			Dimension int    `json:"dimension"`
-		} `json:"metadata"`
-	} `json:"matches"`
-}
-
-// Search for similar inputs in Pinecone
-func searchSimilar(userInput string, dimension int, topK int) (*QueryResult, error) {
-	// First get embedding for user input
-	embedding, err := getEmbedding(userInput, dimension)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding: %v", err)
+// Answer userInput, or queryVector when --vector-file was given
+func answerQuery(ctx context.Context, userInput string) (*ragbot.Response, error) {
+	if queryVector != nil {
+		return bot.AnswerVector(ctx, queryVector, answerVars)
 	}
+	return bot.AnswerWith(ctx, userInput, answerVars)
+}
 
-	// Query Pinecone
-	indexName := indexes[dimension]
-	pineconeEnv := pineconeEnv1[indexName]
-
-	url := fmt.Sprintf("https://%s.svc.%s.pinecone.io/query", indexName, pineconeEnv)
-
-	payload := map[string]interface{}{
-		"vector":          embedding,
-		"topK":            topK,
-		"includeMetadata": true,
-		"namespace":       "chatbot-training-data-test-semantic",
+// Print search results for a query as a single JSON object on one line
+func printJSONResponse(ctx context.Context, userInput string) {
+	out, err := jsonResponse(ctx, userInput)
+	if out.Error != "" {
+		fmt.Fprintf(os.Stderr, "query failed: %v\n", err)
+		return
 	}
 
-	data, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewReader(data))
-	req.Header.Add("Api-Key", pineconeAPIKey)
-	req.Header.Add("Content-Type", "application/json")
-
-	res, err := http.DefaultClient.Do(req)
+	data, err := json.Marshal(out)
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to encode result: %v\n", err)
+		return
 	}
-	defer res.Body.Close()
+	fmt.Println(string(data))
+}
 
-	var result QueryResult
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
+// Search results for a query in their JSON form. A query that fails has Error set,
+// alongside the error itself: one failing before any search has only Query and Error,
+// one failing in every dimension also lists the per-dimension Errors.
+func jsonResponse(ctx context.Context, userInput string) (JSONQueryResult, error) {
+	resp, err := answerQuery(ctx, userInput)
+	if resp == nil {
+		return JSONQueryResult{Query: userInput, Matches: []JSONMatch{}, Error: err.Error()}, err
 	}
-
-	return &result, nil
+	recordScores(resp)
+	out := JSONQueryResult{
+		Query:        userInput,
+		Answer:       resp.Answer,
+		Matches:      jsonMatches(resp.Matches),
+		Alternatives: jsonMatches(resp.Alternatives),
+		ScoreStats:   ragbot.SummarizeScores(resp.Scores()),
+		Errors:       jsonErrors(resp.Errors),
+	}
+	if err != nil {
+		out.Error = err.Error()
+	}
+	return out, err
 }
 
 // Generate enhanced response using vector search results
-func generateEnhancedResponse(userInput string) {
+func generateEnhancedResponse(ctx context.Context, userInput string) {
+	if jsonOutput {
+		printJSONResponse(ctx, userInput)
+		return
+	}
+
 	fmt.Println(strings.Repeat("=", 60))
 	fmt.Println(strings.Repeat("=", 60))
 
-	dimensions := []int{384, 512, 1024}
+	resp, err := answerQuery(ctx, userInput)
+	if resp == nil {
+		fmt.Printf("❌ Error: %v\n", err)
+		return
+	}
+	recordScores(resp)
+	dimensions := resp.Dimensions()
 
 	for _, dim := range dimensions {
 		fmt.Printf("\n📊 Dimension %d Results:\n", dim)
 		fmt.Println(strings.Repeat("-", 30))
 
-		results, err := searchSimilar(userInput, dim, 3)
-		if err != nil {
+		if err, ok := resp.Errors[dim]; ok {
 			fmt.Printf("❌ Error: %v\n", err)
 			continue
 		}
 
-		if len(results.Matches) == 0 {
+		if len(resp.Results[dim].Matches) == 0 {
 			fmt.Println("No matches found")
 			continue
 		}
 
-		for i, match := range results.Matches {
-			fmt.Printf("%d. Score: %.3f\n", i+1, match.Score)
-			fmt.Printf("   Similar Input: %s\n", match.Metadata.Input)
+		for i, match := range resp.Results[dim].Matches {
+			fmt.Printf("%d. Score: %.3f (confidence %.0f%%)\n", i+1, match.Score, cfg.Confidence(match.Score))
+			fmt.Printf("   Similar Input: %s\n", maybeHighlight(userInput, match.Metadata.Input))
 			fmt.Printf("   Response: %s\n", match.Metadata.Output)
+			if len(cfg.SearchNamespaces(dim)) > 1 {
+				fmt.Printf("   Namespace: %s\n", match.Metadata.Namespace)
+			}
 			fmt.Println()
 		}
 	}
+
+	if best := resp.Best; len(dimensions) > 1 && best != nil {
+		fmt.Println("\n🏆 Best Overall Match:")
+		fmt.Println(strings.Repeat("-", 30))
+		fmt.Printf("Dimension %d, Score: %.3f (weighted %.3f, confidence %.0f%%)\n", best.Dimension, best.Score, best.WeightedScore, best.Confidence)
+		fmt.Printf("   Similar Input: %s\n", maybeHighlight(userInput, best.Input))
+		fmt.Printf("   Response: %s\n", best.Output)
+	}
+
+	if len(resp.Alternatives) > 1 {
+		fmt.Println("\n🤔 Did you mean one of these?")
+		for i, alt := range resp.Alternatives {
+			fmt.Printf("%d. %s (score %.3f, confidence %.0f%%)\n", i+1, alt.Output, alt.Score, alt.Confidence)
+		}
+	}
+
+	if resp.Confident && resp.Answer != resp.Best.Output {
+		fmt.Printf("\n💬 Answer: %s\n", resp.Answer)
+	}
+
+	if resp.Ambiguous {
+		fmt.Printf("\n⚠️  Low confidence: %q scored within %.3f of the best match\n", resp.RunnerUp.Output, cfg.AmbiguityEpsilon)
+		fmt.Printf("🤖 %s\n", resp.Answer)
+	}
+}
+
+// Canned queries run by "query test" and "bench"
+var sampleQueries = []string{
+	"I want to book a ride for tomorrow morning",
+	"Cancel my pickup for today",
+	"What time is my ride tomorrow?",
+	"Show me available shifts",
+	"Book transport for next week",
 }
 
 // Test the query functionality
-func testQueries() {
-	testInputs := []string{
-		"I want to book a ride for tomorrow morning",
-		"Cancel my pickup for today",
-		"What time is my ride tomorrow?",
-		"Show me available shifts",
-		"Book transport for next week",
+func testQueries(ctx context.Context) {
+	if !jsonOutput {
+		fmt.Println("🧪 Testing Vector Search Functionality...")
 	}
 
-	fmt.Println("🧪 Testing Vector Search Functionality...")
-
-	for _, input := range testInputs {
-		generateEnhancedResponse(input)
-		fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
+	for _, input := range sampleQueries {
+		generateEnhancedResponse(ctx, input)
+		if !jsonOutput {
+			fmt.Println("\n" + strings.Repeat("=", 80) + "\n")
+		}
 	}
 }
 
-// Main function for query testing
-func main() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file")
+// Search the indexes interactively, run the canned test queries with "query test",
+// hold a multi-turn conversation with "query chat", or run newline-delimited queries
+// from stdin or --queries-file with "query batch"
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.BoolVar(&jsonOutput, "json", false, "print results as one JSON object per query")
+	hybrid := fs.Bool("hybrid", false, "add a BM25-style sparse vector to each query (requires a dotproduct index)")
+	alpha := fs.Float64("alpha", 0, "hybrid weighting between dense (1.0) and sparse (0.0) (default: hybrid_alpha from the config)")
+	lexicalAlpha := fs.Float64("lexical-alpha", -1, "weight of exact keyword overlap in the ranking, 0 to 1 (default: lexical_alpha from the config)")
+	alternatives := fs.Int("alternatives", -1, "list up to N distinct alternative answers (default: alternatives from the config)")
+	topK := fs.Int("topk", 0, "number of matches to return per dimension (default: top_k from the config)")
+	dimension := fs.Int("dim", 0, "only search the index for this dimension (default: query_dimensions from the config)")
+	autoDim := fs.Bool("auto-dim", false, "search one dimension chosen from the query's length and keyword density (see auto_dimension in the config)")
+	fs.BoolVar(&scoreStats, "score-stats", false, "print the score distribution across all queries of a test or batch run")
+	namespaces := fs.String("namespaces", "", "comma-separated namespaces to search and merge (default: query_namespaces from the config)")
+	queriesFile := fs.String("queries-file", "", "run each line of this file as a query and print JSON results (implies batch)")
+	batchOut := fs.String("out", "", "write batch results as JSON lines to this file instead of stdout")
+	fs.BoolVar(&highlight, "highlight", false, "color words shared by the query and each matched input (terminal only)")
+	fs.Func("var", "template variable for answers as name=value, e.g. driverName=Asha (repeatable)", parseAnswerVar)
+	vectorFile := fs.String("vector-file", "", "search with the embedding in this JSON array file instead of embedding a query (index chosen by its length)")
+	includeValues := fs.Bool("include-values", false, "return each match's stored vector in --json output")
+	ensemble := fs.Bool("ensemble", false, "search with every model in the ensemble config and merge their scores (uses --dim, default 1024)")
+	parseFlags(fs, args)
+
+	// Flags win over the config file; the bot validates the result
+	configOverrides = append(configOverrides, func(c *ragbot.Config) {
+		if *hybrid {
+			c.Hybrid = true
+		}
+		if *alpha != 0 {
+			c.HybridAlpha = *alpha
+		}
+		if *lexicalAlpha >= 0 {
+			c.LexicalAlpha = *lexicalAlpha
+		}
+		if *topK != 0 {
+			c.TopK = *topK
+		}
+		if *alternatives >= 0 {
+			c.Alternatives = *alternatives
+		}
+		if *dimension != 0 {
+			c.QueryDimensions = []int{*dimension}
+		}
+		if *autoDim {
+			c.AutoDimension.Enabled = true
+		}
+		if *includeValues {
+			c.IncludeValues = true
+		}
+		if *namespaces != "" {
+			c.QueryNamespaces = splitList(*namespaces)
+		}
+	})
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *vectorFile != "" {
+		vector, err := readVectorFile(*vectorFile)
+		if err != nil {
+			logger.Error("query failed", "err", err)
+			os.Exit(1)
+		}
+		if *dimension != 0 && len(vector) != *dimension {
+			logger.Error("vector length doesn't match --dim", "values", len(vector), "dim", *dimension)
+			os.Exit(2)
+		}
+		queryVector = vector
+		generateEnhancedResponse(ctx, fmt.Sprintf("<vector from %s>", *vectorFile))
+		return
+	}
+
+	// Keep stdout clean for JSON consumers
+	statsOut := os.Stdout
+	if jsonOutput || *queriesFile != "" || (fs.NArg() > 0 && fs.Arg(0) == "batch") {
+		statsOut = os.Stderr
+	}
+
+	if fs.NArg() > 0 && fs.Arg(0) == "test" {
+		testQueries(ctx)
+		if scoreStats {
+			printScoreDistribution(statsOut)
+		}
+		return
+	}
+
+	if *queriesFile != "" || (fs.NArg() > 0 && fs.Arg(0) == "batch") {
+		jsonOutput = true
+		r, err := openQueries(*queriesFile)
+		if err != nil {
+			logger.Error("batch failed", "err", err)
+			os.Exit(1)
+		}
+		defer r.Close()
+		w, err := createBatchOutput(*batchOut)
+		if err != nil {
+			logger.Error("batch failed", "err", err)
+			os.Exit(1)
+		}
+		defer w.Close()
+		if err := runBatch(ctx, r, w); err != nil {
+			logger.Error("batch failed", "err", err)
+			os.Exit(1)
+		}
+		if scoreStats {
+			printScoreDistribution(statsOut)
+		}
+		return
 	}
-	geminiAPIKey = os.Getenv("GEMINI_API_KEY")
-	pineconeAPIKey = os.Getenv("PINECONE_API_KEY")
-	if geminiAPIKey == "" {
-		fmt.Println("❌ GEMINI_API_KEY not set")
+
+	if fs.NArg() > 0 && fs.Arg(0) == "chat" {
+		dim := 1024
+		if *dimension != 0 {
+			dim = *dimension
+		}
+		runConversation(ctx, dim, cfg.TopK)
 		return
 	}
-	if pineconeAPIKey == "" {
-		fmt.Println("❌ PINECONE_API_KEY not set")
+
+	var input string
+
+	if *ensemble {
+		dim := 1024
+		if *dimension != 0 {
+			dim = *dimension
+		}
+		if !jsonOutput {
+			fmt.Print("> ")
+		}
+		input, err := readQueryLine(os.Stdin)
+		if err != nil {
+			logger.Error("query failed", "err", err)
+			os.Exit(1)
+		}
+		printEnsembleResponse(ctx, input, dim)
 		return
 	}
 
-	if len(os.Args) > 1 && os.Args[1] == "test" {
-		testQueries()
+	if jsonOutput {
+		input, err := readQueryLine(os.Stdin)
+		if err != nil {
+			logger.Error("query failed", "err", err)
+			os.Exit(1)
+		}
+		generateEnhancedResponse(ctx, input)
 		return
 	}
 
@@ -199,12 +400,34 @@ func main() {
 	fmt.Println("🤖 Chatbot Vector Search - Interactive Mode")
 	fmt.Println("Type your queries (or 'quit' to exit):")
 
-	var input string
-
 	fmt.Print("\n> ")
 	fmt.Scanln(&input)
 
-	generateEnhancedResponse(input)
+	generateEnhancedResponse(ctx, input)
 
 	fmt.Println("👋 Goodbye!")
 }
+
+// Read one whole query line from r, trimmed. An empty query is an error.
+func readQueryLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read query: %v", err)
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", fmt.Errorf("no query given on stdin")
+	}
+	return line, nil
+}
+
+// Entries of a comma-separated flag value, trimmed, without empty ones
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
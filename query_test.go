@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadQueryLine(t *testing.T) {
+	for in, want := range map[string]string{
+		"Cancel my ride tomorrow\n": "Cancel my ride tomorrow",
+		"  where is my cab  \r\n":   "where is my cab",
+		"no trailing newline":       "no trailing newline",
+		"first line\nsecond line\n": "first line",
+	} {
+		got, err := readQueryLine(strings.NewReader(in))
+		if err != nil || got != want {
+			t.Errorf("readQueryLine(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "\n", "   \n"} {
+		if got, err := readQueryLine(strings.NewReader(in)); err == nil {
+			t.Errorf("readQueryLine(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	for in, want := range map[string][]string{
+		"faq,support":       {"faq", "support"},
+		" faq , support ,,": {"faq", "support"},
+		" , ":               nil,
+	} {
+		if got := splitList(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitList(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
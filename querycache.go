@@ -0,0 +1,94 @@
+package main
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"geminivectortest/ragbot"
+)
+
+// LRU cache of server answers keyed by normalized query text and template variables.
+// The whole Response is cached, so a hit repeats the threshold and fallback decision
+// without embedding or querying Pinecone again.
+type queryCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type queryCacheEntry struct {
+	key     string
+	resp    *ragbot.Response
+	expires time.Time
+}
+
+func newQueryCache(maxSize int, ttl time.Duration) *queryCache {
+	return &queryCache{ttl: ttl, maxSize: maxSize, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Cache key: the query lowercased with whitespace collapsed, plus the variables in name order
+func queryCacheKey(req queryRequest) string {
+	key := strings.Join(strings.Fields(strings.ToLower(req.Query)), " ")
+	names := make([]string, 0, len(req.Variables))
+	for name := range req.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key += "\x00" + name + "=" + req.Variables[name]
+	}
+	return key
+}
+
+// Cached response for key, counting the hit or miss. Expired entries are dropped.
+func (c *queryCache) get(key string) (*ragbot.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if ok && time.Now().After(el.Value.(*queryCacheEntry).expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	c.order.MoveToFront(el)
+	return el.Value.(*queryCacheEntry).resp, true
+}
+
+// Store a response, evicting the least recently used entry when full
+func (c *queryCache) put(key string, resp *ragbot.Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &queryCacheEntry{key: key, resp: resp, expires: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*queryCacheEntry).key)
+	}
+}
+
+// Number of cached entries, including expired ones not yet dropped
+func (c *queryCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,68 @@
+package ragbot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Adaptive pacing for Gemini: every 429 halves the request rate, and after
+// adaptiveRecoverAfter consecutive successes the rate grows back by adaptiveRecoverStep,
+// never above the configured EmbeddingRPM. This converges on the fastest rate the
+// quota actually allows instead of trusting a static guess.
+const (
+	adaptiveRecoverAfter = 20
+	adaptiveRecoverStep  = 1.1
+	adaptiveMinRPM       = 6 // slowest pace: one request every 10s
+)
+
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	limiter   *rate.Limiter
+	max       rate.Limit
+	successes int
+	now       func() time.Time // time.Now, or a fake clock in tests
+}
+
+func newAdaptiveLimiter(rpm int) *adaptiveLimiter {
+	max := rate.Every(time.Minute / time.Duration(rpm))
+	return &adaptiveLimiter{limiter: rate.NewLimiter(max, 1), max: max, now: time.Now}
+}
+
+// Wait for the next request slot
+func (a *adaptiveLimiter) Wait(ctx context.Context) error {
+	return a.limiter.Wait(ctx)
+}
+
+// Current gap between requests
+func (a *adaptiveLimiter) Delay() time.Duration {
+	return time.Duration(float64(time.Second) / float64(a.limiter.Limit()))
+}
+
+// Record a 429 and slow down. Returns the new delay between requests.
+func (a *adaptiveLimiter) Throttled() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.successes = 0
+	limit := max(a.limiter.Limit()/2, rate.Every(time.Minute/adaptiveMinRPM))
+	a.limiter.SetLimitAt(a.now(), limit)
+	return a.Delay()
+}
+
+// Record a success. Returns the new delay and true when the pace was raised.
+func (a *adaptiveLimiter) Succeeded() (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.limiter.Limit() >= a.max {
+		return a.Delay(), false
+	}
+	a.successes++
+	if a.successes < adaptiveRecoverAfter {
+		return a.Delay(), false
+	}
+	a.successes = 0
+	a.limiter.SetLimitAt(a.now(), min(a.limiter.Limit()*adaptiveRecoverStep, a.max))
+	return a.Delay(), true
+}
@@ -0,0 +1,44 @@
+package ragbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiterBacksOffAndRecovers(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := newAdaptiveLimiter(60)
+	a.now = func() time.Time { return now }
+
+	if d := a.Throttled(); d != 2*time.Second {
+		t.Fatalf("delay after a 429 = %v, want 2s (60 RPM halved)", d)
+	}
+	if !a.limiter.AllowN(now, 1) || a.limiter.AllowN(now.Add(time.Second), 1) || !a.limiter.AllowN(now.Add(2*time.Second), 1) {
+		t.Error("the limiter doesn't pace requests 2s apart after the 429")
+	}
+	for i := 0; i < 10; i++ {
+		a.Throttled()
+	}
+	if d := a.Delay(); d != 10*time.Second {
+		t.Errorf("delay after repeated 429s = %v, want the %d RPM floor", d, adaptiveMinRPM)
+	}
+
+	a = newAdaptiveLimiter(60)
+	a.now = func() time.Time { return now }
+	a.Throttled()
+	for i := 1; i < adaptiveRecoverAfter; i++ {
+		if _, raised := a.Succeeded(); raised {
+			t.Fatalf("pace raised after %d successes, want %d", i, adaptiveRecoverAfter)
+		}
+	}
+	d, raised := a.Succeeded()
+	if !raised || d < 1800*time.Millisecond || d > 1820*time.Millisecond {
+		t.Errorf("after %d successes: delay %v (raised %v), want 2s / %v", adaptiveRecoverAfter, d, raised, adaptiveRecoverStep)
+	}
+	for i := 0; i < 20*adaptiveRecoverAfter; i++ {
+		a.Succeeded()
+	}
+	if d, raised := a.Succeeded(); d != time.Second || raised {
+		t.Errorf("recovered delay %v (raised %v), want the configured 1s", d, raised)
+	}
+}
@@ -0,0 +1,95 @@
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// PairID stored for pairs added one at a time with Add, which have no position in a source file
+const AddedPairID = -1
+
+// Outcome of Add at one dimension
+type AddResult struct {
+	Dimension int
+	ID        string
+	Existed   bool // the index already held this pair, so nothing was written
+	Merged    bool // the index held other outputs for the input; the pair's were added to them
+	Replaced  bool // the index held other outputs for the input, now overwritten (Replace only)
+}
+
+// Embed and upsert a single pair at every configured dimension under its input-based ID,
+// leaving every other vector alone. The input is sanitized first (Config.Sanitize).
+// Dimensions already holding the pair are skipped. A vector holding other outputs for
+// the input keeps them and its pair_id: the pair's outputs are merged in, reusing its embedding.
+// Failed dimensions are joined into the error; the results cover the rest.
+func (b *Bot) Add(ctx context.Context, pair InputOutputPair) ([]AddResult, error) {
+	return b.add(ctx, pair, false)
+}
+
+// Like Add, but a vector already holding the input gets the pair's outputs in place of its
+// own. It keeps its pair_id and embedding.
+func (b *Bot) Replace(ctx context.Context, pair InputOutputPair) ([]AddResult, error) {
+	return b.add(ctx, pair, true)
+}
+
+func (b *Bot) add(ctx context.Context, pair InputOutputPair, replace bool) ([]AddResult, error) {
+	pair.Input = b.sanitize(pair.Input)
+	if err := CheckPair(pair); err != nil {
+		return nil, fmt.Errorf("rejected pair: %w", err)
+	}
+
+	if b.local != nil {
+		b.local.add([]InputOutputPair{pair})
+		var results []AddResult
+		for _, dim := range b.cfg.Dimensions() {
+			results = append(results, AddResult{Dimension: dim, ID: contentID(pair, dim)})
+		}
+		return results, nil
+	}
+
+	var results []AddResult
+	var failed []error
+	for _, dim := range b.cfg.Dimensions() {
+		id := contentID(pair, dim)
+		existing, err := b.fetchVectors(ctx, dim, b.cfg.NamespaceFor(dim), []string{id})
+		if err != nil {
+			failed = append(failed, fmt.Errorf("dimension %d: failed to check for an existing vector: %v", dim, err))
+			continue
+		}
+		old, found := existing[id]
+		result := AddResult{Dimension: dim, ID: id}
+
+		vector := b.buildVector(pair, AddedPairID, dim, old.Values)
+		if found {
+			old.ID = id
+			if replace {
+				vector.Metadata.PairID = old.Metadata.PairID
+				result.Replaced = vector.Metadata.ContentHash != contentHash(old.Pair())
+			} else {
+				vector = old
+				vector.Metadata.Outputs = slices.Clone(old.Metadata.Outputs)
+				mergeOutputs(&vector, pair)
+				result.Merged = vector.Metadata.ContentHash != contentHash(old.Pair())
+			}
+			if !result.Replaced && !result.Merged {
+				result.Existed = true
+				results = append(results, result)
+				continue
+			}
+		}
+		if len(vector.Values) != dim {
+			if vector.Values, err = b.getEmbedding(ctx, pair.Input, dim, TaskRetrievalDocument); err != nil {
+				failed = append(failed, fmt.Errorf("dimension %d: %v", dim, err))
+				continue
+			}
+		}
+		if err := b.upsertToPinecone(ctx, []Vector{vector}, dim); err != nil {
+			failed = append(failed, fmt.Errorf("dimension %d: %v", dim, err))
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(failed...)
+}
@@ -0,0 +1,79 @@
+package ragbot
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAddMergesIntoOneToManyVector(t *testing.T) {
+	stored := InputOutputPair{Input: "cancel my ride", Output: "Done", Outputs: []string{"Done", "Cancelled"}}
+	index := &fakeIndex{vectors: map[string]Vector{}}
+	b := newTestBot(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("adding to a stored input should reuse its embedding")
+	}, index.ServeHTTP)
+	for _, dim := range b.cfg.Dimensions() {
+		v := b.buildVector(stored, 7, dim, make([]float32, dim))
+		index.vectors[v.ID] = v
+	}
+
+	results, err := b.Add(context.Background(), InputOutputPair{Input: "cancel my ride", Output: "Your ride is off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(b.cfg.Dimensions()) {
+		t.Fatalf("%d results, want one per dimension", len(results))
+	}
+	for _, r := range results {
+		if !r.Merged {
+			t.Errorf("dimension %d: result %+v, want merged", r.Dimension, r)
+		}
+		v := index.vectors[r.ID]
+		if want := []string{"Done", "Cancelled", "Your ride is off"}; !reflect.DeepEqual(v.Metadata.Outputs, want) {
+			t.Errorf("dimension %d: outputs %q, want %q", r.Dimension, v.Metadata.Outputs, want)
+		}
+		if v.Metadata.Output != "Done" || v.Metadata.PairID != 7 {
+			t.Errorf("dimension %d: output %q pair_id %d, want the stored ones kept", r.Dimension, v.Metadata.Output, v.Metadata.PairID)
+		}
+	}
+
+	results, err = b.Add(context.Background(), InputOutputPair{Input: "cancel my ride", Output: "Cancelled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(b.cfg.Dimensions()) {
+		t.Fatalf("%d results, want one per dimension", len(results))
+	}
+	for _, r := range results {
+		if !r.Existed {
+			t.Errorf("dimension %d: result %+v, want an output already stored to be skipped", r.Dimension, r)
+		}
+	}
+}
+
+func TestReplaceOverwritesOutputsKeepingPairID(t *testing.T) {
+	stored := InputOutputPair{Input: "cancel my ride", Output: "Done", Outputs: []string{"Done", "Cancelled"}}
+	index := &fakeIndex{vectors: map[string]Vector{}}
+	b := newTestBot(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("replacing a stored input should reuse its embedding")
+	}, index.ServeHTTP)
+	for _, dim := range b.cfg.Dimensions() {
+		v := b.buildVector(stored, 7, dim, make([]float32, dim))
+		index.vectors[v.ID] = v
+	}
+
+	results, err := b.Replace(context.Background(), InputOutputPair{Input: "cancel my ride", Output: "Your ride is off"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(b.cfg.Dimensions()) {
+		t.Fatalf("%d results, want one per dimension", len(results))
+	}
+	for _, r := range results {
+		v := index.vectors[r.ID]
+		if !r.Replaced || v.Metadata.Output != "Your ride is off" || v.Metadata.Outputs != nil || v.Metadata.PairID != 7 {
+			t.Errorf("dimension %d: result %+v, metadata %+v, want the outputs replaced under pair_id 7", r.Dimension, r, v.Metadata)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ragbot
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Default word count at or below which a query counts as short when AutoDimension has no rules
+const defaultShortQueryWords = 3
+
+// Single-index search chosen per query instead of searching every dimension.
+// Rules are tried in order and the first match picks the dimension; a query no rule
+// matches is uncertain and searches every dimension as usual.
+type AutoDimensionConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Empty means one rule: queries of up to 3 words use the smallest searched dimension
+	Rules []DimensionRule `yaml:"rules"`
+}
+
+// Query characteristics that select a dimension. Zero bounds are unset.
+type DimensionRule struct {
+	MinWords int `yaml:"min_words"`
+	MaxWords int `yaml:"max_words"`
+
+	// Share of words that aren't stopwords, from 0 to 1
+	MinKeywordDensity float64 `yaml:"min_keyword_density"`
+	MaxKeywordDensity float64 `yaml:"max_keyword_density"`
+
+	Dimension int `yaml:"dimension"`
+}
+
+// Common words that carry little meaning for keyword density
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "and": true, "or": true, "but": true, "if": true,
+	"of": true, "to": true, "in": true, "on": true, "at": true, "for": true, "with": true,
+	"by": true, "from": true, "is": true, "are": true, "was": true, "were": true, "be": true,
+	"been": true, "am": true, "do": true, "does": true, "did": true, "can": true, "could": true,
+	"will": true, "would": true, "should": true, "i": true, "me": true, "my": true, "you": true,
+	"your": true, "it": true, "its": true, "we": true, "our": true, "they": true, "this": true,
+	"that": true, "what": true, "how": true, "why": true, "when": true, "where": true,
+	"there": true, "here": true, "so": true, "not": true, "have": true, "has": true, "please": true,
+}
+
+// Query characteristics AutoDimension rules select on
+type QueryFeatures struct {
+	Words          int
+	KeywordDensity float64
+}
+
+// Word count and keyword density of a query
+func AnalyzeQuery(query string) QueryFeatures {
+	tokens := tokenize(query)
+	f := QueryFeatures{Words: len(tokens)}
+	if len(tokens) == 0 {
+		return f
+	}
+	keywords := 0
+	for _, tok := range tokens {
+		if !stopwords[tok] {
+			keywords++
+		}
+	}
+	f.KeywordDensity = float64(keywords) / float64(len(tokens))
+	return f
+}
+
+// Whether a query's features fall within every bound the rule sets
+func (r DimensionRule) matches(f QueryFeatures) bool {
+	return f.Words >= r.MinWords &&
+		(r.MaxWords == 0 || f.Words <= r.MaxWords) &&
+		f.KeywordDensity >= r.MinKeywordDensity &&
+		(r.MaxKeywordDensity == 0 || f.KeywordDensity <= r.MaxKeywordDensity)
+}
+
+// Readable form of the rule, used when logging why a dimension was chosen
+func (r DimensionRule) String() string {
+	s := fmt.Sprintf("words %d..", r.MinWords)
+	if r.MaxWords > 0 {
+		s += fmt.Sprint(r.MaxWords)
+	}
+	if r.MinKeywordDensity > 0 || r.MaxKeywordDensity > 0 {
+		s += fmt.Sprintf(", keyword density %.2f..", r.MinKeywordDensity)
+		if r.MaxKeywordDensity > 0 {
+			s += fmt.Sprintf("%.2f", r.MaxKeywordDensity)
+		}
+	}
+	return s
+}
+
+// Rules in effect: the configured ones, or the short-query default
+func (c *Config) dimensionRules() []DimensionRule {
+	if len(c.AutoDimension.Rules) > 0 {
+		return c.AutoDimension.Rules
+	}
+	dims := append([]int(nil), c.SearchDimensions()...)
+	if len(dims) == 0 {
+		return nil
+	}
+	sort.Ints(dims)
+	return []DimensionRule{{MaxWords: defaultShortQueryWords, Dimension: dims[0]}}
+}
+
+// Dimensions Answer searches for query, with the reason for the choice. Without
+// AutoDimension, or when no rule matches, every one of SearchDimensions().
+func (c *Config) chooseDimensions(query string) ([]int, string) {
+	if !c.AutoDimension.Enabled {
+		return c.SearchDimensions(), "auto dimension off"
+	}
+	f := AnalyzeQuery(query)
+	for _, r := range c.dimensionRules() {
+		if r.matches(f) {
+			return []int{r.Dimension}, fmt.Sprintf("%d words, keyword density %.2f matched rule (%s)", f.Words, f.KeywordDensity, r)
+		}
+	}
+	return c.SearchDimensions(), fmt.Sprintf("%d words, keyword density %.2f matched no rule", f.Words, f.KeywordDensity)
+}
@@ -0,0 +1,42 @@
+package ragbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChooseDimensions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AutoDimension.Enabled = true
+
+	// Default rule: short queries use the smallest dimension, others search everything
+	if got, _ := cfg.chooseDimensions("cancel ride"); !reflect.DeepEqual(got, []int{384}) {
+		t.Errorf("short query searched %v, want [384]", got)
+	}
+	if got, _ := cfg.chooseDimensions("how do I change the pickup address of my ride"); !reflect.DeepEqual(got, cfg.Dimensions()) {
+		t.Errorf("long query searched %v, want every dimension", got)
+	}
+
+	cfg.AutoDimension.Rules = []DimensionRule{
+		{MinWords: 4, MinKeywordDensity: 0.7, Dimension: 512},
+		{MaxWords: 2, Dimension: 1024},
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"driver late airport pickup", []int{512}},
+		{"where is my driver now", cfg.Dimensions()}, // density 0.4 matches no rule
+		{"refund", []int{1024}},
+	}
+	for _, tt := range tests {
+		if got, reason := cfg.chooseDimensions(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("chooseDimensions(%q) = %v (%s), want %v", tt.query, got, reason, tt.want)
+		}
+	}
+
+	cfg.AutoDimension.Enabled = false
+	if got, _ := cfg.chooseDimensions("refund"); !reflect.DeepEqual(got, cfg.Dimensions()) {
+		t.Errorf("disabled auto dimension searched %v, want every dimension", got)
+	}
+}
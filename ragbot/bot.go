@@ -0,0 +1,418 @@
+// Package ragbot answers questions from a bank of input-output pairs stored as
+// Gemini embeddings in Pinecone, one index per embedding dimension.
+//
+//	bot, err := ragbot.New(cfg)
+//	report, err := bot.Index(ctx, pairs)
+//	resp, err := bot.Answer(ctx, "Cancel my ride for tomorrow")
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Library version, sent in the default User-Agent
+const Version = "0.9.0"
+
+// Client for embedding, indexing and answering. Safe for concurrent use once configured.
+type Bot struct {
+	cfg       *Config
+	client    *http.Client
+	limiter   *adaptiveLimiter // paces Gemini embedding calls, at most Config.EmbeddingRPM
+	flight    singleflight.Group
+	cache     *embeddingCache     // nil unless Config.EmbeddingCacheDir is set
+	local     *localStore         // set for the local backend, which never calls Pinecone
+	speller   *spellCorrector     // nil unless Config.Preprocess.SpellCorrect is set
+	embedders map[string]Embedder // ensemble model name -> embedder
+	log       *slog.Logger
+	verbose   bool
+	progress  func(dim int)
+	telemetry *telemetry    // counters for WritePrometheus
+	jitter    *jitterSource // randomizes retry delays, see SetRandSource
+
+	// Set once Gemini reports Config.EmbeddingModel missing, so later calls go straight
+	// to Config.FallbackEmbeddingModel
+	primaryModelMissing atomic.Bool
+
+	postProcessors []namedPostProcessor // applied to confident answers, see AddPostProcessor
+	outputs        outputPicker         // round-robin turns for one-to-many pairs
+	snapshot       *SnapshotWriter      // nil unless SetSnapshot was called
+	indexDims      indexDimensions      // actual index dimensions, see CheckIndexDimensions
+}
+
+// Create a bot from a validated copy of cfg. Logs go to slog.Default().
+func New(cfg *Config) (*Bot, error) {
+	if cfg == nil {
+		return nil, errors.New("ragbot: nil config")
+	}
+	c := *cfg
+	def := DefaultConfig()
+	if c.GeminiBaseURL == "" {
+		c.GeminiBaseURL = def.GeminiBaseURL
+	}
+	if c.EmbeddingRPM == 0 {
+		c.EmbeddingRPM = def.EmbeddingRPM
+	}
+	if c.MaxMetadataBytes == 0 {
+		c.MaxMetadataBytes = def.MaxMetadataBytes
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = def.UserAgent
+	}
+	if c.FallbackAnswer == "" {
+		c.FallbackAnswer = def.FallbackAnswer
+	}
+	if c.AmbiguityEpsilon == 0 {
+		c.AmbiguityEpsilon = def.AmbiguityEpsilon
+	}
+	if c.ClarifyAnswer == "" {
+		c.ClarifyAnswer = def.ClarifyAnswer
+	}
+	if c.MaxInputTokens == 0 {
+		c.MaxInputTokens = def.MaxInputTokens
+	}
+	if c.EmbeddingModel == "" {
+		c.EmbeddingModel = def.EmbeddingModel
+	}
+	c.GeminiRetry = c.GeminiRetry.withDefaults(def.GeminiRetry)
+	c.PineconeRetry = c.PineconeRetry.withDefaults(def.PineconeRetry)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	b := &Bot{cfg: &c, client: newHTTPClient(c.Concurrency), limiter: newAdaptiveLimiter(c.EmbeddingRPM), log: slog.Default(), telemetry: newTelemetry(), jitter: newJitterSource(c.RandomSeed)}
+	transport := newRateLimitTransport(b.client.Transport, b.jitter, func() *slog.Logger { return b.log })
+	if u, err := url.Parse(c.GeminiBaseURL); err == nil {
+		transport.setLimiter(u.Host, b.limiter)
+		transport.setPolicy(u.Host, c.GeminiRetry)
+	}
+	transport.defaultRPM = c.PineconeRPM
+	transport.defaultPolicy = c.PineconeRetry
+	b.client.Transport = transport
+	if c.Backend == BackendLocal {
+		b.local = newLocalStore()
+	}
+	if c.EmbeddingCacheDir != "" {
+		b.cache = &embeddingCache{dir: c.EmbeddingCacheDir}
+	}
+	b.embedders = map[string]Embedder{}
+	for _, m := range c.Ensemble {
+		model := m.Model
+		if model == "" {
+			model = defaultEmbeddingModel
+		}
+		b.embedders[m.Name] = geminiEmbedder{b: b, model: model}
+	}
+	if c.Preprocess.SpellCorrect {
+		speller, err := loadSpellCorrector(c.Preprocess.SpellDictionary)
+		if err != nil {
+			return nil, err
+		}
+		b.speller = speller
+	}
+	return b, nil
+}
+
+// Use src for retry jitter, making backoff delays reproducible in tests
+func (b *Bot) SetRandSource(src rand.Source) {
+	b.jitter.mu.Lock()
+	defer b.jitter.mu.Unlock()
+	b.jitter.rng = rand.New(src)
+}
+
+// Settings the bot was created with
+func (b *Bot) Config() Config {
+	return *b.cfg
+}
+
+// Use logger instead of slog.Default()
+func (b *Bot) SetLogger(logger *slog.Logger) {
+	b.log = logger
+}
+
+// Call fn after each pair is embedded by Index, e.g. to drive a progress bar
+func (b *Bot) SetProgress(fn func(dim int)) {
+	b.progress = fn
+}
+
+// Embed text at a dimension with a Gemini task type (TaskRetrievalDocument or TaskRetrievalQuery)
+func (b *Bot) Embed(ctx context.Context, text string, dimension int, taskType string) ([]float32, error) {
+	return b.getEmbedding(ctx, text, dimension, taskType)
+}
+
+// Search one dimension's index for the topK inputs closest to query
+func (b *Bot) Search(ctx context.Context, query string, dimension int, topK int) (*QueryResult, error) {
+	return b.searchSimilar(ctx, query, dimension, topK, nil)
+}
+
+// Search without the vectors in excludeIDs, e.g. the training pair an evaluation query was
+// taken from, for leave-one-out accuracy. See searchSimilar for how topK is adjusted.
+func (b *Bot) SearchExcluding(ctx context.Context, query string, dimension int, topK int, excludeIDs []string) (*QueryResult, error) {
+	return b.searchSimilar(ctx, query, dimension, topK, excludeIDs)
+}
+
+// Query one dimension's index with a ready-made dense vector, e.g. an embedding computed
+// elsewhere, across Config.SearchNamespaces(). No Gemini call is made.
+func (b *Bot) QueryVector(ctx context.Context, vector []float32, dimension int, topK int) (*QueryResult, error) {
+	if len(vector) != dimension {
+		return nil, fmt.Errorf("vector has %d values, index for dimension %d needs %d", len(vector), dimension, dimension)
+	}
+	return b.queryNamespaces(ctx, vector, nil, dimension, topK, b.cfg.SearchNamespaces(dimension))
+}
+
+// Upload vectors to one dimension's index, retrying transient failures
+func (b *Bot) Upsert(ctx context.Context, vectors []Vector, dimension int) error {
+	return b.upsertToPinecone(ctx, vectors, dimension)
+}
+
+// Delete vectors by ID from one dimension's index
+func (b *Bot) Delete(ctx context.Context, ids []string, dimension int) error {
+	return b.deleteVectors(ctx, ids, dimension)
+}
+
+// One stored vector by ID from a dimension's index; ErrVectorNotFound when it doesn't exist
+func (b *Bot) FetchVector(ctx context.Context, id string, dimension int) (*Vector, error) {
+	return b.fetchVector(ctx, id, dimension)
+}
+
+// Vector counts of one dimension's index, overall and per namespace
+func (b *Bot) DescribeIndexStats(ctx context.Context, dimension int) (*IndexStats, error) {
+	return b.describeIndexStats(ctx, dimension)
+}
+
+// Make one cheap call to each service so bad credentials fail before any real work
+func (b *Bot) Preflight(ctx context.Context) error {
+	if b.local != nil && b.cfg.GeminiAPIKey == "" {
+		return nil
+	}
+	// The smallest configured dimension is the cheapest one the model is known to serve
+	if _, err := b.getEmbedding(ctx, "preflight", b.cfg.Dimensions()[0], TaskRetrievalQuery); err != nil {
+		return fmt.Errorf("Gemini check failed, verify GEMINI_API_KEY: %v", err)
+	}
+	if b.local != nil {
+		return nil
+	}
+
+	for _, dim := range b.cfg.Dimensions() {
+		stats, err := b.describeIndexStats(ctx, dim)
+		if err != nil {
+			return fmt.Errorf("Pinecone check failed for index %s, verify PINECONE_API_KEY: %v", b.cfg.Indexes[dim], err)
+		}
+		if err := b.CheckMetric(dim, stats); err != nil {
+			return err
+		}
+		if err := b.CheckDimension(dim, stats); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Compare the configured metric of a dimension's index with the one Pinecone reports.
+// Older API versions don't report it, in which case there is nothing to check.
+func (b *Bot) CheckMetric(dim int, stats *IndexStats) error {
+	if stats.Metric == "" || stats.Metric == b.cfg.Metric(dim) {
+		return nil
+	}
+	return fmt.Errorf("index %s uses the %s metric but is configured as %s: set index_metrics[%d]",
+		b.cfg.Indexes[dim], stats.Metric, b.cfg.Metric(dim), dim)
+}
+
+// Make a tiny embedding call and a describe_index_stats call on each searched index so
+// idle connections to Gemini and Pinecone are reopened (DNS, TLS) before a real query
+// needs them. The embedding skips the disk cache so it always reaches Gemini.
+func (b *Bot) Warm(ctx context.Context) error {
+	var errs []error
+	if b.cfg.GeminiAPIKey != "" {
+		dims := b.cfg.SearchDimensions()
+		if _, err := b.fetchEmbedding(ctx, b.cfg.EmbeddingModel, "warmup", dims[0], TaskRetrievalQuery); err != nil {
+			errs = append(errs, fmt.Errorf("gemini: %v", err))
+		}
+	}
+	if b.local == nil {
+		for _, dim := range b.cfg.SearchDimensions() {
+			if _, err := b.describeIndexStats(ctx, dim); err != nil {
+				errs = append(errs, fmt.Errorf("pinecone %s: %v", b.cfg.Indexes[dim], err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Result of Answer
+type Response struct {
+	Query     string
+	Answer    string       // best match's output (one of them for a one-to-many pair) rendered as a template and post-processed, or Config.FallbackAnswer / Config.ClarifyAnswer when not Confident
+	Confident bool         // best match reached Config.ScoreThreshold (see Config.MeetsThreshold) and was not Ambiguous
+	Ambiguous bool         // a match with a different output scored within Config.AmbiguityEpsilon of Best
+	Best      *RankedMatch // nil when nothing matched
+	RunnerUp  *RankedMatch // best match whose output differs from Best's, nil if none
+	Matches   []RankedMatch
+
+	// Up to Config.Alternatives matches of distinct pairs, best first, for
+	// "did you mean one of these?" suggestions, picked by Config.MMR when enabled
+	Alternatives []RankedMatch
+
+	// Raw per-dimension results and failures. A dimension appears in at most one of them.
+	Results map[int]*QueryResult
+	Errors  map[int]error
+}
+
+// Dimensions that were searched, successfully or not, in ascending order
+func (r *Response) Dimensions() []int {
+	dims := make([]int, 0, len(r.Results)+len(r.Errors))
+	for dim := range r.Results {
+		dims = append(dims, dim)
+	}
+	for dim := range r.Errors {
+		if _, ok := r.Results[dim]; !ok {
+			dims = append(dims, dim)
+		}
+	}
+	sort.Ints(dims)
+	return dims
+}
+
+// The first n matches of distinct pairs, so the same pair found in several dimensions
+// counts once. Pairs are told apart by pair_id, or by content hash for pairs from Add.
+func distinctPairs(matches []RankedMatch, n int) []RankedMatch {
+	var out []RankedMatch
+	seen := map[string]bool{}
+	for _, m := range matches {
+		if len(out) >= n {
+			break
+		}
+		key := "hash " + contentHash(InputOutputPair{Input: m.Input, Output: m.Output, Outputs: m.Outputs})
+		if m.PairID != AddedPairID {
+			key = fmt.Sprintf("pair %d", m.PairID)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, m)
+	}
+	return out
+}
+
+// Answer query from the best match across Config.SearchDimensions(), the one dimension
+// Config.AutoDimension picks for it, or the first confident dimension of Config.FallbackChain.
+// Failed dimensions are reported in Response.Errors; an error is returned only when every
+// dimension failed, and the response is still returned alongside it with the per-dimension errors.
+func (b *Bot) Answer(ctx context.Context, query string) (*Response, error) {
+	return b.AnswerWith(ctx, query, nil)
+}
+
+// Answer, filling template variables in the chosen output from vars first and
+// Config.TemplateDefaults second
+func (b *Bot) AnswerWith(ctx context.Context, query string, vars map[string]string) (*Response, error) {
+	if len(b.cfg.FallbackChain) > 0 {
+		resp, err := b.answerChain(ctx, query, vars)
+		b.telemetry.recordAnswer(resp.Confident)
+		return resp, err
+	}
+
+	dims, reason := b.cfg.chooseDimensions(query)
+	if b.cfg.AutoDimension.Enabled {
+		b.log.Info("🎯 Dimensions chosen", "dims", dims, "reason", reason)
+	}
+	results, errs := b.searchDimensions(ctx, query, dims, b.cfg.TopK)
+
+	resp, err := b.answerFrom(query, vars, dims, results, errs)
+	b.telemetry.recordAnswer(resp.Confident)
+	return resp, err
+}
+
+// AnswerWith for a ready-made query embedding, searching the index whose dimension
+// matches the vector's length. The response's Query is empty.
+func (b *Bot) AnswerVector(ctx context.Context, vector []float32, vars map[string]string) (*Response, error) {
+	dim := len(vector)
+	if _, ok := b.cfg.Indexes[dim]; !ok {
+		return nil, fmt.Errorf("no index configured for a %d-value vector (indexes: %v)", dim, b.cfg.Dimensions())
+	}
+	results, errs := map[int]*QueryResult{}, map[int]error{}
+	if r, err := b.QueryVector(ctx, vector, dim, b.cfg.TopK); err != nil {
+		errs[dim] = err
+	} else {
+		results[dim] = r
+	}
+
+	resp, err := b.answerFrom("", vars, []int{dim}, results, errs)
+	b.telemetry.recordAnswer(resp.Confident)
+	return resp, err
+}
+
+// Build the response for query from per-dimension search results
+func (b *Bot) answerFrom(query string, vars map[string]string, dims []int, results map[int]*QueryResult, errs map[int]error) (*Response, error) {
+	resp := &Response{
+		Query:   query,
+		Answer:  b.cfg.FallbackAnswer,
+		Matches: rerankAcrossDimensions(b.cfg, results),
+		Results: results,
+		Errors:  errs,
+	}
+	b.cfg.lexicalRescore(query, resp.Matches)
+	if len(results) == 0 && len(errs) > 0 {
+		joined := make([]error, 0, len(errs))
+		for _, dim := range dims {
+			if err, ok := errs[dim]; ok {
+				joined = append(joined, fmt.Errorf("dimension %d: %v", dim, err))
+			}
+		}
+		return resp, errors.Join(joined...)
+	}
+
+	if len(resp.Matches) == 0 {
+		return resp, nil
+	}
+	resp.Best = &resp.Matches[0]
+	resp.Alternatives = b.cfg.alternatives(resp.Matches, b.cfg.Alternatives)
+	if !b.cfg.MeetsThreshold(resp.Best.Dimension, resp.Best.Score) {
+		return resp, nil
+	}
+
+	for i := range resp.Matches[1:] {
+		if m := &resp.Matches[i+1]; m.Output != resp.Best.Output {
+			resp.RunnerUp = m
+			break
+		}
+	}
+	// Compared before the lexical blend, which rescales scores and would shrink the gap
+	if resp.RunnerUp != nil && math.Abs(resp.Best.SemanticScore-resp.RunnerUp.SemanticScore) < b.cfg.AmbiguityEpsilon {
+		resp.Ambiguous = true
+		resp.Answer = b.cfg.ClarifyAnswer
+		return resp, nil
+	}
+
+	merged := map[string]string{}
+	for k, v := range b.cfg.TemplateDefaults {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	answer, err := renderOutput(b.pickOutput(resp.Best), merged)
+	if err != nil {
+		b.log.Warn("output is not a valid template, answering with it as is", "id", resp.Best.ID, "err", err)
+	}
+	answer, err = b.postProcess(answer, resp.Best)
+	if err != nil {
+		b.log.Warn("answer post-processing failed, answering with the fallback", "id", resp.Best.ID, "err", err)
+		return resp, nil
+	}
+
+	resp.Confident = true
+	resp.Answer = answer
+	return resp, nil
+}
@@ -0,0 +1,68 @@
+package ragbot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Default Config.EmbeddingModel, also used by ensemble models that don't name one. The
+// model is part of the cache key so a model change can't return stale vectors.
+const defaultEmbeddingModel = "gemini-embedding-001"
+
+// Embeddings saved on disk as one JSON file per (model, task type, dimension, text),
+// so repeated runs don't pay for the same text twice
+type embeddingCache struct {
+	dir string
+}
+
+// Key identifying one embedding request
+func embeddingKey(model string, text string, dimension int, taskType string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s", model, taskType, dimension, text)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *embeddingCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Cached embedding for key, if any. Unreadable entries count as misses.
+func (c *embeddingCache) get(key string) ([]float32, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var values []float32
+	if err := json.Unmarshal(data, &values); err != nil || len(values) == 0 {
+		return nil, false
+	}
+	return values, true
+}
+
+// Store an embedding, writing via a temp file so readers never see a partial entry
+func (c *embeddingCache) put(key string, values []float32) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache dir: %v", err)
+	}
+	data, _ := json.Marshal(values)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %v", err)
+	}
+	return nil
+}
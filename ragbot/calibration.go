@@ -0,0 +1,47 @@
+package ragbot
+
+import "math"
+
+// Calibration methods
+const (
+	CalibrationLinear  = "linear"
+	CalibrationSigmoid = "sigmoid"
+)
+
+// Mapping from a raw match score to a 0-100 confidence shown to users.
+// For euclidean distances, where lower is better, use Low > High or a negative Steepness.
+type CalibrationConfig struct {
+	Method string `yaml:"method"` // "linear" (default) or "sigmoid"
+
+	// Linear: Low and below is 0, High and above is 100, straight line between
+	Low  float64 `yaml:"low"`
+	High float64 `yaml:"high"`
+
+	// Sigmoid: 100 / (1 + e^(-Steepness * (score - Midpoint))), 50 at Midpoint
+	Midpoint  float64 `yaml:"midpoint"`
+	Steepness float64 `yaml:"steepness"`
+}
+
+// Used when the config sets no calibration: typical gemini-embedding-001 cosine scores
+// for a paraphrase sit around 0.7-0.9, unrelated text below 0.5
+var defaultCalibration = CalibrationConfig{Method: CalibrationLinear, Low: 0.5, High: 0.95}
+
+// Confidence from 0 to 100 for a raw match score, per Config.Calibration
+func (c *Config) Confidence(score float32) float64 {
+	cal := c.Calibration
+	if cal == (CalibrationConfig{}) {
+		cal = defaultCalibration
+	}
+	s := float64(score)
+	var conf float64
+	switch cal.Method {
+	case CalibrationSigmoid:
+		conf = 100 / (1 + math.Exp(-cal.Steepness*(s-cal.Midpoint)))
+	default:
+		if cal.High == cal.Low {
+			return 0
+		}
+		conf = 100 * (s - cal.Low) / (cal.High - cal.Low)
+	}
+	return math.Max(0, math.Min(100, conf))
+}
@@ -0,0 +1,28 @@
+package ragbot
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConfidence(t *testing.T) {
+	tests := []struct {
+		name  string
+		cal   CalibrationConfig
+		score float32
+		want  float64
+	}{
+		{"default linear middle", CalibrationConfig{}, 0.725, 50},
+		{"default linear below", CalibrationConfig{}, 0.3, 0},
+		{"default linear above", CalibrationConfig{}, 0.99, 100},
+		{"euclidean linear", CalibrationConfig{Method: CalibrationLinear, Low: 1.0, High: 0.2}, 0.6, 50},
+		{"sigmoid midpoint", CalibrationConfig{Method: CalibrationSigmoid, Midpoint: 0.75, Steepness: 20}, 0.75, 50},
+		{"sigmoid high", CalibrationConfig{Method: CalibrationSigmoid, Midpoint: 0.75, Steepness: 20}, 0.95, 98.2},
+	}
+	for _, tt := range tests {
+		c := &Config{Calibration: tt.cal}
+		if got := c.Confidence(tt.score); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("%s: Confidence(%v) = %.2f, want %.1f", tt.name, tt.score, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,676 @@
+package ragbot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backend values
+const (
+	BackendPinecone = "pinecone"
+	BackendLocal    = "local"
+)
+
+// All runtime settings. Each field comes from config.yaml if set there,
+// otherwise from its environment variable (where it has one), otherwise from defaultConfig.
+type Config struct {
+	// API keys. Either the literal key or a reference like "${GEMINI_API_KEY}".
+	// GEMINI_API_KEY_FILE / PINECONE_API_KEY_FILE name files holding the keys, e.g. mounted
+	// secrets, and take precedence over the plain env vars.
+	GeminiAPIKey   string `yaml:"gemini_api_key"`   // env GEMINI_API_KEY
+	PineconeAPIKey string `yaml:"pinecone_api_key"` // env PINECONE_API_KEY
+
+	// Where vectors live: "pinecone", or "local" to answer from pairs held in memory with
+	// no Pinecone at all (and no Gemini either when GeminiAPIKey is empty)
+	Backend        string `yaml:"backend"`
+	LocalPairsFile string `yaml:"local_pairs_file"` // pairs loaded by the CLI for the local backend
+
+	// Snapshot from upload --snapshot loaded by the CLI for the local backend instead of
+	// LocalPairsFile, so its pairs aren't embedded again
+	LocalVectorsFile string `yaml:"local_vectors_file"`
+
+	// Index name per embedding dimension, and the Pinecone environment part of their hosts.
+	// "{dim}" in Namespace or QueryNamespaces is replaced by the dimension, e.g. "training-{dim}"
+	// keeps each dimension in its own namespace; without it every dimension shares the namespace.
+	Indexes             map[int]string `yaml:"indexes"`
+	PineconeEnvironment string         `yaml:"pinecone_environment"` // env PINECONE_ENVIRONMENT
+	Namespace           string         `yaml:"namespace"`            // env PINECONE_NAMESPACE
+
+	// Namespaces searched and merged by queries, e.g. one per tenant; empty searches Namespace only
+	QueryNamespaces []string `yaml:"query_namespaces"`
+
+	// Per-index placement for indexes in different regions, keyed by index name.
+	// When IndexEnvironments is set it replaces PineconeEnvironment and must list every index.
+	// IndexHosts gives an index's full data-plane URL and takes precedence over both.
+	IndexEnvironments map[string]string `yaml:"index_environments"`
+	IndexHosts        map[string]string `yaml:"index_hosts"`
+
+	// Don't ask each index for its dimension when the CLI starts (see Bot.CheckIndexDimensions)
+	SkipDimensionCheck bool `yaml:"skip_dimension_check"`
+
+	TopK           int     `yaml:"top_k"`           // matches returned per dimension
+	ScoreThreshold float64 `yaml:"score_threshold"` // minimum score for a confident answer
+	Concurrency    int     `yaml:"concurrency"`     // parallel embedding calls during upload
+
+	// Dimensions searched in parallel for one query; 0 searches all of them at once, 1 one at a time
+	QueryConcurrency int `yaml:"query_concurrency"`
+
+	// Ask Pinecone queries for each match's stored vector (Match.Values), e.g. to inspect
+	// embedding drift. Off by default as the values make responses much larger.
+	IncludeValues bool `yaml:"include_values"`
+
+	// Mapping of raw scores to the 0-100 confidence reported with matches
+	Calibration CalibrationConfig `yaml:"calibration"`
+
+	// Rerank bonus for newer vectors, off by default
+	RecencyBoost RecencyBoostConfig `yaml:"recency_boost"`
+
+	// Diversity-aware selection of the "did you mean" alternatives, off by default
+	MMR MMRConfig `yaml:"mmr"`
+
+	// Embed each distinct input once at the largest dimension and derive the smaller vectors by
+	// Matryoshka truncation plus renormalization, instead of one Gemini call per dimension.
+	// Saves API calls; retrieval quality may differ slightly from direct calls.
+	TruncateEmbeddings bool `yaml:"truncate_embeddings"`
+
+	// Gemini model embedding pairs and queries, default gemini-embedding-001
+	EmbeddingModel string `yaml:"embedding_model"`
+
+	// Model tried when Gemini reports EmbeddingModel as not found, e.g. after a deprecation.
+	// Its vectors must be comparable with the stored ones (same model family and task
+	// types); otherwise reindex after switching rather than relying on the fallback.
+	FallbackEmbeddingModel string `yaml:"fallback_embedding_model"`
+
+	// Gemini embedding requests allowed per minute, enforced before every embedding call
+	EmbeddingRPM int `yaml:"embedding_rpm"`
+
+	// Pinecone requests allowed per minute to each index host; 0 leaves Pinecone unpaced.
+	// Rate-limited (429) requests to either service are retried regardless.
+	PineconeRPM int `yaml:"pinecone_rpm"`
+
+	// Timeout and retries per provider; unset fields keep the defaults (Gemini 30s and 3
+	// retries from 1s, Pinecone 15s and 4 retries from 500ms)
+	GeminiRetry   RetryPolicy `yaml:"gemini_retry"`
+	PineconeRetry RetryPolicy `yaml:"pinecone_retry"`
+
+	// Directory caching embeddings across runs; empty disables the cache
+	EmbeddingCacheDir string `yaml:"embedding_cache_dir"`
+
+	// Dimensions searched by Answer; empty searches every configured index
+	QueryDimensions []int `yaml:"query_dimensions"`
+
+	// Dimensions searched by the server in place of QueryDimensions, e.g. just 1024 to cut
+	// latency and cost; the CLI keeps using QueryDimensions
+	ServeDimensions []int `yaml:"serve_dimensions"`
+
+	// Search a single dimension picked from the query's length and keyword density
+	AutoDimension AutoDimensionConfig `yaml:"auto_dimension"`
+
+	// Dimensions searched one at a time in priority order, e.g. [1024, 512, 384], until one
+	// gives a confident answer. When set Answer uses it instead of QueryDimensions and
+	// ServeDimensions; Validate rejects it combined with AutoDimension.
+	FallbackChain []int `yaml:"fallback_chain"`
+
+	// Reply used by Answer when no match reaches ScoreThreshold
+	FallbackAnswer string `yaml:"fallback_answer"`
+
+	// How Answer picks among the responses of a one-to-many pair: "random" (default)
+	// or "round_robin" per pair. The server's answer cache repeats a pick until it expires.
+	OutputSelection string `yaml:"output_selection"`
+
+	// Values for text/template variables in stored outputs, e.g. {{.driverName}}, used when
+	// AnswerWith isn't given one. Variables with no value anywhere keep their placeholder.
+	TemplateDefaults map[string]string `yaml:"template_defaults"`
+
+	// Number of distinct alternative outputs Answer lists for "did you mean" suggestions; 0 disables
+	Alternatives int `yaml:"alternatives"`
+
+	// When the best match beats the best match with a different output by less than
+	// AmbiguityEpsilon, Answer asks for clarification instead of guessing. The gap is measured
+	// on the semantic score normalized by dimension weight (RankedMatch.SemanticScore), before
+	// any lexical blend. 0 means unset; a negative value disables the check.
+	AmbiguityEpsilon float64 `yaml:"ambiguity_epsilon"`
+	ClarifyAnswer    string  `yaml:"clarify_answer"`
+
+	// Hybrid sparse+dense search. Pinecone only accepts sparse values on indexes created with
+	// the dotproduct metric, and vectors must be indexed with Hybrid on for the sparse part to match.
+	Hybrid      bool    `yaml:"hybrid"`
+	HybridAlpha float64 `yaml:"hybrid_alpha"` // 1.0 is pure dense; 0 means unset, use e.g. 0.01 for nearly pure sparse
+
+	// Share of the ranking score given to exact keyword overlap between the query and a
+	// match's input after retrieval, from 0 (default, semantic only) to 1. Helps queries
+	// carrying identifiers such as dates or cab numbers. Needs no sparse index.
+	LexicalAlpha float64 `yaml:"lexical_alpha"`
+
+	// Seed for retry jitter, for reproducible runs; 0 seeds from the clock
+	RandomSeed int64 `yaml:"random_seed"`
+
+	// Service endpoints. PineconeBaseURL replaces every index host when set (tests, Pinecone Local).
+	GeminiBaseURL   string `yaml:"gemini_base_url"`
+	PineconeBaseURL string `yaml:"pinecone_base_url"`
+
+	// User-Agent of every outbound call, and extra headers per provider, e.g. for an auth proxy.
+	// Values of credential-like headers are redacted in verbose logs.
+	UserAgent       string            `yaml:"user_agent"`
+	GeminiHeaders   map[string]string `yaml:"gemini_headers"`
+	PineconeHeaders map[string]string `yaml:"pinecone_headers"`
+
+	// Score multipliers applied when ranking matches across dimensions; unset dimensions use 1.0
+	DimensionWeights map[int]float64 `yaml:"dimension_weights"`
+
+	// Similarity metric each index was created with, keyed by dimension; unset dimensions are
+	// cosine. Euclidean scores are distances, so lower is better and ScoreThreshold is a maximum.
+	IndexMetrics map[int]string `yaml:"index_metrics"`
+
+	// Largest serialized metadata allowed per vector (Pinecone rejects more than 40KB).
+	// Bigger vectors get their output truncated, or are skipped with MetadataOverflow "skip".
+	MaxMetadataBytes int    `yaml:"max_metadata_bytes"`
+	MetadataOverflow string `yaml:"metadata_overflow"` // "truncate" (default) or "skip"
+
+	// Longest text sent to Gemini for embedding, in characters and in estimated tokens
+	// (about 4 characters each). MaxInputChars 0 leaves it unset; MaxInputTokens 0 means
+	// the model's 2048 and -1 turns the token limit off. Longer text is cut at a character
+	// boundary with a warning, or fails its embedding with InputOverflow "reject".
+	MaxInputChars  int    `yaml:"max_input_chars"`
+	MaxInputTokens int    `yaml:"max_input_tokens"`
+	InputOverflow  string `yaml:"input_overflow"` // "truncate" (default) or "reject"
+
+	// Removal of invisible characters and stray whitespace from pair inputs and queries
+	Sanitize SanitizeConfig `yaml:"sanitize"`
+
+	// Cleanup of search queries before they are embedded; stored vectors are unaffected
+	Preprocess PreprocessConfig `yaml:"preprocess"`
+
+	// Models combined by EnsembleSearch, each with its own indexes holding vectors from that model
+	Ensemble []EnsembleModel `yaml:"ensemble"`
+}
+
+// One model in an ensemble search
+type EnsembleModel struct {
+	Name    string         `yaml:"name"`    // label reported with matches
+	Model   string         `yaml:"model"`   // Gemini embedding model, default gemini-embedding-001
+	Indexes map[int]string `yaml:"indexes"` // index per dimension holding this model's vectors
+	Weight  float64        `yaml:"weight"`  // share of the merged score; 0 means 1.0
+}
+
+// Pinecone similarity metrics
+const (
+	MetricCosine     = "cosine"
+	MetricDotProduct = "dotproduct"
+	MetricEuclidean  = "euclidean"
+)
+
+// MetadataOverflow values
+const (
+	OverflowTruncate = "truncate"
+	OverflowSkip     = "skip"
+	OverflowReject   = "reject"
+)
+
+// Built-in settings used for anything not set in the file or environment
+func DefaultConfig() *Config {
+	return &Config{
+		Backend:        BackendPinecone,
+		LocalPairsFile: "test_embedding.json",
+		// Three different indexes for different embedding dimensions
+		Indexes: map[int]string{
+			384:  "chatbot-embeddings-384-2x9jann",
+			512:  "chatbot-embeddings-512-2x9jann",
+			1024: "chatbot-embeddings-1024-2x9jann",
+		},
+		PineconeEnvironment: "aped-4627-b74a",
+		Namespace:           "chatbot-training-data-test-semantic",
+		TopK:                3,
+		ScoreThreshold:      0,
+		Concurrency:         1,
+		EmbeddingModel:      defaultEmbeddingModel,
+		EmbeddingRPM:        3000, // gemini-embedding-001 paid tier 1
+		GeminiRetry:         defaultGeminiRetry,
+		PineconeRetry:       defaultPineconeRetry,
+		FallbackAnswer:      "Sorry, I don't have an answer for that yet.",
+		AmbiguityEpsilon:    0.02,
+		ClarifyAnswer:       "I found a few possible answers. Could you tell me a bit more about what you need?",
+		HybridAlpha:         0.75,
+		GeminiBaseURL:       "https://generativelanguage.googleapis.com/v1beta",
+		UserAgent:           "chatbot-rag-go/" + Version,
+		MaxMetadataBytes:    40 * 1024,
+		MetadataOverflow:    OverflowTruncate,
+		MaxInputTokens:      2048, // gemini-embedding-001 input limit
+		InputOverflow:       OverflowTruncate,
+	}
+}
+
+// Load settings from a YAML file, then fill anything unset from the environment and defaults.
+// A missing file is only an error when required is true.
+func LoadConfig(path string, required bool) (*Config, error) {
+	c := &Config{}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && !required:
+	default:
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+
+	if err := secretFromFile(&c.GeminiAPIKey, "GEMINI_API_KEY"); err != nil {
+		return nil, err
+	}
+	if err := secretFromFile(&c.PineconeAPIKey, "PINECONE_API_KEY"); err != nil {
+		return nil, err
+	}
+	c.GeminiAPIKey = os.ExpandEnv(c.GeminiAPIKey)
+	c.PineconeAPIKey = os.ExpandEnv(c.PineconeAPIKey)
+
+	fromEnv := func(field *string, name string) {
+		if *field == "" {
+			*field = os.Getenv(name)
+		}
+	}
+	fromEnv(&c.GeminiAPIKey, "GEMINI_API_KEY")
+	fromEnv(&c.PineconeAPIKey, "PINECONE_API_KEY")
+	fromEnv(&c.PineconeEnvironment, "PINECONE_ENVIRONMENT")
+	fromEnv(&c.Namespace, "PINECONE_NAMESPACE")
+
+	def := DefaultConfig()
+	if c.Backend == "" {
+		c.Backend = def.Backend
+	}
+	if c.LocalPairsFile == "" {
+		c.LocalPairsFile = def.LocalPairsFile
+	}
+	if c.Indexes == nil {
+		// An explicitly empty map is left for Validate to reject
+		c.Indexes = def.Indexes
+	}
+	if c.PineconeEnvironment == "" {
+		c.PineconeEnvironment = def.PineconeEnvironment
+	}
+	if c.Namespace == "" {
+		c.Namespace = def.Namespace
+	}
+	if c.TopK == 0 {
+		c.TopK = def.TopK
+	}
+	if c.Concurrency == 0 {
+		c.Concurrency = def.Concurrency
+	}
+	if c.EmbeddingRPM == 0 {
+		c.EmbeddingRPM = def.EmbeddingRPM
+	}
+	c.GeminiRetry = c.GeminiRetry.withDefaults(def.GeminiRetry)
+	c.PineconeRetry = c.PineconeRetry.withDefaults(def.PineconeRetry)
+	if c.EmbeddingModel == "" {
+		c.EmbeddingModel = def.EmbeddingModel
+	}
+	if c.FallbackAnswer == "" {
+		c.FallbackAnswer = def.FallbackAnswer
+	}
+	if c.AmbiguityEpsilon == 0 {
+		c.AmbiguityEpsilon = def.AmbiguityEpsilon
+	}
+	if c.ClarifyAnswer == "" {
+		c.ClarifyAnswer = def.ClarifyAnswer
+	}
+	if c.HybridAlpha == 0 {
+		c.HybridAlpha = def.HybridAlpha
+	}
+	if c.GeminiBaseURL == "" {
+		c.GeminiBaseURL = def.GeminiBaseURL
+	}
+	if c.MaxMetadataBytes == 0 {
+		c.MaxMetadataBytes = def.MaxMetadataBytes
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = def.UserAgent
+	}
+	if c.MetadataOverflow == "" {
+		c.MetadataOverflow = def.MetadataOverflow
+	}
+	if c.MaxInputTokens == 0 {
+		c.MaxInputTokens = def.MaxInputTokens
+	}
+	if c.InputOverflow == "" {
+		c.InputOverflow = def.InputOverflow
+	}
+
+	return c, nil
+}
+
+// Read a key from the file named by env <name>_FILE, the Docker/Kubernetes secret
+// convention. The file takes precedence over env <name>, also when the config refers to
+// it as ${name}; a literal key in the config is kept.
+func secretFromFile(field *string, name string) error {
+	path := os.Getenv(name + "_FILE")
+	if path == "" || (*field != "" && *field != "${"+name+"}" && *field != "$"+name) {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s_FILE is set but the secret can't be read: %v", name, err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return fmt.Errorf("%s_FILE is set but %s is empty", name, path)
+	}
+	*field = key
+	return nil
+}
+
+// Check that the configuration is usable, reporting every problem at once
+func (c *Config) Validate() error {
+	var problems []string
+	switch c.Backend {
+	case BackendPinecone:
+		if c.GeminiAPIKey == "" {
+			problems = append(problems, "GEMINI_API_KEY not set")
+		}
+		if c.PineconeAPIKey == "" {
+			problems = append(problems, "PINECONE_API_KEY not set")
+		}
+		for _, dim := range c.Dimensions() {
+			if _, err := c.IndexHost(dim); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	case BackendLocal:
+	default:
+		problems = append(problems, fmt.Sprintf("backend must be %q or %q, got %q", BackendPinecone, BackendLocal, c.Backend))
+	}
+	if len(c.Indexes) == 0 {
+		problems = append(problems, "no indexes configured: map each embedding dimension to its Pinecone index under indexes, e.g. 384: my-index-384")
+	}
+	for dim, name := range c.Indexes {
+		if dim <= 0 || name == "" {
+			problems = append(problems, fmt.Sprintf("invalid index entry %d: %q: each entry needs a positive dimension and an index name", dim, name))
+		}
+	}
+	if c.Namespace == "" {
+		problems = append(problems, "namespace not set")
+	}
+	for _, ns := range c.QueryNamespaces {
+		if ns == "" {
+			problems = append(problems, "query_namespaces must not contain an empty namespace")
+		}
+	}
+	if c.Alternatives < 0 {
+		problems = append(problems, fmt.Sprintf("alternatives must not be negative, got %d", c.Alternatives))
+	}
+	if c.TopK < 1 {
+		problems = append(problems, fmt.Sprintf("top_k must be at least 1, got %d", c.TopK))
+	}
+	if c.QueryConcurrency < 0 {
+		problems = append(problems, fmt.Sprintf("query_concurrency must not be negative, got %d", c.QueryConcurrency))
+	}
+	if c.Concurrency < 1 {
+		problems = append(problems, fmt.Sprintf("concurrency must be at least 1, got %d", c.Concurrency))
+	}
+	if c.EmbeddingRPM < 1 {
+		problems = append(problems, fmt.Sprintf("embedding_rpm must be at least 1, got %d", c.EmbeddingRPM))
+	}
+	if c.FallbackEmbeddingModel != "" && c.FallbackEmbeddingModel == c.EmbeddingModel {
+		problems = append(problems, fmt.Sprintf("fallback_embedding_model is the same as embedding_model (%s)", c.EmbeddingModel))
+	}
+	for _, r := range []struct {
+		name   string
+		policy RetryPolicy
+	}{{"gemini_retry", c.GeminiRetry}, {"pinecone_retry", c.PineconeRetry}} {
+		if p := r.policy; p.Timeout < 0 || p.BaseBackoff < 0 || p.MaxBackoff < p.BaseBackoff {
+			problems = append(problems, fmt.Sprintf("%s: timeout and backoffs must not be negative and max_backoff must be at least base_backoff, got %+v", r.name, p))
+		}
+	}
+	if c.PineconeRPM < 0 {
+		problems = append(problems, fmt.Sprintf("pinecone_rpm must not be negative, got %d", c.PineconeRPM))
+	}
+
+	for _, dim := range c.QueryDimensions {
+		if _, ok := c.Indexes[dim]; !ok {
+			problems = append(problems, fmt.Sprintf("query_dimensions: no index configured for dimension %d", dim))
+		}
+	}
+	for _, dim := range c.ServeDimensions {
+		if _, ok := c.Indexes[dim]; !ok {
+			problems = append(problems, fmt.Sprintf("serve_dimensions: no index configured for dimension %d", dim))
+		}
+	}
+	for i, r := range c.AutoDimension.Rules {
+		if _, ok := c.Indexes[r.Dimension]; !ok {
+			problems = append(problems, fmt.Sprintf("auto_dimension.rules[%d]: no index configured for dimension %d", i, r.Dimension))
+		}
+	}
+	seenChain := map[int]bool{}
+	for _, dim := range c.FallbackChain {
+		if _, ok := c.Indexes[dim]; !ok {
+			problems = append(problems, fmt.Sprintf("fallback_chain: no index configured for dimension %d", dim))
+		}
+		if seenChain[dim] {
+			problems = append(problems, fmt.Sprintf("fallback_chain: dimension %d is listed twice", dim))
+		}
+		seenChain[dim] = true
+	}
+	if len(c.FallbackChain) > 0 && c.AutoDimension.Enabled {
+		problems = append(problems, "fallback_chain and auto_dimension can't both be used")
+	}
+	if c.LexicalAlpha < 0 || c.LexicalAlpha > 1 {
+		problems = append(problems, fmt.Sprintf("lexical_alpha must be between 0 and 1, got %g", c.LexicalAlpha))
+	}
+	if c.HybridAlpha < 0 || c.HybridAlpha > 1 {
+		problems = append(problems, fmt.Sprintf("hybrid_alpha must be between 0 and 1, got %g", c.HybridAlpha))
+	}
+
+	for dim, w := range c.DimensionWeights {
+		if w < 0 {
+			problems = append(problems, fmt.Sprintf("dimension_weights[%d] must not be negative, got %g", dim, w))
+		}
+	}
+
+	for dim, metric := range c.IndexMetrics {
+		if _, ok := c.Indexes[dim]; !ok {
+			problems = append(problems, fmt.Sprintf("index_metrics: no index configured for dimension %d", dim))
+		}
+		if metric != MetricCosine && metric != MetricDotProduct && metric != MetricEuclidean {
+			problems = append(problems, fmt.Sprintf("index_metrics[%d] must be %q, %q or %q, got %q", dim, MetricCosine, MetricDotProduct, MetricEuclidean, metric))
+		}
+	}
+
+	if c.MaxMetadataBytes < 0 {
+		problems = append(problems, fmt.Sprintf("max_metadata_bytes must not be negative, got %d", c.MaxMetadataBytes))
+	}
+	if c.OutputSelection != "" && c.OutputSelection != OutputRandom && c.OutputSelection != OutputRoundRobin {
+		problems = append(problems, fmt.Sprintf("output_selection must be %q or %q, got %q", OutputRandom, OutputRoundRobin, c.OutputSelection))
+	}
+	if c.MetadataOverflow != "" && c.MetadataOverflow != OverflowTruncate && c.MetadataOverflow != OverflowSkip {
+		problems = append(problems, fmt.Sprintf("metadata_overflow must be %q or %q, got %q", OverflowTruncate, OverflowSkip, c.MetadataOverflow))
+	}
+	if c.MaxInputChars < 0 || c.MaxInputTokens < -1 {
+		problems = append(problems, fmt.Sprintf("max_input_chars must not be negative and max_input_tokens must be -1 (off) or more, got %d and %d", c.MaxInputChars, c.MaxInputTokens))
+	}
+	if c.RecencyBoost.Weight < 0 || c.RecencyBoost.HalfLifeDays < 0 {
+		problems = append(problems, fmt.Sprintf("recency_boost: weight and half_life_days must not be negative, got %g and %g",
+			c.RecencyBoost.Weight, c.RecencyBoost.HalfLifeDays))
+	}
+	if l := c.MMR.Lambda; l != nil && (*l < 0 || *l > 1) {
+		problems = append(problems, fmt.Sprintf("mmr: lambda must be between 0 and 1, got %g", *l))
+	}
+	switch c.Calibration.Method {
+	case "", CalibrationLinear:
+		if c.Calibration != (CalibrationConfig{}) && c.Calibration.Low == c.Calibration.High {
+			problems = append(problems, fmt.Sprintf("calibration: low and high must differ, both are %g", c.Calibration.Low))
+		}
+	case CalibrationSigmoid:
+		if c.Calibration.Steepness == 0 {
+			problems = append(problems, "calibration: sigmoid needs a non-zero steepness")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("calibration method must be %q or %q, got %q", CalibrationLinear, CalibrationSigmoid, c.Calibration.Method))
+	}
+	if c.InputOverflow != "" && c.InputOverflow != OverflowTruncate && c.InputOverflow != OverflowReject {
+		problems = append(problems, fmt.Sprintf("input_overflow must be %q or %q, got %q", OverflowTruncate, OverflowReject, c.InputOverflow))
+	}
+
+	names := map[string]bool{}
+	for i, m := range c.Ensemble {
+		switch {
+		case m.Name == "":
+			problems = append(problems, fmt.Sprintf("ensemble[%d]: name not set", i))
+		case names[m.Name]:
+			problems = append(problems, fmt.Sprintf("ensemble: duplicate name %q", m.Name))
+		}
+		names[m.Name] = true
+		if len(m.Indexes) == 0 {
+			problems = append(problems, fmt.Sprintf("ensemble[%d]: no indexes configured", i))
+		}
+		if m.Weight < 0 {
+			problems = append(problems, fmt.Sprintf("ensemble[%d]: weight must not be negative, got %g", i, m.Weight))
+		}
+		if c.Backend == BackendPinecone {
+			for dim, name := range m.Indexes {
+				if _, err := c.namedIndexHost(name, fmt.Sprintf("ensemble %s, dimension %d", m.Name, dim)); err != nil {
+					problems = append(problems, err.Error())
+				}
+			}
+		}
+	}
+
+	// Placement entries for no configured index are usually a typo in the index name,
+	// which would otherwise surface as that index missing its host
+	known := map[string]bool{}
+	for _, name := range c.Indexes {
+		known[name] = true
+	}
+	for _, m := range c.Ensemble {
+		for _, name := range m.Indexes {
+			known[name] = true
+		}
+	}
+	for name := range c.IndexHosts {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("index_hosts: %s is not a configured index", name))
+		}
+	}
+	for name := range c.IndexEnvironments {
+		if !known[name] {
+			problems = append(problems, fmt.Sprintf("index_environments: %s is not a configured index", name))
+		}
+	}
+
+	if c.Preprocess.SpellCorrect && c.Preprocess.SpellDictionary == "" {
+		problems = append(problems, "preprocess.spell_correct needs preprocess.spell_dictionary")
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Configured dimensions in ascending order
+func (c *Config) Dimensions() []int {
+	dims := make([]int, 0, len(c.Indexes))
+	for dim := range c.Indexes {
+		dims = append(dims, dim)
+	}
+	sort.Ints(dims)
+	return dims
+}
+
+// Data-plane base URL of the index for a dimension
+func (c *Config) IndexHost(dim int) (string, error) {
+	name, ok := c.Indexes[dim]
+	if !ok {
+		return "", fmt.Errorf("no index configured for dimension %d", dim)
+	}
+	return c.namedIndexHost(name, fmt.Sprintf("dimension %d", dim))
+}
+
+// Data-plane base URL of an index by name; label says what the index is for in errors
+func (c *Config) namedIndexHost(name, label string) (string, error) {
+	if host := c.IndexHosts[name]; host != "" {
+		return host, nil
+	}
+
+	env := c.PineconeEnvironment
+	if len(c.IndexEnvironments) > 0 {
+		env = c.IndexEnvironments[name]
+		if env == "" {
+			return "", fmt.Errorf("index %s (%s) has no entry in index_environments or index_hosts", name, label)
+		}
+	}
+	if env == "" {
+		return "", fmt.Errorf("index %s (%s) has no environment: set pinecone_environment", name, label)
+	}
+	return fmt.Sprintf("https://%s.svc.%s.pinecone.io", name, env), nil
+}
+
+// Score multiplier for a dimension in the cross-dimension ranking, 1.0 unless configured
+func (c *Config) DimensionWeight(dim int) float64 {
+	if w, ok := c.DimensionWeights[dim]; ok {
+		return w
+	}
+	return 1.0
+}
+
+// Similarity metric of a dimension's index, cosine unless configured
+func (c *Config) Metric(dim int) string {
+	if m := c.IndexMetrics[dim]; m != "" {
+		return m
+	}
+	return MetricCosine
+}
+
+// Whether a raw score from a dimension's index is better than another one
+func (c *Config) BetterScore(dim int, a, b float32) bool {
+	if c.Metric(dim) == MetricEuclidean {
+		return a < b
+	}
+	return a > b
+}
+
+// Whether a raw score from a dimension's index reaches ScoreThreshold. For euclidean
+// indexes the threshold is a maximum distance, and 0 leaves it unset.
+func (c *Config) MeetsThreshold(dim int, score float32) bool {
+	if c.Metric(dim) == MetricEuclidean {
+		return c.ScoreThreshold <= 0 || float64(score) <= c.ScoreThreshold
+	}
+	return float64(score) >= c.ScoreThreshold
+}
+
+// Namespace holding vectors of a dimension: Namespace with "{dim}" replaced by it.
+// A Namespace without "{dim}" is the same for every dimension.
+func (c *Config) NamespaceFor(dim int) string {
+	return expandNamespace(c.Namespace, dim)
+}
+
+func expandNamespace(ns string, dim int) string {
+	return strings.ReplaceAll(ns, "{dim}", strconv.Itoa(dim))
+}
+
+// Namespaces searched by queries at a dimension
+func (c *Config) SearchNamespaces(dim int) []string {
+	if len(c.QueryNamespaces) == 0 {
+		return []string{c.NamespaceFor(dim)}
+	}
+	namespaces := make([]string, len(c.QueryNamespaces))
+	for i, ns := range c.QueryNamespaces {
+		namespaces[i] = expandNamespace(ns, dim)
+	}
+	return namespaces
+}
+
+// Dimensions searched by Answer
+func (c *Config) SearchDimensions() []int {
+	if len(c.QueryDimensions) > 0 {
+		return c.QueryDimensions
+	}
+	return c.Dimensions()
+}
@@ -0,0 +1,111 @@
+package ragbot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceFor(t *testing.T) {
+	c := &Config{Namespace: "training-{dim}"}
+	if got := c.NamespaceFor(384); got != "training-384" {
+		t.Errorf("NamespaceFor(384) = %q, want training-384", got)
+	}
+	if got := c.SearchNamespaces(1024); !reflect.DeepEqual(got, []string{"training-1024"}) {
+		t.Errorf("SearchNamespaces(1024) = %v, want [training-1024]", got)
+	}
+
+	c.QueryNamespaces = []string{"tenant-a-{dim}", "shared"}
+	if got := c.SearchNamespaces(512); !reflect.DeepEqual(got, []string{"tenant-a-512", "shared"}) {
+		t.Errorf("SearchNamespaces(512) = %v, want [tenant-a-512 shared]", got)
+	}
+
+	static := &Config{Namespace: "chatbot"}
+	if static.NamespaceFor(384) != "chatbot" || static.NamespaceFor(1024) != "chatbot" {
+		t.Error("a namespace without {dim} should be the same for every dimension")
+	}
+}
+
+func TestLoadConfigReadsSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "gemini_key")
+	if err := os.WriteFile(secret, []byte("  file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GEMINI_API_KEY", "env-key")
+	t.Setenv("GEMINI_API_KEY_FILE", secret)
+	t.Setenv("PINECONE_API_KEY", "pinecone-env-key")
+	t.Setenv("PINECONE_API_KEY_FILE", "")
+
+	c, err := LoadConfig(filepath.Join(dir, "missing.yaml"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.GeminiAPIKey != "file-key" {
+		t.Errorf("GeminiAPIKey = %q, want the trimmed file contents", c.GeminiAPIKey)
+	}
+	if c.PineconeAPIKey != "pinecone-env-key" {
+		t.Errorf("PineconeAPIKey = %q, want the env var without a _FILE", c.PineconeAPIKey)
+	}
+
+	t.Setenv("GEMINI_API_KEY_FILE", filepath.Join(dir, "absent"))
+	if _, err := LoadConfig(filepath.Join(dir, "missing.yaml"), false); err == nil || !strings.Contains(err.Error(), "GEMINI_API_KEY_FILE") {
+		t.Errorf("unreadable secret file: err = %v, want one naming GEMINI_API_KEY_FILE", err)
+	}
+}
+
+func TestValidateReportsIndexMisconfiguration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("indexes: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GEMINI_API_KEY", "g")
+	t.Setenv("PINECONE_API_KEY", "p")
+	c, err := LoadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "no indexes configured") {
+		t.Errorf("empty indexes: Validate = %v, want a no indexes error", err)
+	}
+
+	c = DefaultConfig()
+	c.GeminiAPIKey, c.PineconeAPIKey = "g", "p"
+	c.IndexEnvironments = map[string]string{"chatbot-embeddings-384-typo": "us-east-1"}
+	err = c.Validate()
+	for _, want := range []string{"index_environments: chatbot-embeddings-384-typo is not a configured index", "has no entry in index_environments"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestNewDefaultsAnswerSettings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey, cfg.PineconeAPIKey = "g", "p"
+	cfg.FallbackAnswer, cfg.ClarifyAnswer, cfg.AmbiguityEpsilon = "", "", 0
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, got := DefaultConfig(), b.Config()
+	if got.FallbackAnswer != def.FallbackAnswer || got.ClarifyAnswer != def.ClarifyAnswer || got.AmbiguityEpsilon != def.AmbiguityEpsilon {
+		t.Errorf("New left fallback %q, clarify %q, epsilon %v unset", got.FallbackAnswer, got.ClarifyAnswer, got.AmbiguityEpsilon)
+	}
+}
+
+func TestLoadConfigKeepsMMRLambdaZero(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("mmr:\n  enabled: true\n  lambda: 0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MMR.Lambda == nil || *c.MMR.Lambda != 0 {
+		t.Errorf("mmr lambda = %v, want an explicit 0", c.MMR.Lambda)
+	}
+}
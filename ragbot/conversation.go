@@ -0,0 +1,62 @@
+package ragbot
+
+import (
+	"context"
+	"strings"
+)
+
+// Number of previous user turns folded into each query by default
+const defaultContextTurns = 3
+
+// Multi-turn chat state. Recent user turns are prepended to each query before embedding,
+// so short follow-ups like "yes" or "I need it" are searched with the context they refer to.
+type ConversationSession struct {
+	Dimension int
+	TopK      int
+	MaxTurns  int // rolling window of previous user turns kept as context
+
+	bot     *Bot
+	history []string
+}
+
+// Start a conversation searching one dimension's index
+func (b *Bot) NewConversation(dimension, topK int) *ConversationSession {
+	return &ConversationSession{Dimension: dimension, TopK: topK, MaxTurns: defaultContextTurns, bot: b}
+}
+
+// Text embedded for the next query: the recent turns, oldest first, followed by the new input
+func (c *ConversationSession) contextualQuery(input string) string {
+	turns := c.history
+	if len(turns) > c.MaxTurns {
+		turns = turns[len(turns)-c.MaxTurns:]
+	}
+	return strings.Join(append(append([]string{}, turns...), input), "\n")
+}
+
+// Ask searches with the conversation context and records input as a new turn
+func (c *ConversationSession) Ask(ctx context.Context, input string) (*QueryResult, error) {
+	query := c.contextualQuery(input)
+	c.bot.log.Debug("conversation query", "turns", len(c.history), "query", query)
+
+	result, err := c.bot.searchSimilar(ctx, query, c.Dimension, c.TopK, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.history = append(c.history, input)
+	if len(c.history) > c.MaxTurns {
+		c.history = c.history[len(c.history)-c.MaxTurns:]
+	}
+
+	return result, nil
+}
+
+// Previous user turns currently kept as context
+func (c *ConversationSession) History() []string {
+	return append([]string{}, c.history...)
+}
+
+// Forget all previous turns
+func (c *ConversationSession) Reset() {
+	c.history = nil
+}
@@ -0,0 +1,151 @@
+package ragbot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// How Diff matches vectors between the two sides
+const (
+	DiffByHash   = "hash"    // content hash of input and output
+	DiffByPairID = "pair_id" // position in the source file; pairs from Add fall back to the hash
+)
+
+// One side of a Diff: an index by dimension and a namespace in it
+type DiffTarget struct {
+	Dimension int
+	Namespace string
+}
+
+func (t DiffTarget) String() string {
+	return fmt.Sprintf("dim %d namespace %q", t.Dimension, t.Namespace)
+}
+
+// A pair whose input is on both sides with a different output
+type ChangedPair struct {
+	Input     string `json:"input"`
+	OldOutput string `json:"old_output"`
+	NewOutput string `json:"new_output"`
+}
+
+// Differences between two targets. Added pairs are only in the second target,
+// removed pairs only in the first.
+type DiffReport struct {
+	Added     []InputOutputPair `json:"added"`
+	Removed   []InputOutputPair `json:"removed"`
+	Changed   []ChangedPair     `json:"changed"`
+	Unchanged int               `json:"unchanged"`
+}
+
+// Whether the two sides hold the same pairs
+func (r *DiffReport) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Compare the pairs stored in two targets, e.g. a namespace before and after a reindex,
+// matching vectors by DiffByHash or DiffByPairID
+func (b *Bot) Diff(ctx context.Context, from, to DiffTarget, by string) (*DiffReport, error) {
+	if by != DiffByHash && by != DiffByPairID {
+		return nil, fmt.Errorf("unknown diff key %q (want %q or %q)", by, DiffByHash, DiffByPairID)
+	}
+	read := func(t DiffTarget) ([]Metadata, error) {
+		var records []Metadata
+		err := b.ScanNamespace(ctx, t.Dimension, t.Namespace, func(page []Vector) error {
+			for _, v := range page {
+				records = append(records, v.Metadata)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", t, err)
+		}
+		return records, nil
+	}
+
+	old, err := read(from)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := read(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffPairs(old, cur, by), nil
+}
+
+// Key a stored pair is matched on
+func diffKey(m Metadata, by string) string {
+	if by == DiffByPairID && m.PairID != AddedPairID {
+		return fmt.Sprintf("pair %d", m.PairID)
+	}
+	if m.ContentHash != "" {
+		return "hash " + m.ContentHash
+	}
+	return "hash " + contentHash(InputOutputPair{Input: m.Input, Output: m.Output, Outputs: m.Outputs})
+}
+
+// Match records by key; records left over on both sides that share an input count as
+// changed rather than one removed and one added
+func diffPairs(old, cur []Metadata, by string) *DiffReport {
+	report := &DiffReport{}
+	oldByKey := map[string]Metadata{}
+	for _, m := range old {
+		oldByKey[diffKey(m, by)] = m
+	}
+
+	var added []Metadata
+	for _, m := range cur {
+		key := diffKey(m, by)
+		o, ok := oldByKey[key]
+		if !ok {
+			added = append(added, m)
+			continue
+		}
+		delete(oldByKey, key)
+		if o.Input == m.Input && o.Output == m.Output && slices.Equal(o.Outputs, m.Outputs) {
+			report.Unchanged++
+		} else {
+			report.Changed = append(report.Changed, ChangedPair{Input: m.Input, OldOutput: o.Output, NewOutput: m.Output})
+		}
+	}
+
+	removedByInput := map[string][]Metadata{}
+	for _, m := range oldByKey {
+		removedByInput[m.Input] = append(removedByInput[m.Input], m)
+	}
+	for _, m := range added {
+		if prev := removedByInput[m.Input]; len(prev) > 0 {
+			report.Changed = append(report.Changed, ChangedPair{Input: m.Input, OldOutput: prev[0].Output, NewOutput: m.Output})
+			removedByInput[m.Input] = prev[1:]
+			continue
+		}
+		report.Added = append(report.Added, InputOutputPair{Input: m.Input, Output: m.Output, Outputs: m.Outputs})
+	}
+	for _, ms := range removedByInput {
+		for _, m := range ms {
+			report.Removed = append(report.Removed, InputOutputPair{Input: m.Input, Output: m.Output, Outputs: m.Outputs})
+		}
+	}
+
+	// Scan and map order vary; sort for a stable report
+	sortPairs := func(ps []InputOutputPair) {
+		sort.Slice(ps, func(i, j int) bool {
+			if ps[i].Input != ps[j].Input {
+				return ps[i].Input < ps[j].Input
+			}
+			return ps[i].Output < ps[j].Output
+		})
+	}
+	sortPairs(report.Added)
+	sortPairs(report.Removed)
+	sort.Slice(report.Changed, func(i, j int) bool {
+		a, b := report.Changed[i], report.Changed[j]
+		if a.Input != b.Input {
+			return a.Input < b.Input
+		}
+		return a.NewOutput < b.NewOutput
+	})
+	return report
+}
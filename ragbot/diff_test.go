@@ -0,0 +1,39 @@
+package ragbot
+
+import "testing"
+
+func TestDiffPairs(t *testing.T) {
+	meta := func(pairID int, input, output string) Metadata {
+		return Metadata{PairID: pairID, Input: input, Output: output, ContentHash: contentHash(InputOutputPair{Input: input, Output: output})}
+	}
+	old := []Metadata{
+		meta(0, "where is my cab", "5 minutes away"),
+		meta(1, "cancel my ride", "Cancelled."),
+		meta(2, "book a ride", "When?"),
+	}
+	cur := []Metadata{
+		meta(0, "where is my cab", "5 minutes away"),
+		meta(1, "cancel my ride", "Your ride is cancelled."),
+		meta(AddedPairID, "refund", "Refunds take 3 days."),
+	}
+
+	for _, by := range []string{DiffByHash, DiffByPairID} {
+		r := diffPairs(old, cur, by)
+		if r.Unchanged != 1 {
+			t.Errorf("%s: unchanged = %d, want 1", by, r.Unchanged)
+		}
+		if len(r.Changed) != 1 || r.Changed[0].OldOutput != "Cancelled." || r.Changed[0].NewOutput != "Your ride is cancelled." {
+			t.Errorf("%s: changed = %+v, want the cancel pair", by, r.Changed)
+		}
+		if len(r.Added) != 1 || r.Added[0].Input != "refund" {
+			t.Errorf("%s: added = %+v, want the refund pair", by, r.Added)
+		}
+		if len(r.Removed) != 1 || r.Removed[0].Input != "book a ride" {
+			t.Errorf("%s: removed = %+v, want the booking pair", by, r.Removed)
+		}
+	}
+
+	if r := diffPairs(old, old, DiffByHash); !r.Empty() || r.Unchanged != 3 {
+		t.Errorf("identical sides: %+v, want no differences", r)
+	}
+}
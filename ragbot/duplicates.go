@@ -0,0 +1,101 @@
+package ragbot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Pairs whose inputs are near-duplicates of each other
+type DuplicateCluster struct {
+	Pairs              []int   // indexes into the pairs passed to FindDuplicates, ascending
+	MaxSimilarity      float32 // highest similarity between two members
+	MinSimilarity      float32 // lowest similarity among the links that joined the cluster
+	ConflictingOutputs bool    // members don't all share one output
+}
+
+// Embed every pair's input at dimension with TaskSemanticSimilarity and group pairs whose
+// inputs have cosine similarity of at least threshold. Similarity is transitive within a
+// cluster: A~B and B~C put A, B and C together. Clusters are ordered by MaxSimilarity.
+func (b *Bot) FindDuplicates(ctx context.Context, pairs []InputOutputPair, dimension int, threshold float32) ([]DuplicateCluster, error) {
+	vectors := make([][]float32, len(pairs))
+	for i, pair := range pairs {
+		v, err := b.getEmbedding(ctx, pair.Input, dimension, TaskSemanticSimilarity)
+		if err != nil {
+			return nil, fmt.Errorf("pair %d: %v", i, err)
+		}
+		vectors[i] = v
+		if b.progress != nil {
+			b.progress(dimension)
+		}
+	}
+
+	// Union-find over every pair of pairs above the threshold
+	parent := make([]int, len(pairs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	maxSim := map[int]float32{}
+	minSim := map[int]float32{}
+	type link struct {
+		a, b  int
+		score float32
+	}
+	var links []link
+	for i := range vectors {
+		for j := i + 1; j < len(vectors); j++ {
+			score, err := cosineSimilarity(vectors[i], vectors[j])
+			if err != nil {
+				return nil, fmt.Errorf("pairs %d and %d: %v", i, j, err)
+			}
+			if score >= threshold {
+				links = append(links, link{i, j, score})
+				parent[find(i)] = find(j)
+			}
+		}
+	}
+	for _, l := range links {
+		root := find(l.a)
+		if s, ok := maxSim[root]; !ok || l.score > s {
+			maxSim[root] = l.score
+		}
+		if s, ok := minSim[root]; !ok || l.score < s {
+			minSim[root] = l.score
+		}
+	}
+
+	members := map[int][]int{}
+	for i := range pairs {
+		root := find(i)
+		if _, ok := maxSim[root]; ok {
+			members[root] = append(members[root], i)
+		}
+	}
+
+	clusters := make([]DuplicateCluster, 0, len(members))
+	for root, idx := range members {
+		c := DuplicateCluster{Pairs: idx, MaxSimilarity: maxSim[root], MinSimilarity: minSim[root]}
+		for _, i := range idx[1:] {
+			if pairs[i].Output != pairs[idx[0]].Output {
+				c.ConflictingOutputs = true
+				break
+			}
+		}
+		clusters = append(clusters, c)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].MaxSimilarity != clusters[j].MaxSimilarity {
+			return clusters[i].MaxSimilarity > clusters[j].MaxSimilarity
+		}
+		return clusters[i].Pairs[0] < clusters[j].Pairs[0]
+	})
+	return clusters, nil
+}
@@ -0,0 +1,58 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	vectors := map[string]string{
+		"book a ride":        `[1,0,0]`,
+		"book a ride please": `[0.99,0.1,0]`,
+		"please book a ride": `[0.97,0.2,0]`,
+		"cancel my ride":     `[0,1,0]`,
+		"where is my driver": `[0,0,1]`,
+	}
+	var taskType string
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Content struct {
+					Parts []struct{ Text string } `json:"parts"`
+				} `json:"content"`
+				TaskType string `json:"taskType"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			taskType = req.TaskType
+			w.Write([]byte(`{"embedding":{"values":` + vectors[req.Content.Parts[0].Text] + `}}`))
+		},
+		failPinecone(t),
+	)
+
+	pairs := []InputOutputPair{
+		{Input: "book a ride", Output: "Booked"},
+		{Input: "cancel my ride", Output: "Cancelled"},
+		{Input: "book a ride please", Output: "Booked"},
+		{Input: "where is my driver", Output: "On the way"},
+		{Input: "please book a ride", Output: "Your ride is booked"},
+	}
+	clusters, err := b.FindDuplicates(context.Background(), pairs, 3, 0.95)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if taskType != TaskSemanticSimilarity {
+		t.Errorf("taskType = %s, want %s", taskType, TaskSemanticSimilarity)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters, want 1: %+v", len(clusters), clusters)
+	}
+	c := clusters[0]
+	if len(c.Pairs) != 3 || c.Pairs[0] != 0 || c.Pairs[1] != 2 || c.Pairs[2] != 4 {
+		t.Errorf("cluster pairs = %v, want [0 2 4]", c.Pairs)
+	}
+	if !c.ConflictingOutputs {
+		t.Error("cluster with different outputs not marked conflicting")
+	}
+}
@@ -0,0 +1,155 @@
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Source of embeddings for one model in an ensemble search
+type Embedder interface {
+	Embed(ctx context.Context, text string, dimension int, taskType string) ([]float32, error)
+}
+
+// Embedder calling a Gemini embedding model through the bot's rate limiter and cache
+type geminiEmbedder struct {
+	b     *Bot
+	model string
+}
+
+func (e geminiEmbedder) Embed(ctx context.Context, text string, dimension int, taskType string) ([]float32, error) {
+	return e.b.getModelEmbedding(ctx, e.model, text, dimension, taskType)
+}
+
+// Replace the embedder of the ensemble model called name, e.g. to plug in a non-Gemini model
+func (b *Bot) SetEmbedder(name string, e Embedder) error {
+	if _, ok := b.embedders[name]; !ok {
+		return fmt.Errorf("no ensemble model named %q", name)
+	}
+	b.embedders[name] = e
+	return nil
+}
+
+// A pair found by an ensemble search
+type EnsembleMatch struct {
+	Input  string
+	Output string
+	Score  float64            // weighted mean of the models' scores; a model that missed the pair counts 0
+	Scores map[string]float32 // raw score per model that returned the pair
+	Model  string             // model contributing the most to Score
+}
+
+// Result of EnsembleSearch
+type EnsembleResult struct {
+	Query     string
+	Dimension int
+	Matches   []EnsembleMatch  // best first
+	Errors    map[string]error // per model that failed
+}
+
+// Embed query with every Config.Ensemble model that has an index at dimension, search each
+// model's own index, and merge the matches by pair with the configured weights. An error is
+// returned only when every model failed; the result carries the per-model errors either way.
+func (b *Bot) EnsembleSearch(ctx context.Context, query string, dimension int, topK int) (*EnsembleResult, error) {
+	if b.local != nil {
+		return nil, ErrLocalBackend
+	}
+	if len(b.cfg.Ensemble) == 0 {
+		return nil, errors.New("no ensemble models configured")
+	}
+
+	query = b.preprocessQuery(query)
+	result := &EnsembleResult{Query: query, Dimension: dimension, Errors: map[string]error{}}
+	type pairKey struct{ input, output string }
+	merged := map[pairKey]*EnsembleMatch{}
+	var order []pairKey
+	totalWeight := 0.0
+	searched := 0
+
+	for _, m := range b.cfg.Ensemble {
+		indexName, ok := m.Indexes[dimension]
+		if !ok {
+			continue
+		}
+		searched++
+		weight := m.Weight
+		if weight == 0 {
+			weight = 1.0
+		}
+		totalWeight += weight
+
+		vector, err := b.embedders[m.Name].Embed(ctx, query, dimension, TaskRetrievalQuery)
+		if err != nil {
+			result.Errors[m.Name] = fmt.Errorf("failed to get embedding: %w", err)
+			continue
+		}
+		r, err := b.queryIndex(ctx, indexName, b.ensembleIndexURL(indexName), vector, nil, topK, b.cfg.NamespaceFor(len(vector)))
+		if err != nil {
+			result.Errors[m.Name] = err
+			continue
+		}
+
+		for _, match := range r.Matches {
+			key := pairKey{input: match.Metadata.Input, output: match.Metadata.Output}
+			em, ok := merged[key]
+			if !ok {
+				em = &EnsembleMatch{Input: key.input, Output: key.output, Scores: map[string]float32{}}
+				merged[key] = em
+				order = append(order, key)
+			}
+			// Indexes can hold a pair more than once (e.g. two ID schemes); keep its best score
+			if prev, ok := em.Scores[m.Name]; !ok || match.Score > prev {
+				em.Scores[m.Name] = match.Score
+			}
+		}
+	}
+
+	if searched == 0 {
+		return nil, fmt.Errorf("no ensemble model has an index for dimension %d", dimension)
+	}
+	if len(result.Errors) == searched {
+		errs := make([]error, 0, len(result.Errors))
+		for name, err := range result.Errors {
+			errs = append(errs, fmt.Errorf("%s: %v", name, err))
+		}
+		return result, errors.Join(errs...)
+	}
+
+	for _, key := range order {
+		em := merged[key]
+		best := 0.0
+		for _, m := range b.cfg.Ensemble {
+			score, ok := em.Scores[m.Name]
+			if !ok {
+				continue
+			}
+			weight := m.Weight
+			if weight == 0 {
+				weight = 1.0
+			}
+			contribution := weight * float64(score)
+			em.Score += contribution
+			if em.Model == "" || contribution > best {
+				em.Model, best = m.Name, contribution
+			}
+		}
+		em.Score /= totalWeight
+		result.Matches = append(result.Matches, *em)
+	}
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		return result.Matches[i].Score > result.Matches[j].Score
+	})
+	result.Matches = result.Matches[:min(topK, len(result.Matches))]
+	return result, nil
+}
+
+// Data-plane base URL of an ensemble model's index
+func (b *Bot) ensembleIndexURL(indexName string) string {
+	if b.cfg.PineconeBaseURL != "" {
+		return b.cfg.PineconeBaseURL
+	}
+	// Validate has checked every ensemble index has a host
+	host, _ := b.cfg.namedIndexHost(indexName, "ensemble")
+	return host
+}
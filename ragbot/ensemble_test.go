@@ -0,0 +1,85 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Embedder returning a fixed vector whose first value tells the fake Pinecone which model asked
+type fixedEmbedder []float32
+
+func (e fixedEmbedder) Embed(ctx context.Context, text string, dimension int, taskType string) ([]float32, error) {
+	return e, nil
+}
+
+func TestEnsembleSearchMergesWeightedScores(t *testing.T) {
+	pinecone := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var q struct {
+			Vector []float32 `json:"vector"`
+		}
+		json.NewDecoder(r.Body).Decode(&q)
+		if q.Vector[0] == 1 { // model "a"
+			w.Write([]byte(`{"matches":[
+				{"id":"1","score":0.9,"metadata":{"input":"book a ride","output":"Booking"}},
+				{"id":"2","score":0.5,"metadata":{"input":"cancel","output":"Cancelled"}}]}`))
+			return
+		}
+		w.Write([]byte(`{"matches":[
+			{"id":"2","score":0.95,"metadata":{"input":"cancel","output":"Cancelled"}},
+			{"id":"1","score":0.2,"metadata":{"input":"book a ride","output":"Booking"}}]}`))
+	}))
+	defer pinecone.Close()
+
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey = "test-gemini-key"
+	cfg.PineconeAPIKey = "test-pinecone-key"
+	cfg.PineconeBaseURL = pinecone.URL
+	cfg.Ensemble = []EnsembleModel{
+		{Name: "a", Indexes: map[int]string{2: "index-a"}, Weight: 3},
+		{Name: "b", Indexes: map[int]string{2: "index-b"}, Weight: 1},
+	}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	b.SetEmbedder("a", fixedEmbedder{1, 0})
+	b.SetEmbedder("b", fixedEmbedder{0, 1})
+
+	result, err := b.EnsembleSearch(context.Background(), "book a ride", 2, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(result.Matches))
+	}
+
+	// Booking: (3*0.9 + 1*0.2) / 4 = 0.725; Cancelled: (3*0.5 + 1*0.95) / 4 = 0.6125
+	best := result.Matches[0]
+	if best.Output != "Booking" || best.Model != "a" {
+		t.Errorf("best = %+v, want Booking from model a", best)
+	}
+	if d := best.Score - 0.725; d > 1e-6 || d < -1e-6 {
+		t.Errorf("best score = %v, want 0.725", best.Score)
+	}
+	if result.Matches[1].Model != "a" { // 3*0.5 outweighs 1*0.95
+		t.Errorf("runner-up model = %s, want a", result.Matches[1].Model)
+	}
+}
+
+func TestEnsembleSearchUnknownEmbedder(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey = "test-gemini-key"
+	cfg.PineconeAPIKey = "test-pinecone-key"
+	cfg.PineconeBaseURL = "http://127.0.0.1:0"
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := b.SetEmbedder("missing", fixedEmbedder{1}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected an unknown model error, got %v", err)
+	}
+}
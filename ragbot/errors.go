@@ -0,0 +1,106 @@
+package ragbot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// Header carrying the request ID on every outbound call
+const requestIDHeader = "X-Request-Id"
+
+// Outbound providers, for per-provider headers
+const (
+	providerGemini   = "gemini"
+	providerPinecone = "pinecone"
+)
+
+// Set the User-Agent and the configured extra headers for a provider on an outbound request
+func (b *Bot) setHeaders(req *http.Request, provider string) {
+	req.Header.Set("User-Agent", b.cfg.UserAgent)
+	extra := b.cfg.GeminiHeaders
+	if provider == providerPinecone {
+		extra = b.cfg.PineconeHeaders
+	}
+	for name, value := range extra {
+		req.Header.Set(name, value)
+	}
+}
+
+// Random ID attached to one outbound call and to any error it produces, so a failure
+// can be matched with its "sending request" log line
+func newRequestID() string {
+	var buf [8]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// A failed Gemini embedding call. Status is the HTTP status, 0 when no response arrived.
+// Pair is the pair's position for calls made by Index, -1 otherwise.
+type EmbedError struct {
+	RequestID string
+	Pair      int
+	Dimension int
+	Status    int
+	Err       error
+}
+
+func (e *EmbedError) Error() string {
+	return fmt.Sprintf("%v (request %s)", e.Err, e.RequestID)
+}
+
+func (e *EmbedError) Unwrap() error { return e.Err }
+
+// Whether retrying the embedding may succeed: server errors and failed connections
+func (e *EmbedError) Retryable() bool {
+	return e.Status == 0 || e.Status >= 500
+}
+
+// A failed Pinecone upsert of Vectors vectors. Status is the HTTP status, 0 when no response arrived.
+type UpsertError struct {
+	RequestID string
+	Dimension int
+	Vectors   int
+	Status    int
+	Err       error
+}
+
+func (e *UpsertError) Error() string {
+	return fmt.Sprintf("%v (request %s)", e.Err, e.RequestID)
+}
+
+func (e *UpsertError) Unwrap() error { return e.Err }
+
+// Whether retrying the upsert may succeed: server errors and failed connections
+func (e *UpsertError) Retryable() bool {
+	return e.Status == 0 || e.Status >= 500
+}
+
+// A failed Pinecone query. Status is the HTTP status, 0 when no response arrived.
+type QueryError struct {
+	RequestID string
+	Dimension int
+	Namespace string
+	Status    int
+	Err       error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%v (request %s)", e.Err, e.RequestID)
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// A failed Pinecone call of any other kind, returned by pineconePostURL and pineconeGet
+type pineconeError struct {
+	RequestID string
+	Status    int
+	Err       error
+}
+
+func (e *pineconeError) Error() string {
+	return fmt.Sprintf("%v (request %s)", e.Err, e.RequestID)
+}
+
+func (e *pineconeError) Unwrap() error { return e.Err }
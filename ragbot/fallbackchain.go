@@ -0,0 +1,33 @@
+package ragbot
+
+import "context"
+
+// Search the dimensions of Config.FallbackChain one at a time, in order, stopping at the
+// first confident answer. When none is confident, the response is built from every
+// dimension tried, as a full search would have given.
+func (b *Bot) answerChain(ctx context.Context, query string, vars map[string]string) (*Response, error) {
+	results, errs := map[int]*QueryResult{}, map[int]error{}
+	var tried []int
+	for _, dim := range b.cfg.FallbackChain {
+		if ctx.Err() != nil {
+			break
+		}
+		tried = append(tried, dim)
+		r, e := b.searchDimensions(ctx, query, []int{dim}, b.cfg.TopK)
+		if err, ok := e[dim]; ok {
+			errs[dim] = err
+			b.log.Warn("fallback chain dimension failed, trying the next", "dim", dim, "err", err)
+			continue
+		}
+		results[dim] = r[dim]
+
+		resp, err := b.answerFrom(query, vars, []int{dim}, map[int]*QueryResult{dim: r[dim]}, nil)
+		if err == nil && resp.Confident {
+			b.log.Info("🪜 Answered from fallback chain", "dim", dim, "tried", tried)
+			resp.Errors = errs
+			return resp, nil
+		}
+	}
+	b.log.Info("🪜 No dimension in the fallback chain was confident", "tried", tried)
+	return b.answerFrom(query, vars, tried, results, errs)
+}
@@ -0,0 +1,54 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestFallbackChainStopsAtFirstConfidentDimension(t *testing.T) {
+	scores := map[int]float64{1024: 0.3, 512: 0.9, 384: 0.95}
+	var queried []int
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				OutputDimensionality int `json:"outputDimensionality"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			w.Write(embeddingBody(req.OutputDimensionality))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Vector []float32 `json:"vector"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			dim := len(req.Vector)
+			queried = append(queried, dim)
+			fmt.Fprintf(w, `{"matches":[{"id":"m%d","score":%g,"metadata":{"input":"q","output":"from %d","dimension":%d}}]}`,
+				dim, scores[dim], dim, dim)
+		})
+	b.cfg.FallbackChain = []int{1024, 512, 384}
+	b.cfg.ScoreThreshold = 0.8
+
+	resp, err := b.Answer(context.Background(), "where is my driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Confident || resp.Best.Dimension != 512 || resp.Answer != "from 512" {
+		t.Errorf("answer %q from dimension %v, want a confident answer from 512", resp.Answer, resp.Best)
+	}
+	if want := []int{1024, 512}; !reflect.DeepEqual(queried, want) {
+		t.Errorf("queried %v, want %v (stop after the first confident dimension)", queried, want)
+	}
+
+	scores[512], scores[384] = 0.2, 0.1
+	queried = nil
+	resp, _ = b.Answer(context.Background(), "something else entirely")
+	if resp.Confident || len(resp.Results) != 3 || len(queried) != 3 {
+		t.Errorf("with no confident dimension: confident %v, %d results, queried %v; want the fallback after all 3",
+			resp.Confident, len(resp.Results), queried)
+	}
+}
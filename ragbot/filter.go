@@ -0,0 +1,180 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Whether a vector's metadata satisfies a Pinecone metadata filter, for operations
+// Pinecone can't filter server-side. Supports field equality, $eq, $ne, $gt, $gte, $lt,
+// $lte, $in, $nin, $exists, $and and $or.
+func matchesFilter(m Metadata, filter map[string]interface{}) (bool, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return false, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false, err
+	}
+	return evalFilter(fields, filter)
+}
+
+func evalFilter(fields map[string]interface{}, filter map[string]interface{}) (bool, error) {
+	for key, cond := range filter {
+		var ok bool
+		var err error
+		switch key {
+		case "$and", "$or":
+			ok, err = evalLogical(fields, key, cond)
+		default:
+			ok, err = evalField(fields[key], cond)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func evalLogical(fields map[string]interface{}, op string, cond interface{}) (bool, error) {
+	clauses, ok := cond.([]interface{})
+	if !ok {
+		return false, fmt.Errorf("%s needs a list of filters", op)
+	}
+	for _, c := range clauses {
+		clause, ok := c.(map[string]interface{})
+		if !ok {
+			return false, fmt.Errorf("%s needs a list of filters", op)
+		}
+		matched, err := evalFilter(fields, clause)
+		if err != nil {
+			return false, err
+		}
+		if matched == (op == "$or") {
+			return matched, nil
+		}
+	}
+	return op == "$and", nil
+}
+
+// Check one field's value (nil when absent) against a literal or an operator object
+func evalField(value interface{}, cond interface{}) (bool, error) {
+	ops, ok := cond.(map[string]interface{})
+	if !ok {
+		return value != nil && equalValues(value, cond), nil
+	}
+	for op, arg := range ops {
+		var ok bool
+		switch op {
+		case "$eq":
+			ok = value != nil && equalValues(value, arg)
+		case "$ne":
+			ok = value == nil || !equalValues(value, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			v, vok := value.(float64)
+			a, aok := arg.(float64)
+			if !aok {
+				return false, fmt.Errorf("%s needs a number", op)
+			}
+			switch op {
+			case "$gt":
+				ok = vok && v > a
+			case "$gte":
+				ok = vok && v >= a
+			case "$lt":
+				ok = vok && v < a
+			case "$lte":
+				ok = vok && v <= a
+			}
+		case "$in", "$nin":
+			list, lok := arg.([]interface{})
+			if !lok {
+				return false, fmt.Errorf("%s needs a list", op)
+			}
+			found := false
+			for _, item := range list {
+				if value != nil && equalValues(value, item) {
+					found = true
+					break
+				}
+			}
+			ok = found == (op == "$in")
+		case "$exists":
+			want, bok := arg.(bool)
+			if !bok {
+				return false, fmt.Errorf("$exists needs true or false")
+			}
+			ok = (value != nil) == want
+		default:
+			return false, fmt.Errorf("unsupported filter operator %s", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Compare JSON values, treating all numbers as float64 the way Pinecone does
+func equalValues(a, b interface{}) bool {
+	if n, ok := b.(int); ok {
+		b = float64(n)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// IDs of the vectors in a dimension's namespace whose metadata matches filter, found by
+// scanning the whole namespace
+func (b *Bot) MatchFilter(ctx context.Context, filter map[string]interface{}, dimension int) ([]string, error) {
+	var ids []string
+	err := b.Scan(ctx, dimension, func(page []Vector) error {
+		for _, v := range page {
+			ok, err := matchesFilter(v.Metadata, filter)
+			if err != nil {
+				return fmt.Errorf("invalid filter: %v", err)
+			}
+			if ok {
+				ids = append(ids, v.ID)
+			}
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// Delete every vector in a dimension's namespace whose metadata matches a Pinecone
+// filter, e.g. {"intent": "refund"} to retire a whole intent. Pinecone's delete-with-filter
+// is tried first and the count is then unknown (-1); indexes that reject it (serverless
+// ones do) are scanned instead and the matching vectors deleted by ID.
+func (b *Bot) DeleteByFilter(ctx context.Context, filter map[string]interface{}, dimension int) (int, error) {
+	if len(filter) == 0 {
+		return 0, fmt.Errorf("refusing to delete with an empty filter")
+	}
+	payload := map[string]interface{}{
+		"filter":    filter,
+		"namespace": b.cfg.NamespaceFor(dimension),
+	}
+	err := b.pineconePost(ctx, dimension, "/vectors/delete", payload, nil)
+	if err == nil {
+		return -1, nil
+	}
+	var pe *pineconeError
+	if !errors.As(err, &pe) || pe.Status != http.StatusBadRequest {
+		return 0, err
+	}
+	b.log.Warn("Pinecone rejected delete by filter, deleting matches by ID", "index", b.cfg.Indexes[dimension], "err", err)
+
+	ids, err := b.MatchFilter(ctx, filter, dimension)
+	if err != nil {
+		return 0, err
+	}
+	if err := b.deleteVectors(ctx, ids, dimension); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
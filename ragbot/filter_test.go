@@ -0,0 +1,39 @@
+package ragbot
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchesFilter(t *testing.T) {
+	m := Metadata{Input: "refund please", Output: "Refunds take 3 days.", Dimension: 384, PairID: 4, CreatedAt: 1700000000, Intent: "refund"}
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`{"intent":"refund"}`, true},
+		{`{"intent":"billing"}`, false},
+		{`{"intent":{"$in":["billing","refund"]}}`, true},
+		{`{"intent":{"$nin":["refund"]}}`, false},
+		{`{"dimension":384,"pair_id":{"$gte":4}}`, true},
+		{`{"created_at":{"$lt":1600000000}}`, false},
+		{`{"$or":[{"intent":"billing"},{"pair_id":4}]}`, true},
+		{`{"$and":[{"intent":"refund"},{"pair_id":{"$ne":4}}]}`, false},
+		{`{"id_scheme":{"$exists":false}}`, true},
+		{`{"category":"refund"}`, false}, // absent field
+	}
+	for _, tt := range tests {
+		var filter map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.filter), &filter); err != nil {
+			t.Fatal(err)
+		}
+		got, err := matchesFilter(m, filter)
+		if err != nil || got != tt.want {
+			t.Errorf("matchesFilter(%s) = %v, %v; want %v", tt.filter, got, err, tt.want)
+		}
+	}
+
+	if _, err := matchesFilter(m, map[string]interface{}{"intent": map[string]interface{}{"$regex": "ref"}}); err == nil {
+		t.Error("unsupported operator was accepted")
+	}
+}
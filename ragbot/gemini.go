@@ -0,0 +1,188 @@
+package ragbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Longest error response body included in an error message
+const maxErrorBody = 1024
+
+// Returned (inside an *EmbedError) when Gemini answers 200 with no embedding values,
+// which happens for some malformed inputs
+var ErrEmptyEmbedding = errors.New("Gemini returned an empty embedding")
+
+// Returned (inside an *EmbedError) when Gemini doesn't know the embedding model, e.g. one
+// that has been retired
+var ErrModelNotFound = errors.New("embedding model not found")
+
+// Gemini task types. Stored vectors must be embedded as documents and searches as queries:
+// Gemini optimizes the two sides of the pair differently, and embedding both sides with
+// the same task type measurably hurts retrieval quality.
+const (
+	TaskRetrievalDocument = "RETRIEVAL_DOCUMENT" // text being indexed (upserts)
+	TaskRetrievalQuery    = "RETRIEVAL_QUERY"    // text being searched for
+
+	// Symmetric comparison of two texts, e.g. finding near-duplicate pairs; not for search
+	TaskSemanticSimilarity = "SEMANTIC_SIMILARITY"
+)
+
+// Get embedding from Gemini API with Config.EmbeddingModel, or Config.FallbackEmbeddingModel
+// once the primary model is reported missing. taskType is one of the Task* constants.
+// Answers come from the disk cache when enabled, and concurrent identical requests
+// share a single API call.
+func (b *Bot) getEmbedding(ctx context.Context, text string, dimension int, taskType string) ([]float32, error) {
+	fallback := b.cfg.FallbackEmbeddingModel
+	if fallback != "" && b.primaryModelMissing.Load() {
+		return b.getModelEmbedding(ctx, fallback, text, dimension, taskType)
+	}
+	values, err := b.getModelEmbedding(ctx, b.cfg.EmbeddingModel, text, dimension, taskType)
+	if fallback == "" || !errors.Is(err, ErrModelNotFound) {
+		return values, err
+	}
+	if !b.primaryModelMissing.Swap(true) {
+		b.log.Warn("🔁 Embedding model not found, switching to the fallback", "model", b.cfg.EmbeddingModel, "fallback", fallback, "err", err)
+	}
+	return b.getModelEmbedding(ctx, fallback, text, dimension, taskType)
+}
+
+// getEmbedding with a specific Gemini embedding model
+func (b *Bot) getModelEmbedding(ctx context.Context, model string, text string, dimension int, taskType string) ([]float32, error) {
+	text, err := b.limitInput(text, dimension)
+	if err != nil {
+		return nil, err
+	}
+	key := embeddingKey(model, text, dimension, taskType)
+	if b.cache != nil {
+		if values, ok := b.cache.get(key); ok {
+			return values, nil
+		}
+	}
+
+	// The call is shared by every caller waiting on key, so it runs detached from whichever
+	// caller started it; each caller still stops waiting when its own ctx ends
+	flight := b.flight.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.cfg.GeminiRetry.budget())
+		defer cancel()
+		values, err := b.fetchEmbedding(ctx, model, text, dimension, taskType)
+		if err == nil && b.cache != nil {
+			if err := b.cache.put(key, values); err != nil {
+				b.log.Warn("failed to cache embedding", "err", err)
+			}
+		}
+		return values, err
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-flight:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// Callers sharing a flight get the same slice; give each its own copy
+		return append([]float32(nil), res.Val.([]float32)...), nil
+	}
+}
+
+// Call the Gemini embedContent API, retrying 5xx responses, failed connections and
+// timeouts per Config.GeminiRetry. Pacing under Config.EmbeddingRPM and retries of
+// 429 responses happen in the client's rateLimitTransport.
+func (b *Bot) fetchEmbedding(ctx context.Context, model string, text string, dimension int, taskType string) ([]float32, error) {
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		values, err := b.embedOnce(ctx, model, text, dimension, taskType)
+		b.telemetry.recordEmbedding(dimension, len(text), time.Since(start), err)
+
+		var ee *EmbedError
+		if err == nil || !errors.As(err, &ee) || !ee.Retryable() || ctx.Err() != nil {
+			return values, err
+		}
+		if !b.cfg.GeminiRetry.allows(attempt) {
+			ee.Err = fmt.Errorf("%v (gave up after %d attempts)", ee.Err, attempt+1)
+			return nil, ee
+		}
+		delay := b.cfg.GeminiRetry.delay(b.jitter, attempt)
+		b.log.Warn("gemini embedding failed, retrying", "model", model, "dim", dimension,
+			"attempt", attempt+1, "delay", delay, "err", err)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Send one embedContent request. Failures are *EmbedError carrying the HTTP status.
+func (b *Bot) embedOnce(ctx context.Context, model string, text string, dimension int, taskType string) ([]float32, error) {
+	url := b.cfg.GeminiBaseURL + "/models/" + model + ":embedContent?key=" + b.cfg.GeminiAPIKey
+
+	payload := map[string]interface{}{
+		"content": map[string]interface{}{
+			"parts": []map[string]string{
+				{"text": text},
+			},
+		},
+		"taskType":             taskType,
+		"outputDimensionality": dimension,
+	}
+
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	b.setHeaders(req, providerGemini)
+	requestID := newRequestID()
+	req.Header.Set(requestIDHeader, requestID)
+	b.log.Debug("sending request", "request_id", requestID, "service", providerGemini, "model", model, "dim", dimension, "task_type", taskType)
+	b.logRequest(req, body)
+
+	fail := func(status int, err error) ([]float32, error) {
+		b.log.Debug("request failed", "request_id", requestID, "service", providerGemini, "status", status, "err", err)
+		return nil, &EmbedError{RequestID: requestID, Pair: -1, Dimension: dimension, Status: status, Err: err}
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return fail(0, fmt.Errorf("API request failed: %v", err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		// Gemini explains the failure (quota, invalid dimension, bad key) in the body
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody+1))
+		msg := strings.TrimSpace(string(body))
+		if len(body) > maxErrorBody {
+			msg = strings.TrimSpace(string(body[:maxErrorBody])) + "... (truncated)"
+		}
+		if res.StatusCode == http.StatusNotFound {
+			return fail(res.StatusCode, fmt.Errorf("%w: %s (status 404: %s)", ErrModelNotFound, model, msg))
+		}
+		return fail(res.StatusCode, fmt.Errorf("API returned status %d: %s", res.StatusCode, msg))
+	}
+
+	var resp EmbeddingResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return fail(res.StatusCode, fmt.Errorf("failed to decode response: %v", err))
+	}
+
+	// Never let an empty vector reach an upsert, where it would be stored useless or fail
+	// with a confusing dimension error
+	if len(resp.Embedding.Values) == 0 {
+		return fail(res.StatusCode, fmt.Errorf("%w for a %d-character input", ErrEmptyEmbedding, len(text)))
+	}
+
+	// A size other than the one requested means outputDimensionality and the index have drifted
+	// apart; catch it here rather than as a dimension error from Pinecone on upsert or query
+	if len(resp.Embedding.Values) != dimension {
+		return fail(res.StatusCode, fmt.Errorf("Gemini returned %d values for requested dimension %d: check the model supports outputDimensionality %d",
+			len(resp.Embedding.Values), dimension, dimension))
+	}
+
+	b.log.Debug("embedding served", "request_id", requestID, "model", model, "dim", dimension)
+	b.logEmbedding(dimension, taskType, resp.Embedding.Values)
+	return resp.Embedding.Values, nil
+}
@@ -0,0 +1,333 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Fake Pinecone that fails every call; getEmbedding tests must not touch it
+func failPinecone(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected Pinecone call to %s", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Gemini response body holding an embedding of dim values
+func embeddingBody(dim int) []byte {
+	values := make([]float32, dim)
+	for i := range values {
+		values[i] = float32(i+1) / float32(dim)
+	}
+	data, _ := json.Marshal(map[string]interface{}{"embedding": map[string]interface{}{"values": values}})
+	return data
+}
+
+func TestGetEmbeddingReturnsValues(t *testing.T) {
+	var got map[string]interface{}
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, ":embedContent") {
+				t.Errorf("unexpected path %s", r.URL.Path)
+			}
+			json.NewDecoder(r.Body).Decode(&got)
+			w.Write([]byte(`{"embedding":{"values":[0.25,-0.5,1]}}`))
+		},
+		failPinecone(t),
+	)
+
+	values, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 3, TaskRetrievalDocument)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []float32{0.25, -0.5, 1}
+	if len(values) != len(want) {
+		t.Fatalf("got %v, want %v", values, want)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Fatalf("got %v, want %v", values, want)
+		}
+	}
+
+	if got["taskType"] != "RETRIEVAL_DOCUMENT" {
+		t.Errorf("taskType = %v, want RETRIEVAL_DOCUMENT", got["taskType"])
+	}
+	if got["outputDimensionality"] != float64(3) {
+		t.Errorf("outputDimensionality = %v, want 3", got["outputDimensionality"])
+	}
+}
+
+func TestGetEmbeddingRateLimited(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"code":429,"status":"RESOURCE_EXHAUSTED"}}`))
+		},
+		failPinecone(t),
+	)
+
+	_, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 384, TaskRetrievalDocument)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected a 429 error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "RESOURCE_EXHAUSTED") {
+		t.Errorf("error should include the response body, got %q", err)
+	}
+}
+
+func TestGetEmbeddingTruncatesLongErrorBody(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(strings.Repeat("x", 10*maxErrorBody)))
+		},
+		failPinecone(t),
+	)
+
+	_, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 384, TaskRetrievalDocument)
+	if err == nil || !strings.Contains(err.Error(), "(truncated)") {
+		t.Fatalf("expected a truncated error body, got %v", err)
+	}
+	if len(err.Error()) > 2*maxErrorBody {
+		t.Errorf("error is %d bytes, want at most about %d", len(err.Error()), maxErrorBody)
+	}
+}
+
+func TestGetEmbeddingMalformedBody(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"embedding":`))
+		},
+		failPinecone(t),
+	)
+
+	_, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 384, TaskRetrievalDocument)
+	if err == nil || !strings.Contains(err.Error(), "decode") {
+		t.Fatalf("expected a decode error, got %v", err)
+	}
+}
+
+func TestGetEmbeddingDimensionMismatch(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"embedding":{"values":[0.25,-0.5,1,0]}}`))
+		},
+		failPinecone(t),
+	)
+
+	_, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 3, TaskRetrievalDocument)
+	if err == nil || !strings.Contains(err.Error(), "returned 4 values for requested dimension 3") {
+		t.Fatalf("expected a dimension mismatch error, got %v", err)
+	}
+}
+
+func TestGetEmbeddingEmptyValues(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"embedding":{"values":[]}}`))
+		},
+		failPinecone(t),
+	)
+
+	_, err := b.getEmbedding(context.Background(), "Book my ride", 3, TaskRetrievalDocument)
+	if !errors.Is(err, ErrEmptyEmbedding) {
+		t.Fatalf("expected ErrEmptyEmbedding, got %v", err)
+	}
+	var embedErr *EmbedError
+	if !errors.As(err, &embedErr) || embedErr.Status != http.StatusOK {
+		t.Fatalf("expected an *EmbedError with status 200, got %#v", err)
+	}
+
+	// Indexing records the pair as failed instead of upserting an empty vector
+	b.cfg.Indexes = map[int]string{384: "chatbot-embeddings-384"}
+	report, _ := b.Index(context.Background(), []InputOutputPair{{Input: "Book my ride", Output: "When?"}})
+	if len(report.Failures) == 0 || !strings.Contains(report.Failures[0].Error, "empty embedding") {
+		t.Fatalf("expected the pair to be recorded as failed, got %+v", report.Failures)
+	}
+}
+
+func TestGetEmbeddingSharesConcurrentIdenticalRequests(t *testing.T) {
+	var hits atomic.Int32
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			// Hold the request open so every caller arrives while it is in flight
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{"embedding":{"values":[0.25,-0.5,1]}}`))
+		},
+		failPinecone(t),
+	)
+
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = b.getEmbedding(context.Background(), "Where is my cab?", 3, TaskRetrievalQuery)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Gemini was called %d times, want 1", got)
+	}
+}
+
+func TestGetEmbeddingSharedCallOutlivesCancelledCaller(t *testing.T) {
+	var hits atomic.Int32
+	arrived, release := make(chan struct{}), make(chan struct{})
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			if hits.Add(1) == 1 {
+				close(arrived)
+			}
+			<-release
+			w.Write([]byte(`{"embedding":{"values":[0.25,-0.5,1]}}`))
+		},
+		failPinecone(t),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := b.getEmbedding(ctx, "Where is my cab?", 3, TaskRetrievalQuery)
+		first <- err
+	}()
+	<-arrived
+	second := make(chan error, 1)
+	go func() {
+		_, err := b.getEmbedding(context.Background(), "Where is my cab?", 3, TaskRetrievalQuery)
+		second <- err
+	}()
+	time.Sleep(50 * time.Millisecond) // let the second caller join the flight
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("caller sharing the flight failed with %v after the first caller cancelled", err)
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Gemini was called %d times, want 1", got)
+	}
+}
+
+func TestGetEmbeddingUsesDiskCache(t *testing.T) {
+	var hits atomic.Int32
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			w.Write([]byte(`{"embedding":{"values":[0.25,-0.5,1]}}`))
+		},
+		failPinecone(t),
+	)
+	b.cache = &embeddingCache{dir: t.TempDir()}
+
+	for i := 0; i < 3; i++ {
+		if _, err := b.getEmbedding(context.Background(), "Where is my cab?", 3, TaskRetrievalQuery); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("Gemini was called %d times, want 1", got)
+	}
+
+	// A different task type is a different embedding
+	b.getEmbedding(context.Background(), "Where is my cab?", 3, TaskRetrievalDocument)
+	if got := hits.Load(); got != 2 {
+		t.Errorf("Gemini was called %d times, want 2", got)
+	}
+}
+
+func TestGetEmbeddingFallsBackToSecondModel(t *testing.T) {
+	var models []string
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			models = append(models, r.URL.Path)
+			if strings.Contains(r.URL.Path, "/models/retired-model:") {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"code":404,"message":"models/retired-model is not found"}}`))
+				return
+			}
+			w.Write(embeddingBody(384))
+		},
+		failPinecone(t),
+	)
+	b.cfg.EmbeddingModel = "retired-model"
+
+	_, err := b.getEmbedding(context.Background(), "Book my ride", 384, TaskRetrievalQuery)
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("without a fallback: got %v, want ErrModelNotFound", err)
+	}
+
+	b.cfg.FallbackEmbeddingModel = "gemini-embedding-001"
+	models = nil
+	for _, text := range []string{"Book my ride", "Cancel my ride"} {
+		if _, err := b.getEmbedding(context.Background(), text, 384, TaskRetrievalQuery); err != nil {
+			t.Fatalf("getEmbedding(%q): %v", text, err)
+		}
+	}
+	// The retired model is only tried once; afterwards calls go straight to the fallback
+	want := []string{"/models/retired-model:embedContent", "/models/gemini-embedding-001:embedContent", "/models/gemini-embedding-001:embedContent"}
+	if strings.Join(models, " ") != strings.Join(want, " ") {
+		t.Errorf("requested %v, want %v", models, want)
+	}
+}
+
+func TestGetEmbeddingRetriesTimeoutsAndServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	g := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			time.Sleep(200 * time.Millisecond) // past the attempt timeout
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(embeddingBody(384))
+	}))
+	defer g.Close()
+
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey = "test-gemini-key"
+	cfg.PineconeAPIKey = "test-pinecone-key"
+	cfg.GeminiBaseURL = g.URL
+	cfg.GeminiRetry = RetryPolicy{Timeout: 50 * time.Millisecond, MaxRetries: 2, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	values, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 384, TaskRetrievalDocument)
+	if err != nil || len(values) != 384 {
+		t.Fatalf("got %d values, err %v, want success on the third attempt", len(values), err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("%d calls, want 3", n)
+	}
+
+	b.cfg.GeminiRetry.MaxRetries = -1
+	calls.Store(1) // next call fails with 503
+	if _, err := b.getEmbedding(context.Background(), "Another ride", 384, TaskRetrievalDocument); err == nil {
+		t.Error("max_retries -1 should not retry the 503")
+	}
+}
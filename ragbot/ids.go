@@ -0,0 +1,44 @@
+package ragbot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Vector ID scheme, stored in each vector's metadata as "id_scheme".
+// The ID is "pair_<h>_dim_<dim>" where h is the first 16 hex characters of
+// sha256(input), so editing a pair's answer overwrites its vector in place instead
+// of leaving the old answer behind. Pairs sharing an input share a vector: Index
+// stores them as one one-to-many vector holding all their outputs. Vectors under
+// the earlier position-based "pair_<i>_dim_<dim>" IDs are moved by MigrateIDs.
+const idScheme = "sha256-input-v1"
+
+// Stable hash of a pair's input, independent of its position in the input file
+func inputHash(input string) string {
+	sum := sha256.Sum256([]byte(input))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// Hash of a pair's input and outputs, stored as content_hash so a changed answer can
+// be told apart from an unchanged one under the same ID. A one-to-many pair's further
+// outputs are appended, each after another "\x00".
+func contentHash(pair InputOutputPair) string {
+	content := pair.Input + "\x00" + pair.Output
+	if len(pair.Outputs) > 1 {
+		content += "\x00" + strings.Join(pair.Outputs[1:], "\x00")
+	}
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// ID the pair's vector is stored under at a dimension
+func VectorID(pair InputOutputPair, dimension int) string {
+	return contentID(pair, dimension)
+}
+
+// Deterministic vector ID for a pair at a dimension, from its input only
+func contentID(pair InputOutputPair, dimension int) string {
+	return fmt.Sprintf("pair_%s_dim_%d", inputHash(pair.Input), dimension)
+}
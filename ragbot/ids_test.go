@@ -0,0 +1,118 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestContentIDDependsOnInputAndDimension(t *testing.T) {
+	pair := InputOutputPair{Input: "cancel my ride", Output: "Done"}
+	edited := InputOutputPair{Input: "cancel my ride", Output: "Your ride is cancelled"}
+	if contentID(pair, 384) != contentID(edited, 384) {
+		t.Error("editing the answer changed the vector ID")
+	}
+	if contentHash(pair) == contentHash(edited) {
+		t.Error("editing the answer should change the content hash")
+	}
+	if contentID(pair, 384) == contentID(pair, 1024) {
+		t.Error("dimensions should get different IDs")
+	}
+}
+
+// Fake Pinecone index for list, fetch, upsert and delete, keyed by vector ID
+type fakeIndex struct {
+	mu      sync.Mutex
+	vectors map[string]Vector
+}
+
+func (f *fakeIndex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/vectors/list"):
+		var page struct {
+			Vectors []map[string]string `json:"vectors"`
+		}
+		for id := range f.vectors {
+			page.Vectors = append(page.Vectors, map[string]string{"id": id})
+		}
+		json.NewEncoder(w).Encode(page)
+	case strings.HasSuffix(r.URL.Path, "/vectors/fetch"):
+		found := map[string]Vector{}
+		for _, id := range r.URL.Query()["ids"] {
+			if v, ok := f.vectors[id]; ok {
+				found[id] = v
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"vectors": found})
+	case strings.HasSuffix(r.URL.Path, "/vectors/upsert"):
+		var req struct {
+			Vectors []Vector `json:"vectors"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, v := range req.Vectors {
+			f.vectors[v.ID] = v
+		}
+		w.Write([]byte(`{}`))
+	case strings.HasSuffix(r.URL.Path, "/vectors/delete"):
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		for _, id := range req.IDs {
+			delete(f.vectors, id)
+		}
+		w.Write([]byte(`{}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestMigrateIDsMovesOldVectors(t *testing.T) {
+	ride := InputOutputPair{Input: "cancel my ride", Output: "Done"}
+	rideAgain := InputOutputPair{Input: "cancel my ride", Output: "Cancelled"}
+	cab := InputOutputPair{Input: "where is my cab", Output: "On the way"}
+	old := func(id string, pair InputOutputPair) Vector {
+		return Vector{ID: id, Values: []float32{1, 0, 0}, Metadata: Metadata{Input: pair.Input, Output: pair.Output}}
+	}
+	index := &fakeIndex{vectors: map[string]Vector{
+		"pair_0_dim_384":    old("pair_0_dim_384", ride),
+		"pair_1_dim_384":    old("pair_1_dim_384", rideAgain),
+		"pair_2_dim_384":    old("pair_2_dim_384", cab),
+		contentID(cab, 384): old(contentID(cab, 384), InputOutputPair{Input: cab.Input, Output: "Arriving now"}),
+		"faq-cab":           old("faq-cab", cab),
+	}}
+	b := newTestBot(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("migration should not embed")
+	}, index.ServeHTTP)
+
+	report, err := b.MigrateIDs(context.Background(), 384, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 5 || report.Moved != 1 || report.Deleted != 3 {
+		t.Errorf("report %+v, want 5 scanned, 1 moved, 3 deleted", report)
+	}
+	ids := make([]string, 0, len(index.vectors))
+	for id := range index.vectors {
+		ids = append(ids, id)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("index holds %v, want one vector per input and the foreign ID", ids)
+	}
+	if _, ok := index.vectors["faq-cab"]; !ok {
+		t.Error("a vector outside the positional scheme was migrated")
+	}
+	merged := index.vectors[contentID(ride, 384)]
+	if !reflect.DeepEqual(merged.Metadata.Outputs, []string{"Done", "Cancelled"}) && !reflect.DeepEqual(merged.Metadata.Outputs, []string{"Cancelled", "Done"}) {
+		t.Errorf("merged ride vector has outputs %v, want both answers", merged.Metadata.Outputs)
+	}
+	if got := index.vectors[contentID(cab, 384)].Metadata.Output; got != "Arriving now" {
+		t.Errorf("the vector already under the current ID was overwritten with %q", got)
+	}
+}
@@ -0,0 +1,316 @@
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// How long an interrupted Index may spend flushing the vectors it already embedded
+const flushTimeout = 2 * time.Second
+
+// Outcome of Index. When Interrupted, PartialDimension holds vectors for pairs
+// [0, NextPair) and none after; dimensions after it were not started.
+type IndexReport struct {
+	Metrics             *RunMetrics
+	CompletedDimensions []int
+	Rejected            []int // indexes of pairs that failed CheckPair and were not uploaded
+	Interrupted         bool
+	PartialDimension    int
+	NextPair            int
+	PartialUploaded     int
+	Failures            []PairFailure // pairs left out of a dimension because embedding failed
+}
+
+// A pair whose embedding failed at one dimension and so is missing from that index
+type PairFailure struct {
+	PairID    int      `json:"pair_id"`
+	Dimension int      `json:"dimension"`
+	Input     string   `json:"input"`
+	Output    string   `json:"output"`
+	Outputs   []string `json:"outputs,omitempty"`
+	Error     string   `json:"error"`
+}
+
+// Embed pairs at every configured dimension and upload them to the matching indexes.
+// If ctx is cancelled, the vectors already embedded for the current dimension are flushed
+// and the report says where indexing stopped. Failed dimensions are joined into the error.
+func (b *Bot) Index(ctx context.Context, pairs []InputOutputPair) (*IndexReport, error) {
+	return b.IndexPairs(ctx, pairs, nil)
+}
+
+// Index pairs that are a subset of a larger source, e.g. earlier failures being retried.
+// pairIDs[i] is pairs[i]'s position in the source and is stored as its pair_id; nil
+// means the pairs are the whole source in order.
+func (b *Bot) IndexPairs(ctx context.Context, pairs []InputOutputPair, pairIDs []int) (*IndexReport, error) {
+	if pairIDs != nil && len(pairIDs) != len(pairs) {
+		return nil, fmt.Errorf("got %d pair IDs for %d pairs", len(pairIDs), len(pairs))
+	}
+	pairID := func(i int) int {
+		if pairIDs == nil {
+			return i
+		}
+		return pairIDs[i]
+	}
+	pairs = b.sanitizePairs(pairs)
+	dimensions := b.cfg.Dimensions()
+	report := &IndexReport{Metrics: newRunMetrics(), CompletedDimensions: []int{}}
+	metrics := report.Metrics
+	var failed []error
+
+	b.log.Info("📊 Processing input-output pairs", "pairs", len(pairs), "dimensions", len(dimensions))
+
+	// Reject corrupted pairs before spending any embedding calls on them
+	rejected := make([]bool, len(pairs))
+	for i, pair := range pairs {
+		if err := CheckPair(pair); err != nil {
+			b.log.Error("rejecting corrupted pair", "pair", i, "input", pair.Input, "err", err)
+			rejected[i] = true
+			report.Rejected = append(report.Rejected, i)
+		}
+	}
+
+	// The local backend keeps the pairs in memory and embeds them when first searched
+	if b.local != nil {
+		added := b.local.add(pairs)
+		b.log.Info("📥 Loaded pairs into local backend", "added", added)
+		for _, dim := range dimensions {
+			report.CompletedDimensions = append(report.CompletedDimensions, dim)
+		}
+		return report, nil
+	}
+
+	// Pairs sharing an input share its embedding, so each distinct input costs one call
+	// per dimension, or one call in all with TruncateEmbeddings: it is embedded once at
+	// the largest dimension and the smaller vectors are cut from that
+	maxDim := dimensions[len(dimensions)-1]
+	full := map[string][]float32{}
+
+	for _, dim := range dimensions {
+		b.log.Info("🔄 Processing dimension", "dim", dim, "gemini_delay", b.limiter.Delay())
+		dimStart := time.Now()
+		var vectors []Vector
+		next := 0
+		byInput := map[string][]float32{}
+		byID := map[string]int{} // position in vectors
+		reused, merged := 0, 0
+
+		// Embed cfg.Concurrency pairs at a time. A chunk cut short by an interrupt is
+		// dropped whole, so the report always describes a prefix of the pairs.
+		for start := 0; start < len(pairs) && ctx.Err() == nil; start += b.cfg.Concurrency {
+			end := min(start+b.cfg.Concurrency, len(pairs))
+
+			// Distinct inputs of the chunk not embedded at this dimension yet
+			var inputs []string
+			pending := map[string]bool{}
+			for i := start; i < end; i++ {
+				input := pairs[i].Input
+				if _, done := byInput[input]; rejected[i] || done || pending[input] {
+					continue
+				}
+				pending[input] = true
+				inputs = append(inputs, input)
+			}
+			embeddings := make([][]float32, len(inputs))
+			fulls := make([][]float32, len(inputs))
+			errs := make([]error, len(inputs))
+			called := make([]bool, len(inputs))
+
+			var wg sync.WaitGroup
+			for j, input := range inputs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if !b.cfg.TruncateEmbeddings {
+						called[j] = true
+						embeddings[j], errs[j] = b.getEmbedding(ctx, input, dim, TaskRetrievalDocument)
+						return
+					}
+					values, ok := full[input]
+					if !ok {
+						called[j] = true
+						if values, errs[j] = b.getEmbedding(ctx, input, maxDim, TaskRetrievalDocument); errs[j] != nil {
+							return
+						}
+						fulls[j] = values
+					}
+					embeddings[j], errs[j] = truncateEmbedding(values, dim)
+				}()
+			}
+			wg.Wait()
+			if ctx.Err() != nil {
+				// The calls were cut short by the interrupt, not real failures
+				break
+			}
+			next = end
+
+			failedInputs := map[string]error{}
+			// A truncated embedding was bought at maxDim, so its call counts there
+			callDim := dim
+			if b.cfg.TruncateEmbeddings {
+				callDim = maxDim
+			}
+			for j, input := range inputs {
+				if called[j] {
+					metrics.RecordEmbedding(callDim, len(input), errs[j])
+				}
+				if fulls[j] != nil {
+					full[input] = fulls[j]
+				}
+				if errs[j] != nil {
+					failedInputs[input] = errs[j]
+					continue
+				}
+				byInput[input] = embeddings[j]
+			}
+
+			for i := start; i < end; i++ {
+				if b.progress != nil {
+					b.progress(dim)
+				}
+				if rejected[i] {
+					continue
+				}
+				pair := pairs[i]
+				embedding, ok := byInput[pair.Input]
+				if !ok {
+					err := failedInputs[pair.Input]
+					if e, ok := err.(*EmbedError); ok {
+						// Calls may be shared with other pairs and callers, so tag a copy with the pair
+						tagged := *e
+						tagged.Pair = pairID(i)
+						err = &tagged
+					}
+					b.log.Error("failed to get embedding", "pair", pairID(i), "dim", dim, "err", err)
+					report.Failures = append(report.Failures, PairFailure{
+						PairID: pairID(i), Dimension: dim, Input: pair.Input, Output: pair.Output, Outputs: pair.Outputs, Error: err.Error(),
+					})
+					continue
+				}
+				if !pending[pair.Input] {
+					reused++
+				}
+				delete(pending, pair.Input)
+				id := contentID(pair, dim)
+				if j, ok := byID[id]; ok {
+					mergeOutputs(&vectors[j], pair)
+					merged++
+					continue
+				}
+				byID[id] = len(vectors)
+				vectors = append(vectors, b.buildVector(pair, pairID(i), dim, embedding))
+			}
+		}
+		if reused > 0 {
+			b.log.Info("♻️ Reused embeddings of repeated inputs", "dim", dim, "pairs", reused)
+		}
+		if merged > 0 {
+			b.log.Info("🔀 Merged pairs sharing an input into one-to-many vectors", "dim", dim, "pairs", merged)
+		}
+
+		if b.snapshot != nil {
+			if err := b.snapshot.Write(vectors); err != nil {
+				b.log.Error("failed to write snapshot", "dim", dim, "err", err)
+			}
+		}
+
+		if ctx.Err() != nil {
+			report.Interrupted = true
+			report.PartialDimension = dim
+			report.NextPair = next
+			report.PartialUploaded = b.flushInterrupted(vectors, dim)
+			metrics.Dimension(dim).Elapsed = time.Since(dimStart)
+			break
+		}
+
+		// Upload to Pinecone
+		if len(vectors) > 0 {
+			err := b.upsertToPinecone(ctx, vectors, dim)
+			if err != nil {
+				b.log.Error("failed to upload vectors", "dim", dim, "err", err)
+				failed = append(failed, fmt.Errorf("dimension %d: %v", dim, err))
+			} else {
+				b.log.Info("✅ Uploaded vectors", "dim", dim, "vectors", len(vectors))
+				report.CompletedDimensions = append(report.CompletedDimensions, dim)
+			}
+		}
+		metrics.Dimension(dim).Elapsed = time.Since(dimStart)
+
+		// Small delay between dimensions
+		sleepCtx(ctx, 500*time.Millisecond)
+	}
+
+	return report, errors.Join(failed...)
+}
+
+// Create the vector for a pair with rich metadata
+func (b *Bot) buildVector(pair InputOutputPair, pairID, dim int, embedding []float32) Vector {
+	vector := Vector{
+		ID:     contentID(pair, dim),
+		Values: embedding,
+		Metadata: Metadata{
+			Input:       pair.Input,
+			Output:      pair.Output,
+			Outputs:     pair.Outputs,
+			Dimension:   dim,
+			PairID:      pairID,
+			ContentHash: contentHash(pair),
+			IDScheme:    idScheme,
+			CreatedAt:   time.Now().Unix(),
+			InputLen:    len(pair.Input),
+			OutputLen:   len(pair.Output),
+		},
+	}
+
+	if b.cfg.Hybrid {
+		if sparse := sparseEncode(pair.Input); len(sparse.Indices) > 0 {
+			vector.SparseValues = &sparse
+		}
+	}
+
+	return vector
+}
+
+// Fold the outputs of another pair with v's input into v, making it a one-to-many
+// vector. Outputs it already holds are not repeated.
+func mergeOutputs(v *Vector, pair InputOutputPair) {
+	m := &v.Metadata
+	outputs := m.Outputs
+	if outputs == nil {
+		outputs = []string{m.Output}
+	}
+	more := pair.Outputs
+	if more == nil {
+		more = []string{pair.Output}
+	}
+	for _, out := range more {
+		if !slices.Contains(outputs, out) {
+			outputs = append(outputs, out)
+		}
+	}
+	if len(outputs) > 1 {
+		m.Outputs = outputs
+	}
+	m.ContentHash = contentHash(v.Pair())
+}
+
+// Upload the vectors embedded before an interrupt, giving up after flushTimeout.
+// Returns how many vectors made it into the index.
+func (b *Bot) flushInterrupted(vectors []Vector, dim int) int {
+	if len(vectors) == 0 {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if err := b.upsertToPinecone(ctx, vectors, dim); err != nil {
+		b.log.Warn("abandoned in-progress batch", "dim", dim, "vectors", len(vectors), "err", err)
+		return 0
+	}
+	b.log.Info("✅ Flushed in-progress batch", "dim", dim, "vectors", len(vectors))
+	return len(vectors)
+}
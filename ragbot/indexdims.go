@@ -0,0 +1,87 @@
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Dimensions Pinecone reports for the configured indexes, recorded from every
+// describe_index_stats call and kept for the life of the Bot
+type indexDimensions struct {
+	mu       sync.Mutex
+	reported map[int]int // configured dimension -> index's actual dimension
+}
+
+// Remember the dimension stats reports for the index configured under dim
+func (d *indexDimensions) record(dim int, stats *IndexStats) {
+	if stats.Dimension == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.reported == nil {
+		d.reported = map[int]int{}
+	}
+	d.reported[dim] = stats.Dimension
+}
+
+// The recorded dimension of the index configured under dim, if known
+func (d *indexDimensions) lookup(dim int) (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	actual, ok := d.reported[dim]
+	return actual, ok
+}
+
+// An index whose actual dimension differs from the one it's configured under
+type DimensionMismatchError struct {
+	Index      string
+	Configured int
+	Actual     int
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("index %s has dimension %d but is configured under %d: move it to indexes[%d] or point indexes[%d] at a %d-dimension index",
+		e.Index, e.Actual, e.Configured, e.Actual, e.Configured, e.Configured)
+}
+
+// Compare the dimension Pinecone reports for an index with the one it's configured under
+func (b *Bot) CheckDimension(dim int, stats *IndexStats) error {
+	b.indexDims.record(dim, stats)
+	return b.knownMismatch(dim)
+}
+
+// The mismatch already recorded for dim's index, without calling Pinecone
+func (b *Bot) knownMismatch(dim int) error {
+	actual, ok := b.indexDims.lookup(dim)
+	if !ok || actual == dim {
+		return nil
+	}
+	return &DimensionMismatchError{Index: b.cfg.Indexes[dim], Configured: dim, Actual: actual}
+}
+
+// Learn the actual dimension of every configured index, once per Bot, and warn about
+// any that differ from the config, which would otherwise only show up as a Pinecone
+// error on the first query or upload. Indexes that can't be reached are skipped; the
+// returned error joins the mismatches.
+func (b *Bot) CheckIndexDimensions(ctx context.Context) error {
+	if b.local != nil {
+		return nil
+	}
+	var mismatches []error
+	for _, dim := range b.cfg.Dimensions() {
+		if _, ok := b.indexDims.lookup(dim); !ok {
+			if _, err := b.describeIndexStats(ctx, dim); err != nil {
+				b.log.Debug("could not check index dimension", "index", b.cfg.Indexes[dim], "err", err)
+				continue
+			}
+		}
+		if err := b.knownMismatch(dim); err != nil {
+			b.log.Warn("⚠️ index dimension doesn't match config", "index", b.cfg.Indexes[dim], "configured", dim, "err", err)
+			mismatches = append(mismatches, err)
+		}
+	}
+	return errors.Join(mismatches...)
+}
@@ -0,0 +1,44 @@
+package ragbot
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Longest text in characters allowed by Config.MaxInputChars and MaxInputTokens, 0 for no limit
+func (c *Config) inputCharLimit() int {
+	limit := c.MaxInputChars
+	if c.MaxInputTokens > 0 && (limit == 0 || c.MaxInputTokens*charsPerToken < limit) {
+		limit = c.MaxInputTokens * charsPerToken
+	}
+	return limit
+}
+
+// First n characters of s, never splitting a multibyte character
+func truncateRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// Text to embed after applying the input length limit: truncated with a warning, or
+// rejected when Config.InputOverflow is "reject"
+func (b *Bot) limitInput(text string, dimension int) (string, error) {
+	limit := b.cfg.inputCharLimit()
+	if limit == 0 || len(text) <= limit {
+		return text, nil
+	}
+	chars := utf8.RuneCountInString(text)
+	if chars <= limit {
+		return text, nil
+	}
+	if b.cfg.InputOverflow == OverflowReject {
+		return "", fmt.Errorf("input is %d characters, over the embedding limit of %d", chars, limit)
+	}
+	b.log.Warn("input too long to embed, truncating", "chars", chars, "limit", limit, "dim", dimension)
+	return truncateRunes(text, limit), nil
+}
@@ -0,0 +1,65 @@
+package ragbot
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"pickup at 8", 6, "pickup"},
+		{"café ☕ now", 6, "café ☕"}, // multibyte runes stay whole
+		{"short", 10, "short"},
+		{"naïve", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateRunes(tt.in, tt.n); got != tt.want {
+			t.Errorf("truncateRunes(%q, %d) = %q, want %q", tt.in, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestLimitInput(t *testing.T) {
+	b := &Bot{cfg: &Config{MaxInputChars: 100, MaxInputTokens: 2}, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	// 2 tokens is about 8 characters, tighter than MaxInputChars
+	if got, err := b.limitInput("où est mon taxi", 384); err != nil || got != "où est m" {
+		t.Errorf("limitInput truncated to %q, %v; want \"où est m\"", got, err)
+	}
+	if got, err := b.limitInput("taxi", 384); err != nil || got != "taxi" {
+		t.Errorf("limitInput changed short input to %q, %v", got, err)
+	}
+
+	b.cfg.InputOverflow = OverflowReject
+	if _, err := b.limitInput("où est mon taxi", 384); err == nil {
+		t.Error("limitInput accepted an over-long input with input_overflow reject")
+	}
+}
+
+func TestMaxInputTokensOff(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey, cfg.PineconeAPIKey = "g", "p"
+	cfg.MaxInputTokens = -1
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.cfg.inputCharLimit(); got != 0 {
+		t.Errorf("max_input_tokens -1 left a limit of %d characters", got)
+	}
+
+	cfg.MaxInputTokens = 0
+	if b, _ = New(cfg); b.cfg.inputCharLimit() != 2048*charsPerToken {
+		t.Errorf("max_input_tokens 0 gave a limit of %d characters, want the model's 2048 tokens", b.cfg.inputCharLimit())
+	}
+
+	cfg.MaxInputTokens = -2
+	if _, err := New(cfg); err == nil {
+		t.Error("New accepted max_input_tokens -2")
+	}
+}
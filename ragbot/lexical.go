@@ -0,0 +1,53 @@
+package ragbot
+
+import "sort"
+
+// Share of the query keywords (tokens that aren't stopwords) found among the tokens of
+// input, from 0 to 1. Tokens must match exactly after lowercasing, so an identifier
+// such as a cab number only counts when it appears in full.
+func lexicalOverlap(keywords map[string]bool, input string) float64 {
+	if len(keywords) == 0 {
+		return 0
+	}
+	found := map[string]bool{}
+	for _, tok := range tokenize(input) {
+		if keywords[tok] {
+			found[tok] = true
+		}
+	}
+	return float64(len(found)) / float64(len(keywords))
+}
+
+// Distinct tokens of query that aren't stopwords
+func queryKeywords(query string) map[string]bool {
+	keywords := map[string]bool{}
+	for _, tok := range tokenize(query) {
+		if !stopwords[tok] {
+			keywords[tok] = true
+		}
+	}
+	return keywords
+}
+
+// Blend each match's semantic score, scaled to 0-1 over the matches as MMR does, with its
+// lexical overlap with query by Config.LexicalAlpha, and re-sort the matches, best first.
+// Scaling first gives alpha the same meaning on cosine and euclidean indexes whatever
+// the dimension weights. Like the recency boost this only changes the order; thresholds
+// still see the raw Score and ambiguity the SemanticScore. Nothing changes when the alpha
+// is 0 or the query has no keywords.
+func (c *Config) lexicalRescore(query string, matches []RankedMatch) {
+	alpha := c.LexicalAlpha
+	keywords := queryKeywords(query)
+	if alpha == 0 || len(keywords) == 0 {
+		return
+	}
+	semantic := normalizedScores(matches)
+	for i := range matches {
+		m := &matches[i]
+		m.LexicalOverlap = lexicalOverlap(keywords, m.Input)
+		m.WeightedScore = (1-alpha)*semantic[i] + alpha*m.LexicalOverlap
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].WeightedScore > matches[j].WeightedScore
+	})
+}
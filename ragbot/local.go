@@ -0,0 +1,162 @@
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+)
+
+// In-memory stand-in for Pinecone used by the "local" backend. Pairs are embedded with
+// Gemini per dimension on first search; without a Gemini key, or when Gemini can't be
+// reached, they are matched by plain token overlap instead.
+type localStore struct {
+	mu      sync.Mutex
+	pairs   []InputOutputPair
+	ids     map[string]int               // content hash -> index in pairs
+	vectors map[int]map[string][]float32 // dimension -> content hash -> embedding
+}
+
+// Returned by Pinecone-only operations such as Scan or Delete on the local backend
+var ErrLocalBackend = errors.New("not available with the local backend")
+
+func newLocalStore() *localStore {
+	return &localStore{ids: map[string]int{}, vectors: map[int]map[string][]float32{}}
+}
+
+// Add pairs, ignoring ones already stored
+func (s *localStore) add(pairs []InputOutputPair) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := 0
+	for _, pair := range pairs {
+		hash := contentHash(pair)
+		if _, ok := s.ids[hash]; ok {
+			continue
+		}
+		s.ids[hash] = len(s.pairs)
+		s.pairs = append(s.pairs, pair)
+		added++
+	}
+	return added
+}
+
+// Add a vector's pair with its embedding at the vector's dimension, e.g. from a snapshot
+func (s *localStore) addVector(v Vector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pair := v.Pair()
+	hash := contentHash(pair)
+	if _, ok := s.ids[hash]; !ok {
+		s.ids[hash] = len(s.pairs)
+		s.pairs = append(s.pairs, pair)
+	}
+	dim := len(v.Values)
+	if s.vectors[dim] == nil {
+		s.vectors[dim] = map[string][]float32{}
+	}
+	s.vectors[dim][hash] = v.Values
+}
+
+// Stored pairs with their embeddings at dim, embedding any that are missing. The Gemini
+// calls run without the lock, so searches and adds aren't held up behind them; a search
+// racing this one may embed the same pair, and whichever finishes last is kept.
+func (s *localStore) embedded(ctx context.Context, b *Bot, dim int) ([]InputOutputPair, [][]float32, error) {
+	s.mu.Lock()
+	pairs := append([]InputOutputPair{}, s.pairs...)
+	vectors := make([][]float32, len(pairs))
+	var missing []int
+	for i, pair := range pairs {
+		if v, ok := s.vectors[dim][contentHash(pair)]; ok {
+			vectors[i] = v
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, i := range missing {
+		v, err := b.getEmbedding(ctx, pairs[i].Input, dim, TaskRetrievalDocument)
+		if err != nil {
+			return nil, nil, err
+		}
+		vectors[i] = v
+
+		s.mu.Lock()
+		if s.vectors[dim] == nil {
+			s.vectors[dim] = map[string][]float32{}
+		}
+		s.vectors[dim][contentHash(pairs[i])] = v
+		s.mu.Unlock()
+	}
+	return pairs, vectors, nil
+}
+
+// Rank stored pairs against a query, best first
+func (s *localStore) search(ctx context.Context, b *Bot, query string, dim int, topK int) (*QueryResult, error) {
+	var ranked []neighbor
+	var pairs []InputOutputPair
+
+	if b.cfg.GeminiAPIKey != "" {
+		stored, vectors, err := s.embedded(ctx, b, dim)
+		if err == nil {
+			var q []float32
+			if q, err = b.getEmbedding(ctx, query, dim, TaskRetrievalQuery); err == nil {
+				pairs = stored
+				ranked, err = nearestNeighbors(q, vectors, topK)
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			b.log.Warn("local backend: embedding failed, falling back to text similarity", "err", err)
+		}
+	}
+
+	if ranked == nil {
+		s.mu.Lock()
+		pairs = append([]InputOutputPair{}, s.pairs...)
+		s.mu.Unlock()
+		all := make([]neighbor, len(pairs))
+		for i, pair := range pairs {
+			all[i] = neighbor{Index: i, Score: textSimilarity(query, pair.Input)}
+		}
+		ranked = topNeighbors(all, topK)
+	}
+
+	result := &QueryResult{}
+	for _, n := range ranked {
+		pair := pairs[n.Index]
+		result.Matches = append(result.Matches, Match{
+			ID:       contentID(pair, dim),
+			Score:    n.Score,
+			Metadata: Metadata{Input: pair.Input, Output: pair.Output, Outputs: pair.Outputs, Dimension: dim, PairID: n.Index, ContentHash: contentHash(pair), IDScheme: idScheme},
+		})
+	}
+	return result, nil
+}
+
+// Cosine similarity of the two texts' token counts, for matching without embeddings
+func textSimilarity(a, b string) float32 {
+	ta, tb := map[string]float64{}, map[string]float64{}
+	for _, tok := range tokenize(a) {
+		ta[tok]++
+	}
+	for _, tok := range tokenize(b) {
+		tb[tok]++
+	}
+
+	var dot, na, nb float64
+	for tok, n := range ta {
+		dot += n * tb[tok]
+		na += n * n
+	}
+	for _, n := range tb {
+		nb += n * n
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb)))
+}
@@ -0,0 +1,47 @@
+package ragbot
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalEmbeddingDoesNotHoldTheLock(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			once.Do(func() { close(started) })
+			<-release
+			w.Write(embeddingBody(384))
+		},
+		failPinecone(t),
+	)
+	b.local = newLocalStore()
+	b.local.add([]InputOutputPair{{Input: "cancel my ride", Output: "Done"}})
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := b.local.embedded(context.Background(), b, 384)
+		done <- err
+	}()
+	<-started
+
+	added := make(chan int, 1)
+	go func() { added <- b.local.add([]InputOutputPair{{Input: "where is my cab", Output: "On the way"}}) }()
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Error("add waited for an in-flight Gemini call")
+	}
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(b.local.vectors[384]) != 1 {
+		t.Errorf("%d embeddings published, want the one computed", len(b.local.vectors[384]))
+	}
+}
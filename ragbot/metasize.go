@@ -0,0 +1,86 @@
+package ragbot
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Size in bytes of a vector's metadata as sent to Pinecone
+func metadataSize(m Metadata) int {
+	data, _ := json.Marshal(m)
+	return len(data)
+}
+
+// Apply Config.MaxMetadataBytes to vectors about to be upserted. Oversized vectors lose
+// further responses of a one-to-many pair and then have their output cut until the
+// metadata fits, or are dropped with a warning when
+// MetadataOverflow is "skip" or the input alone is too big.
+func (b *Bot) guardMetadata(vectors []Vector, dimension int) []Vector {
+	limit := b.cfg.MaxMetadataBytes
+	kept := vectors[:0:0]
+	for _, v := range vectors {
+		size := metadataSize(v.Metadata)
+		if size <= limit {
+			kept = append(kept, v)
+			continue
+		}
+
+		if b.cfg.MetadataOverflow == OverflowSkip {
+			b.log.Warn("skipping vector with oversized metadata", "id", v.ID, "dim", dimension, "bytes", size, "limit", limit)
+			continue
+		}
+		m, ok := truncateOutput(v.Metadata, limit)
+		if !ok {
+			b.log.Warn("skipping vector whose metadata is too big even without its output", "id", v.ID, "dim", dimension, "bytes", size, "limit", limit)
+			continue
+		}
+		b.log.Warn("truncated output to fit the metadata limit", "id", v.ID, "dim", dimension,
+			"bytes", size, "limit", limit, "output_len", len(v.Metadata.Output), "kept", len(m.Output),
+			"outputs", len(v.Metadata.Outputs), "outputs_kept", len(m.Outputs))
+		v.Metadata = m
+		kept = append(kept, v)
+	}
+	return kept
+}
+
+// Metadata fitting in limit bytes, marked as truncated: further Outputs are dropped last
+// first, and if the first response alone is still too big, Outputs goes and Output is cut
+// to its longest fitting prefix on a character boundary. Outputs[0] stays equal to
+// Output. Reports false if it can't fit even with an empty output.
+func truncateOutput(m Metadata, limit int) (Metadata, bool) {
+	m.OutputTruncated = true
+	for len(m.Outputs) > 1 {
+		m.Outputs = m.Outputs[:len(m.Outputs)-1]
+		if len(m.Outputs) == 1 {
+			m.Outputs = nil
+		}
+		if metadataSize(m) <= limit {
+			return m, true
+		}
+	}
+
+	output := m.Output
+	fits := func(n int) bool {
+		m.Output = output[:n]
+		return metadataSize(m) <= limit
+	}
+	if !fits(0) {
+		return m, false
+	}
+
+	// JSON escaping makes some characters cost more than their bytes, so search for the cut
+	lo, hi := 0, len(output)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if fits(mid) {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	for lo > 0 && !utf8.RuneStart(output[lo]) {
+		lo--
+	}
+	m.Output = output[:lo]
+	return m, true
+}
@@ -0,0 +1,83 @@
+package ragbot
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestGuardMetadataTruncatesOutput(t *testing.T) {
+	b := &Bot{cfg: &Config{MaxMetadataBytes: 300, MetadataOverflow: OverflowTruncate}, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	long := strings.Repeat("é\"", 200) // multi-byte and escaped characters
+	vectors := []Vector{
+		{ID: "small", Metadata: Metadata{Input: "hi", Output: "hello"}},
+		{ID: "big", Metadata: Metadata{Input: "hi", Output: long, OutputLen: len(long)}},
+	}
+
+	got := b.guardMetadata(vectors, 3)
+	if len(got) != 2 {
+		t.Fatalf("got %d vectors, want 2", len(got))
+	}
+	if got[0].Metadata.OutputTruncated {
+		t.Error("small vector marked truncated")
+	}
+	m := got[1].Metadata
+	if !m.OutputTruncated || !strings.HasPrefix(long, m.Output) || m.Output == "" {
+		t.Errorf("big vector output = %q (truncated %v), want a non-empty prefix marked truncated", m.Output, m.OutputTruncated)
+	}
+	if !utf8.ValidString(m.Output) {
+		t.Error("output cut inside a character")
+	}
+	if size := metadataSize(m); size > 300 {
+		t.Errorf("metadata is %d bytes, want at most 300", size)
+	}
+	if vectors[1].Metadata.Output != long {
+		t.Error("caller's vector was modified")
+	}
+}
+
+func TestGuardMetadataSkip(t *testing.T) {
+	b := &Bot{cfg: &Config{MaxMetadataBytes: 300, MetadataOverflow: OverflowSkip}, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	vectors := []Vector{
+		{ID: "small", Metadata: Metadata{Input: "hi", Output: "hello"}},
+		{ID: "big", Metadata: Metadata{Input: "hi", Output: strings.Repeat("x", 1000)}},
+	}
+
+	got := b.guardMetadata(vectors, 3)
+	if len(got) != 1 || got[0].ID != "small" {
+		t.Errorf("got %v, want only the small vector", got)
+	}
+}
+
+func TestGuardMetadataTrimsOutputs(t *testing.T) {
+	b := &Bot{cfg: &Config{MaxMetadataBytes: 300, MetadataOverflow: OverflowTruncate}, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	first, second, third := "Tap Cancel on the trip.", strings.Repeat("b", 60), strings.Repeat("c", 200)
+	long := strings.Repeat("x", 400)
+	vectors := []Vector{
+		{ID: "drop-last", Metadata: Metadata{Input: "hi", Output: first, Outputs: []string{first, second, third}}},
+		{ID: "cut-first", Metadata: Metadata{Input: "hi", Output: long, Outputs: []string{long, second}}},
+	}
+
+	got := b.guardMetadata(vectors, 3)
+	if len(got) != 2 {
+		t.Fatalf("got %d vectors, want 2", len(got))
+	}
+	m := got[0].Metadata
+	if !m.OutputTruncated || m.Output != first || len(m.Outputs) != 2 || m.Outputs[0] != first || m.Outputs[1] != second {
+		t.Errorf("one-to-many vector kept output %q and outputs %q, want only the last response dropped", m.Output, m.Outputs)
+	}
+	m = got[1].Metadata
+	if !m.OutputTruncated || m.Outputs != nil || m.Output == "" || !strings.HasPrefix(long, m.Output) {
+		t.Errorf("vector with an oversized first response kept output %q and outputs %q, want a cut output alone", m.Output, m.Outputs)
+	}
+	for _, v := range got {
+		if size := metadataSize(v.Metadata); size > 300 {
+			t.Errorf("%s: metadata is %d bytes, want at most 300", v.ID, size)
+		}
+	}
+	if len(vectors[0].Metadata.Outputs) != 3 {
+		t.Error("caller's vector was modified")
+	}
+}
@@ -0,0 +1,99 @@
+package ragbot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Gemini bills embeddings per input token, roughly one token per four characters of English text
+const (
+	charsPerToken               = 4
+	geminiEmbeddingPricePerMTok = 0.15 // USD per million input tokens, gemini-embedding-001 paid tier
+)
+
+// Counters for the Gemini embedding calls made for one dimension
+type DimensionMetrics struct {
+	EmbeddingCalls int           `json:"embedding_calls"`
+	FailedCalls    int           `json:"failed_calls"`
+	CharsEmbedded  int           `json:"chars_embedded"`
+	Elapsed        time.Duration `json:"elapsed_ns"`
+}
+
+// Estimated input tokens billed for the embedded characters
+func (d DimensionMetrics) EstimatedTokens() int {
+	return (d.CharsEmbedded + charsPerToken - 1) / charsPerToken
+}
+
+// Estimated cost in USD for the embedded characters
+func (d DimensionMetrics) EstimatedCost() float64 {
+	return float64(d.EstimatedTokens()) / 1e6 * geminiEmbeddingPricePerMTok
+}
+
+// Embedding usage for a whole bulk run, broken down by dimension
+type RunMetrics struct {
+	Started    time.Time
+	Dimensions map[int]*DimensionMetrics
+}
+
+func newRunMetrics() *RunMetrics {
+	return &RunMetrics{Started: time.Now(), Dimensions: map[int]*DimensionMetrics{}}
+}
+
+// Counters for a dimension, created on first use
+func (m *RunMetrics) Dimension(dim int) *DimensionMetrics {
+	d, ok := m.Dimensions[dim]
+	if !ok {
+		d = &DimensionMetrics{}
+		m.Dimensions[dim] = d
+	}
+	return d
+}
+
+// Record one embedding call. Failed calls count as calls but not as embedded characters.
+func (m *RunMetrics) RecordEmbedding(dim int, chars int, err error) {
+	d := m.Dimension(dim)
+	d.EmbeddingCalls++
+	if err != nil {
+		d.FailedCalls++
+		return
+	}
+	d.CharsEmbedded += chars
+}
+
+// Sum of all dimensions
+func (m *RunMetrics) Totals() DimensionMetrics {
+	var t DimensionMetrics
+	for _, d := range m.Dimensions {
+		t.EmbeddingCalls += d.EmbeddingCalls
+		t.FailedCalls += d.FailedCalls
+		t.CharsEmbedded += d.CharsEmbedded
+		t.Elapsed += d.Elapsed
+	}
+	return t
+}
+
+// Dimensions with recorded metrics, in ascending order
+func (m *RunMetrics) SortedDimensions() []int {
+	dims := make([]int, 0, len(m.Dimensions))
+	for dim := range m.Dimensions {
+		dims = append(dims, dim)
+	}
+	sort.Ints(dims)
+	return dims
+}
+
+// Human-readable summary, one line per dimension plus a total
+func (m *RunMetrics) Summary() string {
+	var b strings.Builder
+	line := func(label string, d DimensionMetrics) {
+		fmt.Fprintf(&b, "%-6s calls=%d failed=%d chars=%d est_tokens=%d est_cost=$%.6f elapsed=%s\n",
+			label, d.EmbeddingCalls, d.FailedCalls, d.CharsEmbedded, d.EstimatedTokens(), d.EstimatedCost(), d.Elapsed.Round(time.Millisecond))
+	}
+	for _, dim := range m.SortedDimensions() {
+		line(fmt.Sprintf("%d", dim), *m.Dimensions[dim])
+	}
+	line("total", m.Totals())
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package ragbot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Position-based vector ID, "pair_<i>_dim_<dim>", used before IDs were derived from the input
+var positionalID = regexp.MustCompile(`^pair_[0-9]+_dim_[0-9]+$`)
+
+// Vectors per upsert request when moving vectors to their current ID
+const migrateBatchSize = 100
+
+// Outcome of MigrateIDs at one dimension
+type MigrateReport struct {
+	Scanned int
+	Moved   int // vectors written under their current ID from an old one
+	Deleted int // old IDs removed, including ones whose current ID already existed
+}
+
+// Move the vectors of a dimension stored under position-based "pair_<i>_dim_<dim>" IDs
+// to the ID their input now gets. When that ID already exists, e.g. after a fresh
+// upload, the old vector is just deleted; old vectors sharing an input become one
+// one-to-many vector. Vectors under any other ID are left alone. Stored embeddings
+// are reused, so no Gemini calls are made. With dryRun nothing is written.
+func (b *Bot) MigrateIDs(ctx context.Context, dim int, dryRun bool) (*MigrateReport, error) {
+	report := &MigrateReport{}
+	current := map[string]bool{}
+	moves := map[string]*Vector{}
+	var order, stale []string
+	err := b.Scan(ctx, dim, func(page []Vector) error {
+		for _, v := range page {
+			report.Scanned++
+			if v.Metadata.Input == "" {
+				continue
+			}
+			id := contentID(v.Pair(), dim)
+			if v.ID == id {
+				current[id] = true
+				continue
+			}
+			if !positionalID.MatchString(v.ID) {
+				continue
+			}
+			stale = append(stale, v.ID)
+			if moved, ok := moves[id]; ok {
+				mergeOutputs(moved, v.Pair())
+				continue
+			}
+			moved := v
+			moved.ID = id
+			moved.Metadata.IDScheme = idScheme
+			moved.Metadata.ContentHash = contentHash(v.Pair())
+			moves[id] = &moved
+			order = append(order, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	var upserts []Vector
+	for _, id := range order {
+		if !current[id] {
+			upserts = append(upserts, *moves[id])
+		}
+	}
+	report.Moved, report.Deleted = len(upserts), len(stale)
+	if dryRun || len(stale) == 0 {
+		return report, nil
+	}
+	for start := 0; start < len(upserts); start += migrateBatchSize {
+		if err := b.upsertToPinecone(ctx, upserts[start:min(start+migrateBatchSize, len(upserts))], dim); err != nil {
+			return report, fmt.Errorf("failed to write migrated vectors: %v", err)
+		}
+	}
+	if err := b.deleteVectors(ctx, stale, dim); err != nil {
+		return report, fmt.Errorf("failed to delete old vectors: %v", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,66 @@
+package ragbot
+
+import "math"
+
+// Default relevance/diversity balance for an MMRConfig that leaves Lambda unset
+const defaultMMRLambda = 0.7
+
+// Maximal Marginal Relevance selection of the "did you mean" alternatives, so near-identical
+// outputs don't crowd out different answers. Each pick maximizes
+// Lambda*relevance - (1-Lambda)*similarity to the outputs already picked, where relevance is
+// the weighted score scaled to 0-1 over the candidates and similarity is the word-count
+// cosine of the outputs (textSimilarity). The best match is always picked first.
+type MMRConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// 1 ranks by relevance only, lower values favour diversity down to 0 for diversity
+	// only; nil for the default 0.7
+	Lambda *float64 `yaml:"lambda"`
+}
+
+// Up to n of candidates, best first, chosen by MMR. candidates are ordered best first.
+func mmrSelect(candidates []RankedMatch, n int, lambda float64) []RankedMatch {
+	if n <= 0 || len(candidates) == 0 {
+		return nil
+	}
+	relevance := normalizedScores(candidates)
+
+	picked := []int{0}
+	used := map[int]bool{0: true}
+	for len(picked) < min(n, len(candidates)) {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			var similarity float64
+			for _, p := range picked {
+				similarity = max(similarity, float64(textSimilarity(candidates[i].Output, candidates[p].Output)))
+			}
+			if score := lambda*relevance[i] - (1-lambda)*similarity; score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked = append(picked, best)
+		used[best] = true
+	}
+
+	out := make([]RankedMatch, len(picked))
+	for i, p := range picked {
+		out[i] = candidates[p]
+	}
+	return out
+}
+
+// The "did you mean" alternatives from matches ordered best first: the first n distinct
+// pairs, or with MMR enabled n distinct pairs balancing relevance and diversity
+func (c *Config) alternatives(matches []RankedMatch, n int) []RankedMatch {
+	if !c.MMR.Enabled {
+		return distinctPairs(matches, n)
+	}
+	lambda := defaultMMRLambda
+	if c.MMR.Lambda != nil {
+		lambda = *c.MMR.Lambda
+	}
+	return mmrSelect(distinctPairs(matches, len(matches)), n, lambda)
+}
@@ -0,0 +1,38 @@
+package ragbot
+
+import "sync"
+
+// OutputSelection values
+const (
+	OutputRandom     = "random"
+	OutputRoundRobin = "round_robin"
+)
+
+// Choice of response for matches of one-to-many pairs. Round-robin turns are kept per
+// vector ID in memory, so they restart with the process and are not shared between servers.
+type outputPicker struct {
+	mu   sync.Mutex
+	next map[string]int
+}
+
+// The response to give for match: its only output, or one of its Outputs by
+// Config.OutputSelection
+func (b *Bot) pickOutput(match *RankedMatch) string {
+	if len(match.Outputs) < 2 {
+		return match.Output
+	}
+	if b.cfg.OutputSelection == OutputRoundRobin {
+		p := &b.outputs
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.next == nil {
+			p.next = map[string]int{}
+		}
+		i := p.next[match.ID] % len(match.Outputs)
+		p.next[match.ID] = i + 1
+		return match.Outputs[i]
+	}
+	b.jitter.mu.Lock()
+	defer b.jitter.mu.Unlock()
+	return match.Outputs[b.jitter.rng.Intn(len(match.Outputs))]
+}
@@ -0,0 +1,47 @@
+package ragbot
+
+import "testing"
+
+func TestAnswerRotatesOneToManyOutputs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey = "test-gemini-key"
+	cfg.PineconeAPIKey = "test-pinecone-key"
+	cfg.OutputSelection = OutputRoundRobin
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	outputs := []string{"Hello!", "Hi there!", "Hey, how can I help?"}
+	results := map[int]*QueryResult{384: {Matches: []Match{
+		{ID: "greet", Score: 0.9, Metadata: Metadata{Input: "hi", Output: outputs[0], Outputs: outputs}},
+	}}}
+
+	for i := 0; i < 4; i++ {
+		resp, _ := b.answerFrom("hi", nil, []int{384}, results, nil)
+		if want := outputs[i%len(outputs)]; resp.Answer != want {
+			t.Errorf("answer %d = %q, want %q", i, resp.Answer, want)
+		}
+	}
+
+	b.cfg.OutputSelection = OutputRandom
+	for i := 0; i < 10; i++ {
+		resp, _ := b.answerFrom("hi", nil, []int{384}, results, nil)
+		if resp.Answer != outputs[0] && resp.Answer != outputs[1] && resp.Answer != outputs[2] {
+			t.Fatalf("random answer %q is not one of the outputs", resp.Answer)
+		}
+	}
+}
+
+func TestOneToManyContentHash(t *testing.T) {
+	single := InputOutputPair{Input: "hi", Output: "Hello!"}
+	many := InputOutputPair{Input: "hi", Output: "Hello!", Outputs: []string{"Hello!", "Hi there!"}}
+	if contentHash(single) != contentHash(InputOutputPair{Input: "hi", Output: "Hello!", Outputs: []string{"Hello!"}}) {
+		t.Error("a single-element Outputs should keep the single-output hash")
+	}
+	if contentHash(single) == contentHash(many) {
+		t.Error("further outputs should change the content hash")
+	}
+	if contentID(single, 384) != contentID(many, 384) {
+		t.Error("the ID should depend on the input only")
+	}
+}
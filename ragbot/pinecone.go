@@ -0,0 +1,315 @@
+package ragbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Pinecone accepts at most 1000 IDs per delete request
+const deleteBatchSize = 1000
+
+// Returned for a dimension with no entry in Config.Indexes, e.g. a --dim flag naming
+// an index the config doesn't list
+var ErrNoIndex = errors.New("no index configured")
+
+// Data-plane base URL of the index holding vectors of a dimension
+func (b *Bot) indexURL(dimension int) (string, error) {
+	if _, ok := b.cfg.Indexes[dimension]; !ok {
+		return "", fmt.Errorf("%w for dimension %d (indexes: %v): add it under indexes in the config", ErrNoIndex, dimension, b.cfg.Dimensions())
+	}
+	if b.cfg.PineconeBaseURL != "" {
+		return b.cfg.PineconeBaseURL, nil
+	}
+	return b.cfg.IndexHost(dimension)
+}
+
+// POST a JSON payload to an index endpoint. Non-200 responses become errors carrying the body.
+func (b *Bot) pineconePost(ctx context.Context, dimension int, path string, payload interface{}, out interface{}) error {
+	base, err := b.indexURL(dimension)
+	if err != nil {
+		return err
+	}
+	return b.pineconePostURL(ctx, base+path, payload, out)
+}
+
+// pineconePost to a full endpoint URL, for indexes not keyed by dimension
+func (b *Bot) pineconePostURL(ctx context.Context, url string, payload interface{}, out interface{}) error {
+	if b.local != nil {
+		return ErrLocalBackend
+	}
+	data, _ := json.Marshal(payload)
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	req.Header.Add("Api-Key", b.cfg.PineconeAPIKey)
+	req.Header.Add("Content-Type", "application/json")
+	return b.pineconeDo(req, data, out)
+}
+
+// Send a Pinecone request tagged with a new request ID and decode the JSON response into
+// out unless it is nil. Failures are *pineconeError carrying the HTTP status.
+func (b *Bot) pineconeDo(req *http.Request, body []byte, out interface{}) error {
+	b.setHeaders(req, providerPinecone)
+	requestID := newRequestID()
+	req.Header.Set(requestIDHeader, requestID)
+	b.log.Debug("sending request", "request_id", requestID, "service", providerPinecone, "method", req.Method, "path", req.URL.Path)
+	b.logRequest(req, body)
+
+	fail := func(status int, err error) error {
+		b.log.Debug("request failed", "request_id", requestID, "service", providerPinecone, "status", status, "err", err)
+		return &pineconeError{RequestID: requestID, Status: status, Err: err}
+	}
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return fail(0, fmt.Errorf("request to Pinecone failed: %v", err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		var errBody bytes.Buffer
+		errBody.ReadFrom(res.Body)
+		return fail(res.StatusCode, fmt.Errorf("Pinecone error %d: %s", res.StatusCode, errBody.String()))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return fail(res.StatusCode, fmt.Errorf("failed to decode response: %v", err))
+		}
+	}
+	return nil
+}
+
+// Upload vectors to specific Pinecone index, retrying 5xx responses and connection
+// errors with jittered exponential backoff per Config.PineconeRetry. 429s are retried by the client's
+// rateLimitTransport; other 4xx responses are returned immediately.
+// Metadata over Config.MaxMetadataBytes is truncated or skipped first.
+// Failures are *UpsertError.
+func (b *Bot) upsertToPinecone(ctx context.Context, vectors []Vector, dimension int) error {
+	if b.local != nil {
+		return ErrLocalBackend
+	}
+	if err := b.knownMismatch(dimension); err != nil {
+		return err
+	}
+	base, err := b.indexURL(dimension)
+	if err != nil {
+		return err
+	}
+	indexName := b.cfg.Indexes[dimension]
+	vectors = b.guardMetadata(vectors, dimension)
+	if len(vectors) == 0 {
+		return nil
+	}
+	url := base + "/vectors/upsert"
+
+	payload := map[string]interface{}{
+		"vectors":   vectors,
+		"namespace": b.cfg.NamespaceFor(dimension),
+	}
+	data, _ := json.Marshal(payload)
+
+	for attempt := 0; ; attempt++ {
+		err := b.upsertOnce(ctx, url, data, dimension, len(vectors))
+		if err == nil {
+			b.log.Debug("pinecone upsert succeeded", "index", indexName, "dim", dimension, "vectors", len(vectors), "attempts", attempt+1)
+			return nil
+		}
+		if !err.Retryable() || ctx.Err() != nil {
+			return err
+		}
+		if !b.cfg.PineconeRetry.allows(attempt) {
+			err.Err = fmt.Errorf("%v (gave up after %d attempts)", err.Err, attempt+1)
+			return err
+		}
+
+		delay := b.cfg.PineconeRetry.delay(b.jitter, attempt)
+		b.log.Warn("pinecone upsert failed, retrying", "index", indexName, "dim", dimension,
+			"attempt", attempt+1, "delay", delay, "err", err)
+		if err := sleepCtx(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Send one upsert request. UpsertError.Retryable reports whether a failure is worth retrying.
+func (b *Bot) upsertOnce(ctx context.Context, url string, data []byte, dimension, count int) *UpsertError {
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	req.Header.Add("Api-Key", b.cfg.PineconeAPIKey)
+	req.Header.Add("Content-Type", "application/json")
+	if err := b.pineconeDo(req, data, nil); err != nil {
+		pe := err.(*pineconeError)
+		return &UpsertError{RequestID: pe.RequestID, Dimension: dimension, Vectors: count, Status: pe.Status, Err: pe.Err}
+	}
+	return nil
+}
+
+// Search for similar inputs in Pinecone, across Config.SearchNamespaces(), after
+// applying the configured query preprocessing. Matches with an ID in excludeIDs are
+// dropped client-side; topK+len(excludeIDs) matches are requested so that topK usually
+// remain, and any surplus is trimmed. Fewer than topK come back only when the index has
+// too few other vectors.
+func (b *Bot) searchSimilar(ctx context.Context, userInput string, dimension int, topK int, excludeIDs []string) (*QueryResult, error) {
+	namespaces := b.cfg.SearchNamespaces(dimension)
+	if len(excludeIDs) == 0 {
+		return b.searchNamespaces(ctx, b.preprocessQuery(userInput), dimension, topK, namespaces)
+	}
+	result, err := b.searchNamespaces(ctx, b.preprocessQuery(userInput), dimension, topK+len(excludeIDs), namespaces)
+	if err != nil {
+		return nil, err
+	}
+	return excludeMatches(result, excludeIDs, topK), nil
+}
+
+// result without matches whose ID is in excludeIDs, cut to topK
+func excludeMatches(result *QueryResult, excludeIDs []string, topK int) *QueryResult {
+	excluded := map[string]bool{}
+	for _, id := range excludeIDs {
+		excluded[id] = true
+	}
+	kept := &QueryResult{Matches: []Match{}}
+	for _, m := range result.Matches {
+		if !excluded[m.ID] && len(kept.Matches) < topK {
+			kept.Matches = append(kept.Matches, m)
+		}
+	}
+	return kept
+}
+
+// Search each namespace with one query embedding and merge the matches into a single
+// list ranked by score, each tagged with its namespace. Any failing namespace fails the search.
+func (b *Bot) searchNamespaces(ctx context.Context, userInput string, dimension int, topK int, namespaces []string) (*QueryResult, error) {
+	if b.local != nil {
+		return b.local.search(ctx, b, userInput, dimension, topK)
+	}
+
+	// First get embedding for user input
+	embedding, err := b.getEmbedding(ctx, userInput, dimension, TaskRetrievalQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding: %w", err)
+	}
+
+	var sparse *SparseVector
+	if b.cfg.Hybrid {
+		if sv := sparseEncode(userInput); len(sv.Indices) > 0 {
+			var scaledSparse SparseVector
+			embedding, scaledSparse = hybridScale(embedding, sv, b.cfg.HybridAlpha)
+			sparse = &scaledSparse
+		}
+	}
+
+	return b.queryNamespaces(ctx, embedding, sparse, dimension, topK, namespaces)
+}
+
+// Query each namespace with a ready-made vector and merge the matches as searchNamespaces does
+func (b *Bot) queryNamespaces(ctx context.Context, embedding []float32, sparse *SparseVector, dimension int, topK int, namespaces []string) (*QueryResult, error) {
+	if err := b.knownMismatch(dimension); err != nil {
+		return nil, err
+	}
+	merged := &QueryResult{}
+	for _, ns := range namespaces {
+		result, err := b.queryPinecone(ctx, embedding, sparse, dimension, topK, ns)
+		if err != nil {
+			if len(namespaces) > 1 {
+				return nil, fmt.Errorf("namespace %s: %v", ns, err)
+			}
+			return nil, err
+		}
+		merged.Matches = append(merged.Matches, result.Matches...)
+	}
+
+	if len(namespaces) > 1 {
+		sort.SliceStable(merged.Matches, func(i, j int) bool {
+			return b.cfg.BetterScore(dimension, merged.Matches[i].Score, merged.Matches[j].Score)
+		})
+		merged.Matches = merged.Matches[:min(topK, len(merged.Matches))]
+	}
+	return merged, nil
+}
+
+// Query one namespace of an index with a ready-made vector and optional sparse part
+func (b *Bot) queryPinecone(ctx context.Context, vector []float32, sparse *SparseVector, dimension int, topK int, namespace string) (*QueryResult, error) {
+	base, err := b.indexURL(dimension)
+	if err != nil {
+		return nil, err
+	}
+	return b.queryIndex(ctx, b.cfg.Indexes[dimension], base, vector, sparse, topK, namespace)
+}
+
+// queryPinecone against an index given by name and base URL
+func (b *Bot) queryIndex(ctx context.Context, indexName, baseURL string, vector []float32, sparse *SparseVector, topK int, namespace string) (*QueryResult, error) {
+	dimension := len(vector)
+	b.log.Debug("querying pinecone", "index", indexName, "namespace", namespace, "dim", dimension, "topK", topK)
+
+	payload := map[string]interface{}{
+		"vector":          vector,
+		"topK":            topK,
+		"includeMetadata": true,
+		"namespace":       namespace,
+	}
+	if b.cfg.IncludeValues {
+		payload["includeValues"] = true
+	}
+	if sparse != nil {
+		payload["sparseVector"] = sparse
+	}
+
+	var result QueryResult
+	start := time.Now()
+	err := b.pineconePostURL(ctx, baseURL+"/query", payload, &result)
+	b.telemetry.recordQuery(dimension, time.Since(start), err)
+	if err != nil {
+		if pe, ok := err.(*pineconeError); ok {
+			return nil, &QueryError{RequestID: pe.RequestID, Dimension: dimension, Namespace: namespace, Status: pe.Status, Err: pe.Err}
+		}
+		return nil, err
+	}
+
+	for i := range result.Matches {
+		result.Matches[i].Metadata.Namespace = namespace
+	}
+
+	b.log.Debug("pinecone query response", "index", indexName, "dim", dimension, "matches", len(result.Matches))
+	return &result, nil
+}
+
+// Vector counts reported by describe_index_stats
+type IndexStats struct {
+	Dimension        int                       `json:"dimension"`
+	Metric           string                    `json:"metric"` // empty on API versions that don't report it
+	TotalVectorCount int                       `json:"totalVectorCount"`
+	Namespaces       map[string]NamespaceStats `json:"namespaces"`
+}
+
+type NamespaceStats struct {
+	VectorCount int `json:"vectorCount"`
+}
+
+// Call describe_index_stats on the index for a dimension
+func (b *Bot) describeIndexStats(ctx context.Context, dimension int) (*IndexStats, error) {
+	var stats IndexStats
+	if err := b.pineconePost(ctx, dimension, "/describe_index_stats", map[string]interface{}{}, &stats); err != nil {
+		return nil, err
+	}
+	b.indexDims.record(dimension, &stats)
+	return &stats, nil
+}
+
+// Delete vectors by ID from the index for a dimension. Missing IDs are ignored by Pinecone.
+func (b *Bot) deleteVectors(ctx context.Context, ids []string, dimension int) error {
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := min(start+deleteBatchSize, len(ids))
+		payload := map[string]interface{}{
+			"ids":       ids[start:end],
+			"namespace": b.cfg.NamespaceFor(dimension),
+		}
+		if err := b.pineconePost(ctx, dimension, "/vectors/delete", payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// Bot whose Gemini and Pinecone endpoints are test servers, closed when the test ends
+func newTestBot(t *testing.T, gemini, pinecone http.HandlerFunc) *Bot {
+	t.Helper()
+	g := httptest.NewServer(gemini)
+	p := httptest.NewServer(pinecone)
+	t.Cleanup(func() {
+		g.Close()
+		p.Close()
+	})
+
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey = "test-gemini-key"
+	cfg.PineconeAPIKey = "test-pinecone-key"
+	cfg.GeminiBaseURL = g.URL
+	cfg.PineconeBaseURL = p.URL
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return b
+}
+
+func TestSearchSimilarReturnsPineconeError(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"code":7,"message":"Invalid API Key"}`))
+		},
+	)
+
+	result, err := b.searchSimilar(context.Background(), "cancel my ride", 384, 3, nil)
+	if err == nil {
+		t.Fatalf("expected an error, got result %+v", result)
+	}
+	if !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "Invalid API Key") {
+		t.Errorf("error should include the status and body, got %q", err)
+	}
+}
+
+func TestTypedErrorsCarryRequestID(t *testing.T) {
+	var geminiID, pineconeID string
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			geminiID = r.Header.Get(requestIDHeader)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"bad dimension"}`))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			pineconeID = r.Header.Get(requestIDHeader)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	)
+
+	_, err := b.searchSimilar(context.Background(), "cancel my ride", 384, 3, nil)
+	var ee *EmbedError
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected an *EmbedError, got %T %v", err, err)
+	}
+	if ee.Status != http.StatusBadRequest || ee.Dimension != 384 || ee.RequestID == "" || ee.RequestID != geminiID {
+		t.Errorf("EmbedError = %+v, want status 400, dimension 384 and request ID %q", ee, geminiID)
+	}
+
+	_, err = b.QueryVector(context.Background(), make([]float32, 384), 384, 3)
+	var qe *QueryError
+	if !errors.As(err, &qe) {
+		t.Fatalf("expected a *QueryError, got %T %v", err, err)
+	}
+	if qe.Status != http.StatusServiceUnavailable || qe.RequestID != pineconeID || !strings.Contains(err.Error(), pineconeID) {
+		t.Errorf("QueryError = %+v, want status 503 and request ID %q in the message", qe, pineconeID)
+	}
+}
+
+func TestAnswerVectorSkipsGemini(t *testing.T) {
+	var queried []float32
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected Gemini call to %s", r.URL.Path)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Vector []float32 `json:"vector"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			queried = body.Vector
+			w.Write([]byte(`{"matches":[{"id":"a","score":0.9,"metadata":{"input":"where is my cab","output":"5 minutes away"}}]}`))
+		},
+	)
+
+	vector := make([]float32, 384)
+	vector[0] = 1
+	resp, err := b.AnswerVector(context.Background(), vector, nil)
+	if err != nil {
+		t.Fatalf("AnswerVector: %v", err)
+	}
+	if len(queried) != 384 || resp.Best == nil || resp.Best.Output != "5 minutes away" || resp.Best.Dimension != 384 {
+		t.Errorf("queried %d values, best %+v; want the 384 index's match", len(queried), resp.Best)
+	}
+
+	if _, err := b.AnswerVector(context.Background(), make([]float32, 100), nil); err == nil {
+		t.Error("AnswerVector accepted a vector with no matching index")
+	}
+	if _, err := b.QueryVector(context.Background(), vector, 1024, 3); err == nil {
+		t.Error("QueryVector accepted a 384-value vector for the 1024 index")
+	}
+}
+
+func TestSearchExcludingDropsIDsAndWidensTopK(t *testing.T) {
+	var requestedTopK int
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				TopK int `json:"topK"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			requestedTopK = req.TopK
+			w.Write([]byte(`{"matches":[{"id":"a","score":0.9},{"id":"self","score":0.85},{"id":"b","score":0.8},{"id":"c","score":0.7}]}`))
+		},
+	)
+
+	result, err := b.SearchExcluding(context.Background(), "cancel my ride", 384, 2, []string{"self"})
+	if err != nil {
+		t.Fatalf("SearchExcluding: %v", err)
+	}
+	if requestedTopK != 3 {
+		t.Errorf("requested topK %d, want 3 to make up for the excluded ID", requestedTopK)
+	}
+	var ids []string
+	for _, m := range result.Matches {
+		ids = append(ids, m.ID)
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Errorf("matches %v, want [a b]", ids)
+	}
+}
+
+func TestIncludeValues(t *testing.T) {
+	var sent map[string]interface{}
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			sent = nil
+			json.NewDecoder(r.Body).Decode(&sent)
+			if sent["includeValues"] == true {
+				w.Write([]byte(`{"matches":[{"id":"a","score":0.9,"values":[0.1,0.2]}]}`))
+				return
+			}
+			w.Write([]byte(`{"matches":[{"id":"a","score":0.9}]}`))
+		},
+	)
+
+	result, err := b.Search(context.Background(), "cancel my ride", 384, 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if _, ok := sent["includeValues"]; ok || result.Matches[0].Values != nil {
+		t.Errorf("values requested without IncludeValues: payload %v", sent)
+	}
+
+	b.cfg.IncludeValues = true
+	result, err = b.Search(context.Background(), "cancel my ride", 384, 1)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got := result.Matches[0].Values; len(got) != 2 || got[1] != 0.2 {
+		t.Errorf("match values %v, want [0.1 0.2]", got)
+	}
+}
+
+func TestCheckIndexDimensionsReportsMismatchOnce(t *testing.T) {
+	var describes, queries int
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/describe_index_stats") {
+				describes++
+				w.Write([]byte(`{"dimension":768,"totalVectorCount":10}`))
+				return
+			}
+			queries++
+			w.Write([]byte(`{"matches":[]}`))
+		})
+
+	err := b.CheckIndexDimensions(context.Background())
+	var mismatch *DimensionMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Actual != 768 {
+		t.Fatalf("CheckIndexDimensions = %v, want a mismatch with dimension 768", err)
+	}
+	b.CheckIndexDimensions(context.Background())
+	if want := len(b.cfg.Dimensions()); describes != want {
+		t.Errorf("describe_index_stats called %d times, want %d (once per index)", describes, want)
+	}
+
+	if _, err := b.QueryVector(context.Background(), make([]float32, 384), 384, 5); !errors.As(err, &mismatch) {
+		t.Errorf("QueryVector = %v, want the mismatch", err)
+	}
+	if queries != 0 {
+		t.Errorf("%d queries reached a mismatched index", queries)
+	}
+}
+
+func TestUnconfiguredDimensionIsAConfigError(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected Gemini call")
+		},
+		failPinecone(t),
+	)
+	_, err := b.QueryVector(context.Background(), make([]float32, 768), 768, 5)
+	if !errors.Is(err, ErrNoIndex) || !strings.Contains(err.Error(), "dimension 768") {
+		t.Errorf("QueryVector at an unconfigured dimension = %v, want ErrNoIndex naming it", err)
+	}
+}
+
+func TestPreflightEmbedsAtSmallestConfiguredDimension(t *testing.T) {
+	var requested atomic.Int32
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				OutputDimensionality int `json:"outputDimensionality"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			requested.Store(int32(req.OutputDimensionality))
+			w.Write(embeddingBody(req.OutputDimensionality))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"dimension":768,"totalVectorCount":10}`))
+		})
+	b.cfg.Indexes = map[int]string{768: "idx-768", 1024: "idx-1024"}
+
+	b.Preflight(context.Background())
+	if got := requested.Load(); got != 768 {
+		t.Errorf("preflight embedded at dimension %d, want the smallest configured, 768", got)
+	}
+}
+
+func TestSearchNamespacesMergesByScore(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Namespace string `json:"namespace"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			switch req.Namespace {
+			case "faq":
+				w.Write([]byte(`{"matches":[{"id":"f1","score":0.9},{"id":"f2","score":0.5}]}`))
+			case "support":
+				w.Write([]byte(`{"matches":[{"id":"s1","score":0.7},{"id":"s2","score":0.4}]}`))
+			default:
+				t.Errorf("unexpected namespace %q", req.Namespace)
+			}
+		})
+
+	result, err := b.searchNamespaces(context.Background(), "cancel my ride", 384, 3, []string{"faq", "support"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range result.Matches {
+		got = append(got, m.ID+"@"+m.Metadata.Namespace)
+	}
+	if want := []string{"f1@faq", "s1@support", "f2@faq"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged matches %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package ragbot
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Transformation of a confident answer before Answer returns it, e.g. redacting PII or
+// localizing phrasing. match is the match the answer came from.
+type PostProcessor func(answer string, match *RankedMatch) (string, error)
+
+type namedPostProcessor struct {
+	name string
+	fn   PostProcessor
+}
+
+// Add a post-processor to the end of the chain. Post-processors run in the order they
+// were added, each on the previous one's result, after the stored output is rendered as
+// a template, and only on confident answers: FallbackAnswer and ClarifyAnswer are returned
+// as configured. If one fails the response falls back to FallbackAnswer rather than
+// return a partly processed answer, e.g. one a redaction step didn't finish with.
+// Register post-processors before answering; the chain isn't safe to change concurrently.
+func (b *Bot) AddPostProcessor(name string, p PostProcessor) {
+	b.postProcessors = append(b.postProcessors, namedPostProcessor{name: name, fn: p})
+}
+
+// Run the chain on a confident answer
+func (b *Bot) postProcess(answer string, match *RankedMatch) (string, error) {
+	for _, p := range b.postProcessors {
+		var err error
+		answer, err = p.fn(answer, match)
+		if err != nil {
+			return "", fmt.Errorf("post-processor %s: %v", p.name, err)
+		}
+	}
+	return answer, nil
+}
+
+// Built-in post-processor removing leading and trailing whitespace
+func TrimAnswer(answer string, _ *RankedMatch) (string, error) {
+	return strings.TrimSpace(answer), nil
+}
+
+// Built-in post-processor adding footer after the answer on a new line, e.g. a support
+// contact or a disclaimer
+func AppendFooter(footer string) PostProcessor {
+	return func(answer string, _ *RankedMatch) (string, error) {
+		if footer == "" {
+			return answer, nil
+		}
+		return answer + "\n" + footer, nil
+	}
+}
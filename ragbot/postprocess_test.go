@@ -0,0 +1,40 @@
+package ragbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPostProcessorsRunInOrder(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey = "test-gemini-key"
+	cfg.PineconeAPIKey = "test-pinecone-key"
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	results := map[int]*QueryResult{384: {Matches: []Match{
+		{ID: "a", Score: 0.9, Metadata: Metadata{Input: "call my driver", Output: "  Call {{.driver}} at 555-0100  "}},
+	}}}
+
+	b.AddPostProcessor("trim", TrimAnswer)
+	b.AddPostProcessor("redact", func(answer string, _ *RankedMatch) (string, error) {
+		return strings.ReplaceAll(answer, "555-0100", "[phone]"), nil
+	})
+	b.AddPostProcessor("footer", AppendFooter("Need more help? Reply AGENT."))
+
+	resp, _ := b.answerFrom("call my driver", map[string]string{"driver": "Asha"}, []int{384}, results, nil)
+	want := "Call Asha at [phone]\nNeed more help? Reply AGENT."
+	if !resp.Confident || resp.Answer != want {
+		t.Errorf("answer %q (confident %v), want %q", resp.Answer, resp.Confident, want)
+	}
+
+	b.AddPostProcessor("broken", func(string, *RankedMatch) (string, error) {
+		return "", errors.New("translation service down")
+	})
+	resp, _ = b.answerFrom("call my driver", nil, []int{384}, results, nil)
+	if resp.Confident || resp.Answer != cfg.FallbackAnswer {
+		t.Errorf("failed chain answered %q (confident %v), want the fallback", resp.Answer, resp.Confident)
+	}
+}
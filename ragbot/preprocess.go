@@ -0,0 +1,137 @@
+package ragbot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Query preprocessing applied by searches before embedding; every step is off by default
+type PreprocessConfig struct {
+	Lowercase    bool `yaml:"lowercase"`
+	Trim         bool `yaml:"trim"` // trim the ends and collapse runs of whitespace
+	SpellCorrect bool `yaml:"spell_correct"`
+
+	// Text file whose words the spell corrector knows, e.g. the training pairs file.
+	// Words are counted, so frequent words win ties between equally close candidates.
+	SpellDictionary string `yaml:"spell_dictionary"`
+}
+
+// Word frequencies used to correct misspelled query words to the closest known word
+type spellCorrector struct {
+	counts map[string]int
+}
+
+// Build a corrector from every letter/digit token in text
+func newSpellCorrector(text string) *spellCorrector {
+	counts := map[string]int{}
+	for _, tok := range tokenize(text) {
+		counts[tok]++
+	}
+	return &spellCorrector{counts: counts}
+}
+
+// Load a corrector from a dictionary file
+func loadSpellCorrector(path string) (*spellCorrector, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spell dictionary: %v", err)
+	}
+	return newSpellCorrector(string(data)), nil
+}
+
+// Closest known word to word, or word itself when it is known, too short to correct,
+// or nothing is close enough. Longer words tolerate two edits, short ones one.
+func (s *spellCorrector) correct(word string) string {
+	lower := strings.ToLower(word)
+	if _, ok := s.counts[lower]; ok || len([]rune(lower)) < 3 {
+		return word
+	}
+	for _, r := range lower {
+		if unicode.IsDigit(r) {
+			return word
+		}
+	}
+
+	maxDist := 1
+	if len([]rune(lower)) > 4 {
+		maxDist = 2
+	}
+	best, bestDist, bestCount := word, maxDist+1, 0
+	for known, count := range s.counts {
+		d := editDistance(lower, known)
+		if d < bestDist || (d == bestDist && (count > bestCount || (count == bestCount && known < best))) {
+			best, bestDist, bestCount = known, d, count
+		}
+	}
+	return best
+}
+
+// Correct each word of text, keeping everything between words as it is
+func (s *spellCorrector) correctText(text string) string {
+	var out, word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			out.WriteString(s.correct(word.String()))
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		out.WriteRune(r)
+	}
+	flush()
+	return out.String()
+}
+
+// Optimal string alignment distance: insertions, deletions, substitutions and
+// transpositions of adjacent letters each cost one
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// Sanitize a search query and apply the configured preprocessing steps
+func (b *Bot) preprocessQuery(query string) string {
+	query = b.sanitize(query)
+	p := b.cfg.Preprocess
+	out := query
+	if p.Trim {
+		out = strings.Join(strings.Fields(out), " ")
+	}
+	if p.Lowercase {
+		out = strings.ToLower(out)
+	}
+	if p.SpellCorrect && b.speller != nil {
+		out = b.speller.correctText(out)
+	}
+	if b.verbose && out != query {
+		b.log.Info("🔬 preprocessed query", "query", query, "preprocessed", out)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package ragbot
+
+import "testing"
+
+func TestSpellCorrector(t *testing.T) {
+	s := newSpellCorrector("book a ride. book a cab. cancel my ride. where is my driver? bike")
+
+	tests := []struct {
+		in, want string
+	}{
+		{"boook a rid", "book a ride"},
+		{"cancle my ride", "cancel my ride"},
+		{"wher is my drivr?", "where is my driver?"},
+		{"xylophone", "xylophone"}, // nothing close enough
+		{"ok", "ok"},               // too short to correct
+	}
+	for _, tt := range tests {
+		if got := s.correctText(tt.in); got != tt.want {
+			t.Errorf("correctText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"ride", "ride", 0},
+		{"rid", "ride", 1},
+		{"cancle", "cancel", 1}, // transposition
+		{"boook", "book", 1},
+		{"", "cab", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package ragbot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A longer Retry-After than this is returned to the caller instead of waited out
+const maxRetryAfter = time.Minute
+
+// RoundTripper that paces requests with a token bucket per host and retries 429
+// responses, waiting as long as Retry-After asks or a jittered exponential backoff
+// without it. Each 429 also halves the host's pace (see adaptiveLimiter), so callers
+// never deal with rate limiting themselves. Requests whose body can't be replayed
+// (no GetBody) are sent once. Each attempt is limited to the host's RetryPolicy.Timeout.
+type rateLimitTransport struct {
+	next   http.RoundTripper
+	jitter *jitterSource
+	log    func() *slog.Logger
+	sleep  func(req *http.Request, d time.Duration) error
+
+	mu         sync.Mutex
+	limiters   map[string]*adaptiveLimiter // host -> pace
+	defaultRPM int                         // pace for hosts not in limiters; 0 leaves them unlimited
+
+	policies      map[string]RetryPolicy // host -> timeout and retries
+	defaultPolicy RetryPolicy            // for hosts not in policies
+}
+
+func newRateLimitTransport(next http.RoundTripper, jitter *jitterSource, log func() *slog.Logger) *rateLimitTransport {
+	return &rateLimitTransport{
+		next:     next,
+		jitter:   jitter,
+		log:      log,
+		sleep:    func(req *http.Request, d time.Duration) error { return sleepCtx(req.Context(), d) },
+		limiters: map[string]*adaptiveLimiter{},
+
+		policies:      map[string]RetryPolicy{},
+		defaultPolicy: defaultPineconeRetry,
+	}
+}
+
+// Time out and retry requests to host per p
+func (t *rateLimitTransport) setPolicy(host string, p RetryPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policies[host] = p
+}
+
+func (t *rateLimitTransport) policyFor(host string) RetryPolicy {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.policies[host]; ok {
+		return p
+	}
+	return t.defaultPolicy
+}
+
+// Pace requests to host with l
+func (t *rateLimitTransport) setLimiter(host string, l *adaptiveLimiter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limiters[host] = l
+}
+
+// The host's limiter, created at defaultRPM on first use; nil when the host is unlimited
+func (t *rateLimitTransport) limiterFor(host string) *adaptiveLimiter {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.limiters[host]
+	if !ok && t.defaultRPM > 0 {
+		l = newAdaptiveLimiter(t.defaultRPM)
+		t.limiters[host] = l
+	}
+	return l
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	limiter := t.limiterFor(req.URL.Host)
+	policy := t.policyFor(req.URL.Host)
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		try := req
+		if attempt > 0 {
+			try = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				try.Body = body
+			}
+		}
+		res, err := t.roundTripWithin(try, policy.Timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusTooManyRequests {
+			if limiter != nil && res.StatusCode < 400 {
+				if delay, raised := limiter.Succeeded(); raised {
+					t.log().Info("⏩ Pace raised", "host", req.URL.Host, "delay", delay)
+				}
+			}
+			return res, nil
+		}
+
+		if limiter != nil {
+			limiter.Throttled()
+		}
+		delay, ok := retryAfter(res.Header.Get("Retry-After"), time.Now())
+		if !ok {
+			delay = policy.delay(t.jitter, attempt)
+		}
+		if !policy.allows(attempt) || delay > maxRetryAfter || (req.Body != nil && req.GetBody == nil) {
+			return res, nil
+		}
+		res.Body.Close()
+
+		t.log().Warn("rate limited, retrying", "host", req.URL.Host, "attempt", attempt+1, "delay", delay)
+		if err := t.sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Send req, failing it after timeout (0 for no limit). The deadline covers reading the
+// body, so it is released when the body is closed.
+func (t *rateLimitTransport) roundTripWithin(req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	res, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// Response body releasing its request's timeout when closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Wait requested by a Retry-After header, given in seconds or as an HTTP date
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,152 @@
+package ragbot
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Client through a rateLimitTransport that records its waits instead of sleeping
+func newTestRateLimitClient() (*http.Client, *[]time.Duration) {
+	var slept []time.Duration
+	t := newRateLimitTransport(http.DefaultTransport, newJitterSource(1), slog.Default)
+	t.sleep = func(req *http.Request, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return &http.Client{Transport: t}, &slept
+}
+
+func TestRateLimitTransportHonorsRetryAfter(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, slept := newTestRateLimitClient()
+	res, err := client.Post(srv.URL, "application/json", strings.NewReader(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200 after retrying", res.StatusCode)
+	}
+	if len(bodies) != 3 || bodies[2] != `{"text":"hi"}` {
+		t.Errorf("server saw %q, want the same body three times", bodies)
+	}
+	if len(*slept) != 2 || (*slept)[0] != 2*time.Second || (*slept)[1] != 2*time.Second {
+		t.Errorf("waited %v, want the 2s Retry-After twice", *slept)
+	}
+}
+
+func TestRateLimitTransportGivesUp(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("slow down"))
+	}))
+	defer srv.Close()
+
+	client, slept := newTestRateLimitClient()
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusTooManyRequests || string(body) != "slow down" {
+		t.Errorf("got %d %q, want the last 429 with its body", res.StatusCode, body)
+	}
+	p := defaultPineconeRetry
+	if requests != p.MaxRetries+1 {
+		t.Errorf("%d requests, want %d", requests, p.MaxRetries+1)
+	}
+	// No Retry-After: jittered exponential backoff
+	for i, d := range *slept {
+		want := backoffDelay(i, p.BaseBackoff, p.MaxBackoff)
+		if d < want/2 || d > want {
+			t.Errorf("wait %d = %v, want between %v and %v", i, d, want/2, want)
+		}
+	}
+}
+
+func TestRateLimitTransportReturnsLongRetryAfter(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client, _ := newTestRateLimitClient()
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if requests != 1 || res.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("%d requests ending in %d, want one 429 without waiting an hour", requests, res.StatusCode)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"Fri, 10 May 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Fri, 10 May 2024 11:00:00 GMT", 0, true}, // already past
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.header, now)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGeminiRetriesThroughTransport(t *testing.T) {
+	calls := 0
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write(embeddingBody(384))
+		},
+		failPinecone(t),
+	)
+
+	if _, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", 384, TaskRetrievalQuery); err != nil {
+		t.Fatalf("getEmbedding: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("%d Gemini calls, want 2", calls)
+	}
+}
@@ -0,0 +1,44 @@
+package ragbot
+
+import (
+	"math"
+	"time"
+)
+
+// Defaults for a RecencyBoostConfig that leaves Weight or HalfLifeDays unset
+const (
+	defaultRecencyWeight   = 0.02
+	defaultRecencyHalfLife = 30
+)
+
+// Small rerank bonus for recently added vectors, from their created_at metadata, so an
+// updated answer outranks a stale near-duplicate still in the index. The boost only
+// changes the order of matches (WeightedScore); thresholds still see the raw Score.
+type RecencyBoostConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Bonus added to the weighted score of a vector created just now, default 0.02.
+	// Keep it around the score gap between duplicates so it can't lift unrelated matches.
+	Weight float64 `yaml:"weight"`
+
+	// Days after which the bonus has halved, default 30
+	HalfLifeDays float64 `yaml:"half_life_days"`
+}
+
+// Bonus for a vector created at createdAt (Unix seconds): Weight halved every HalfLifeDays.
+// 0 when the boost is off or the vector has no created_at.
+func (c *Config) recencyBoost(createdAt int64, now time.Time) float64 {
+	r := c.RecencyBoost
+	if !r.Enabled || createdAt == 0 {
+		return 0
+	}
+	weight, halfLife := r.Weight, r.HalfLifeDays
+	if weight == 0 {
+		weight = defaultRecencyWeight
+	}
+	if halfLife == 0 {
+		halfLife = defaultRecencyHalfLife
+	}
+	ageDays := max(now.Sub(time.Unix(createdAt, 0)).Hours()/24, 0)
+	return weight * math.Pow(0.5, ageDays/halfLife)
+}
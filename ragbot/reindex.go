@@ -0,0 +1,88 @@
+package ragbot
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Outcome of Reindex
+type ReindexReport struct {
+	Metrics  *RunMetrics
+	Scanned  int // vectors read from the source index
+	Uploaded int // vectors written to the target index
+	Skipped  int // vectors without an input to embed, or whose embedding failed
+}
+
+// Populate the target dimension's index from the vectors already in the source index:
+// each stored input is re-embedded at the target dimension and upserted with its original
+// metadata, so a new dimension can be added without the original source file.
+func (b *Bot) Reindex(ctx context.Context, from, to int) (*ReindexReport, error) {
+	if _, ok := b.cfg.Indexes[from]; !ok {
+		return nil, fmt.Errorf("no index configured for source dimension %d", from)
+	}
+	if _, ok := b.cfg.Indexes[to]; !ok {
+		return nil, fmt.Errorf("no index configured for target dimension %d", to)
+	}
+	if from == to {
+		return nil, fmt.Errorf("source and target dimension are both %d", from)
+	}
+
+	report := &ReindexReport{Metrics: newRunMetrics()}
+	start := time.Now()
+	defer func() { report.Metrics.Dimension(to).Elapsed = time.Since(start) }()
+
+	b.log.Info("🔁 Reindexing", "from", b.cfg.Indexes[from], "to", b.cfg.Indexes[to])
+
+	err := b.Scan(ctx, from, func(page []Vector) error {
+		report.Scanned += len(page)
+
+		var vectors []Vector
+		for _, src := range page {
+			input := src.Pair().Input
+			if input == "" {
+				b.log.Warn("skipping vector without input metadata", "id", src.ID)
+				report.Skipped++
+				continue
+			}
+
+			embedding, err := b.getEmbedding(ctx, input, to, TaskRetrievalDocument)
+			report.Metrics.RecordEmbedding(to, len(input), err)
+			if b.progress != nil {
+				b.progress(to)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				b.log.Error("failed to get embedding", "id", src.ID, "dim", to, "err", err)
+				report.Skipped++
+				continue
+			}
+
+			vectors = append(vectors, reindexedVector(src, to, embedding))
+		}
+
+		if len(vectors) == 0 {
+			return nil
+		}
+		if err := b.upsertToPinecone(ctx, vectors, to); err != nil {
+			return fmt.Errorf("failed to upload vectors: %v", err)
+		}
+		report.Uploaded += len(vectors)
+		b.log.Info("✅ Reindexed page", "dim", to, "vectors", len(vectors), "total", report.Uploaded)
+		return nil
+	})
+
+	return report, err
+}
+
+// Copy of a stored vector re-embedded at another dimension. All metadata is kept
+// except Dimension and IDScheme, and the ID is rebuilt for the target dimension.
+func reindexedVector(src Vector, dim int, embedding []float32) Vector {
+	metadata := src.Metadata
+	metadata.Dimension = dim
+	metadata.IDScheme = idScheme
+
+	return Vector{ID: contentID(src.Pair(), dim), Values: embedding, SparseValues: src.SparseValues, Metadata: metadata}
+}
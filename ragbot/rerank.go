@@ -0,0 +1,135 @@
+package ragbot
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A match from one dimension's index, placed in the cross-dimension ranking
+type RankedMatch struct {
+	ID             string
+	PairID         int // position of the pair in its source file, AddedPairID for Add
+	Dimension      int
+	Score          float32 // raw score from Pinecone, a distance on euclidean indexes
+	WeightedScore  float64 // SemanticScore, or with Config.LexicalAlpha set its 0-1 normalized form blended with LexicalOverlap
+	SemanticScore  float64 // Score scaled by the dimension's weight, negated distance on euclidean indexes so higher is always better, plus RecencyBoost
+	RecencyBoost   float64 // bonus for a newer vector included in WeightedScore, see Config.RecencyBoost
+	LexicalOverlap float64 // share of the query's keywords found in Input, set only when Config.LexicalAlpha is
+	Confidence     float64 // Score mapped to 0-100 by Config.Calibration
+	Input          string
+	Output         string
+	Outputs        []string // every response of a one-to-many pair, nil for a single one
+	Namespace      string
+	Values         []float32 // stored vector, nil unless Config.IncludeValues is set
+}
+
+// Merge per-dimension results into one list ordered by weighted score, best first.
+// With the default weights of 1.0 this is a plain comparison of raw scores.
+// Euclidean distances are divided by the weight and negated so they rank the same way;
+// scores from indexes with different metrics are not really comparable.
+func rerankAcrossDimensions(cfg *Config, results map[int]*QueryResult) []RankedMatch {
+	dims := make([]int, 0, len(results))
+	for dim := range results {
+		dims = append(dims, dim)
+	}
+	sort.Ints(dims)
+
+	now := time.Now()
+	var ranked []RankedMatch
+	for _, dim := range dims {
+		weight := cfg.DimensionWeight(dim)
+		euclidean := cfg.Metric(dim) == MetricEuclidean
+		for _, m := range results[dim].Matches {
+			weighted := float64(m.Score) * weight
+			if euclidean {
+				weighted = math.Inf(-1)
+				if weight > 0 {
+					weighted = -float64(m.Score) / weight
+				}
+			}
+			boost := cfg.recencyBoost(m.Metadata.CreatedAt, now)
+			ranked = append(ranked, RankedMatch{
+				ID:            m.ID,
+				PairID:        m.Metadata.PairID,
+				Dimension:     dim,
+				Score:         m.Score,
+				WeightedScore: weighted + boost,
+				SemanticScore: weighted + boost,
+				RecencyBoost:  boost,
+				Confidence:    cfg.Confidence(m.Score),
+				Input:         m.Metadata.Input,
+				Output:        m.Metadata.Output,
+				Outputs:       m.Metadata.Outputs,
+				Namespace:     m.Metadata.Namespace,
+				Values:        m.Values,
+			})
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].WeightedScore > ranked[j].WeightedScore
+	})
+	return ranked
+}
+
+// WeightedScore of each match scaled to 0-1 over matches, from the lowest to the highest;
+// all 1 when they are equal, and 0 for a match on an index it has zero weight in
+func normalizedScores(matches []RankedMatch) []float64 {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, m := range matches {
+		if !math.IsInf(m.WeightedScore, -1) {
+			lo, hi = min(lo, m.WeightedScore), max(hi, m.WeightedScore)
+		}
+	}
+	scores := make([]float64, len(matches))
+	for i, m := range matches {
+		switch {
+		case math.IsInf(m.WeightedScore, -1):
+			scores[i] = 0
+		case hi <= lo:
+			scores[i] = 1
+		default:
+			scores[i] = (m.WeightedScore - lo) / (hi - lo)
+		}
+	}
+	return scores
+}
+
+// Search each dimension, keeping the results and errors per dimension. Up to
+// Config.QueryConcurrency dimensions are searched at once, all of them by default;
+// a failing dimension doesn't stop the others.
+func (b *Bot) searchDimensions(ctx context.Context, userInput string, dimensions []int, topK int) (map[int]*QueryResult, map[int]error) {
+	found := make([]*QueryResult, len(dimensions))
+	failed := make([]error, len(dimensions))
+	limit := b.cfg.QueryConcurrency
+	if limit <= 0 || limit > len(dimensions) {
+		limit = len(dimensions)
+	}
+	sem := make(chan struct{}, max(limit, 1))
+
+	var wg sync.WaitGroup
+	for i, dim := range dimensions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			found[i], failed[i] = b.searchSimilar(ctx, userInput, dim, topK, nil)
+		}()
+	}
+	wg.Wait()
+
+	results := map[int]*QueryResult{}
+	errs := map[int]error{}
+	for i, dim := range dimensions {
+		if failed[i] != nil {
+			errs[dim] = failed[i]
+			continue
+		}
+		results[dim] = found[i]
+	}
+	return results, errs
+}
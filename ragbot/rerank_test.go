@@ -0,0 +1,258 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRerankEuclideanLowerIsBetter(t *testing.T) {
+	cfg := &Config{IndexMetrics: map[int]string{384: MetricEuclidean}, ScoreThreshold: 0.5}
+	results := map[int]*QueryResult{
+		384: {Matches: []Match{
+			{ID: "near", Score: 0.2, Metadata: Metadata{Output: "near"}},
+			{ID: "far", Score: 0.9, Metadata: Metadata{Output: "far"}},
+		}},
+	}
+
+	ranked := rerankAcrossDimensions(cfg, results)
+	if ranked[0].ID != "near" {
+		t.Errorf("best = %s, want the smallest distance", ranked[0].ID)
+	}
+	if !cfg.MeetsThreshold(384, 0.2) || cfg.MeetsThreshold(384, 0.9) {
+		t.Error("euclidean threshold should accept distances up to 0.5 only")
+	}
+	if !cfg.MeetsThreshold(1024, 0.9) || cfg.MeetsThreshold(1024, 0.2) {
+		t.Error("cosine threshold should accept scores from 0.5 up only")
+	}
+}
+
+func TestRecencyBoostFavorsNewerDuplicate(t *testing.T) {
+	now := time.Now()
+	day := int64(24 * 60 * 60)
+	cfg := &Config{RecencyBoost: RecencyBoostConfig{Enabled: true, Weight: 0.02, HalfLifeDays: 30}}
+	results := map[int]*QueryResult{
+		1024: {Matches: []Match{
+			{ID: "stale", Score: 0.91, Metadata: Metadata{Output: "Refunds take 7 days.", CreatedAt: now.Unix() - 365*day}},
+			{ID: "updated", Score: 0.90, Metadata: Metadata{Output: "Refunds take 3 days.", CreatedAt: now.Unix() - day}},
+			{ID: "unrelated", Score: 0.60, Metadata: Metadata{Output: "Your ride is booked.", CreatedAt: now.Unix()}},
+		}},
+	}
+
+	ranked := rerankAcrossDimensions(cfg, results)
+	if ranked[0].ID != "updated" || ranked[2].ID != "unrelated" {
+		t.Errorf("ranking %s, %s, %s; want the updated answer first and the unrelated one last", ranked[0].ID, ranked[1].ID, ranked[2].ID)
+	}
+
+	if got := cfg.recencyBoost(now.Unix()-30*day, now); got < 0.0099 || got > 0.0101 {
+		t.Errorf("boost after one half-life = %v, want 0.01", got)
+	}
+	if got := cfg.recencyBoost(0, now); got != 0 {
+		t.Errorf("boost without created_at = %v, want 0", got)
+	}
+
+	cfg.RecencyBoost.Enabled = false
+	if ranked := rerankAcrossDimensions(cfg, results); ranked[0].ID != "stale" {
+		t.Errorf("with the boost off, best = %s, want the highest raw score", ranked[0].ID)
+	}
+}
+
+func TestMMRPrefersDiverseAlternatives(t *testing.T) {
+	matches := []RankedMatch{
+		{ID: "a", PairID: 1, WeightedScore: 0.95, Output: "Your ride is booked for tomorrow morning."},
+		{ID: "b", PairID: 2, WeightedScore: 0.94, Output: "Your ride is booked for tomorrow morning!"},
+		{ID: "c", PairID: 3, WeightedScore: 0.93, Output: "Your ride is booked for tomorrow, see you."},
+		{ID: "d", PairID: 4, WeightedScore: 0.85, Output: "Refunds take 3 days."},
+	}
+
+	plain := (&Config{}).alternatives(matches, 2)
+	if plain[1].ID != "b" {
+		t.Errorf("without MMR second alternative = %s, want b", plain[1].ID)
+	}
+
+	lambda := 0.5
+	cfg := &Config{MMR: MMRConfig{Enabled: true, Lambda: &lambda}}
+	got := cfg.alternatives(matches, 2)
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "d" {
+		t.Errorf("MMR alternatives = %v, want a then the diverse d", got)
+	}
+
+	lambda = 1
+	if got := cfg.alternatives(matches, 2); got[1].ID != "b" {
+		t.Errorf("lambda 1 second alternative = %s, want b by relevance", got[1].ID)
+	}
+
+	// An explicit lambda 0 (pure diversity) must not fall back to the 0.7 default,
+	// which still ranks the near-duplicate b second on relevance
+	cfg.MMR.Lambda = nil
+	if got := cfg.alternatives(matches, 2); got[1].ID != "b" {
+		t.Errorf("default lambda second alternative = %s, want b", got[1].ID)
+	}
+	lambda = 0
+	cfg.MMR.Lambda = &lambda
+	if got := cfg.alternatives(matches, 2); got[1].ID != "d" {
+		t.Errorf("lambda 0 second alternative = %s, want the diverse d", got[1].ID)
+	}
+}
+
+func TestAlternativesDedupeByPair(t *testing.T) {
+	matches := []RankedMatch{
+		{ID: "cancel-1024", PairID: 3, Dimension: 1024, Output: "Your ride is cancelled."},
+		{ID: "cancel-384", PairID: 3, Dimension: 384, Output: "Your ride is cancelled."},
+		{ID: "abort", PairID: 7, Output: "Your ride is cancelled."},
+		{ID: "added", PairID: AddedPairID, Input: "stop my ride", Output: "Your ride is cancelled."},
+		{ID: "added-384", PairID: AddedPairID, Input: "stop my ride", Output: "Your ride is cancelled."},
+	}
+	var got []string
+	for _, m := range (&Config{}).alternatives(matches, 5) {
+		got = append(got, m.ID)
+	}
+	if want := []string{"cancel-1024", "abort", "added"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("alternatives %v, want one per pair %v", got, want)
+	}
+}
+
+func TestLexicalRescoreFavorsExactIdentifiers(t *testing.T) {
+	cfg := DefaultConfig()
+	matches := func() []RankedMatch {
+		return []RankedMatch{
+			{ID: "other-cab", Input: "Where is my cab KA05XY9999", WeightedScore: 0.90},
+			{ID: "this-cab", Input: "Where is my cab KA01AB1234", WeightedScore: 0.86},
+			{ID: "refund", Input: "How do refunds work", WeightedScore: 0.50},
+		}
+	}
+	query := "Where is my cab (KA01AB1234)"
+
+	semantic := matches()
+	cfg.lexicalRescore(query, semantic)
+	if semantic[0].ID != "other-cab" || semantic[0].WeightedScore != 0.90 {
+		t.Errorf("with lexical_alpha 0 the ranking changed: %+v", semantic)
+	}
+
+	cfg.LexicalAlpha = 0.5
+	blended := matches()
+	cfg.lexicalRescore(query, blended)
+	if blended[0].ID != "this-cab" || blended[0].LexicalOverlap != 1 || blended[1].LexicalOverlap != 0.5 {
+		t.Errorf("with lexical_alpha 0.5: %+v, want this-cab first with full overlap", blended)
+	}
+}
+
+func TestLexicalRescoreOnEuclideanDistances(t *testing.T) {
+	cfg := &Config{IndexMetrics: map[int]string{384: MetricEuclidean}, LexicalAlpha: 0.5}
+	// Unnormalized vectors give distances far outside 0-1, which would drown the overlap
+	results := map[int]*QueryResult{384: {Matches: []Match{
+		{ID: "other-cab", Score: 30, Metadata: Metadata{Input: "Where is my cab KA05XY9999"}},
+		{ID: "this-cab", Score: 31, Metadata: Metadata{Input: "Where is my cab KA01AB1234"}},
+		{ID: "refund", Score: 60, Metadata: Metadata{Input: "How do refunds work"}},
+	}}}
+
+	ranked := rerankAcrossDimensions(cfg, results)
+	cfg.lexicalRescore("Where is my cab (KA01AB1234)", ranked)
+	if ranked[0].ID != "this-cab" || ranked[2].ID != "refund" {
+		t.Errorf("ranking %s, %s, %s; want this-cab first and the far refund last", ranked[0].ID, ranked[1].ID, ranked[2].ID)
+	}
+	for _, m := range ranked {
+		if m.WeightedScore < 0 || m.WeightedScore > 1 {
+			t.Errorf("%s: blended score %v, want 0-1", m.ID, m.WeightedScore)
+		}
+	}
+	if ranked[0].SemanticScore != -31 {
+		t.Errorf("semantic score %v, want the negated distance -31 kept", ranked[0].SemanticScore)
+	}
+}
+
+func TestAmbiguityUsesScoresBeforeLexicalBlend(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.GeminiAPIKey, cfg.PineconeAPIKey = "g", "p"
+	cfg.LexicalAlpha = 0.5
+	cfg.AmbiguityEpsilon = 0.05
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := map[int]*QueryResult{384: {Matches: []Match{
+		{ID: "book", Score: 0.95, Metadata: Metadata{Input: "book a ride", Output: "Booked"}},
+		{ID: "cancel", Score: 0.73, Metadata: Metadata{Input: "cancel a ride", Output: "Cancelled"}},
+		{ID: "refund", Score: 0.50, Metadata: Metadata{Input: "refund a ride", Output: "Refunded"}},
+	}}}
+
+	// Blended, cancel edges out book by 0.756 to 0.75, but 0.95 and 0.73 are far apart
+	resp, _ := b.answerFrom("cancel ride", nil, []int{384}, results, nil)
+	if resp.Ambiguous || resp.Best.ID != "cancel" {
+		t.Errorf("best %s, ambiguous %v; want cancel, with the semantic gap too wide to be ambiguous", resp.Best.ID, resp.Ambiguous)
+	}
+
+	// Blended, book leads by 0.28, but 0.95 and 0.93 are within epsilon
+	results[384].Matches[1].Score = 0.93
+	resp, _ = b.answerFrom("book ride", nil, []int{384}, results, nil)
+	if !resp.Ambiguous || resp.Best.ID != "book" {
+		t.Errorf("best %s, ambiguous %v; want book flagged ambiguous", resp.Best.ID, resp.Ambiguous)
+	}
+}
+
+func TestSearchDimensionsCapsConcurrency(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				OutputDimensionality int `json:"outputDimensionality"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			w.Write(embeddingBody(req.OutputDimensionality))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			<-release
+
+			var req struct {
+				Vector []float32 `json:"vector"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			if len(req.Vector) == 512 {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"message":"no access"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"matches":[{"id":"match-%d","score":0.9}]}`, len(req.Vector))
+		})
+	b.cfg.Indexes = map[int]string{384: "idx-384", 512: "idx-512", 768: "idx-768", 1024: "idx-1024"}
+	b.cfg.QueryConcurrency = 2
+
+	done := make(chan struct{})
+	var results map[int]*QueryResult
+	var errs map[int]error
+	go func() {
+		results, errs = b.searchDimensions(context.Background(), "where is my cab", []int{384, 512, 768, 1024}, 3)
+		close(done)
+	}()
+	time.Sleep(200 * time.Millisecond) // let every dimension that may start reach Pinecone
+	if got := peak.Load(); got != 2 {
+		t.Errorf("%d queries in flight, want QueryConcurrency 2", got)
+	}
+	close(release)
+	<-done
+
+	if peak.Load() != 2 {
+		t.Errorf("peak of %d queries in flight, want 2", peak.Load())
+	}
+	for _, dim := range []int{384, 768, 1024} {
+		if r := results[dim]; r == nil || len(r.Matches) != 1 || r.Matches[0].ID != fmt.Sprintf("match-%d", dim) {
+			t.Errorf("dimension %d: result %+v, want its own match", dim, r)
+		}
+	}
+	if len(results) != 3 || len(errs) != 1 || errs[512] == nil {
+		t.Errorf("results for %d dimensions and errors %v, want 3 and only 512 failing", len(results), errs)
+	}
+}
@@ -0,0 +1,107 @@
+package ragbot
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Timeout and retries for the requests to one provider. Every request is retried on 429
+// responses; embeddings and upserts also on 5xx responses and failed connections.
+type RetryPolicy struct {
+	Timeout     time.Duration `yaml:"timeout"`      // limit for one attempt, excluding rate-limit pacing
+	MaxRetries  int           `yaml:"max_retries"`  // retries after the first attempt; negative for none
+	BaseBackoff time.Duration `yaml:"base_backoff"` // wait before the first retry, doubled for each further one
+	MaxBackoff  time.Duration `yaml:"max_backoff"`  // cap on the doubled wait
+}
+
+// Default policies: patient with Gemini, whose embeddings are slow but worth waiting for,
+// quicker to give up on Pinecone, whose queries are fast when healthy
+var (
+	defaultGeminiRetry   = RetryPolicy{Timeout: 30 * time.Second, MaxRetries: 3, BaseBackoff: time.Second, MaxBackoff: 30 * time.Second}
+	defaultPineconeRetry = RetryPolicy{Timeout: 15 * time.Second, MaxRetries: 4, BaseBackoff: 500 * time.Millisecond, MaxBackoff: 8 * time.Second}
+)
+
+// p with unset (zero) fields taken from def
+func (p RetryPolicy) withDefaults(def RetryPolicy) RetryPolicy {
+	if p.Timeout == 0 {
+		p.Timeout = def.Timeout
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = def.MaxRetries
+	}
+	if p.BaseBackoff == 0 {
+		p.BaseBackoff = def.BaseBackoff
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = def.MaxBackoff
+	}
+	return p
+}
+
+// Whether retry number attempt (0-based) is allowed
+func (p RetryPolicy) allows(attempt int) bool {
+	return attempt < p.MaxRetries
+}
+
+// Longest a request may take through every attempt and backoff, rate-limit pacing aside
+func (p RetryPolicy) budget() time.Duration {
+	total := p.Timeout
+	for attempt := 0; p.allows(attempt); attempt++ {
+		total += backoffDelay(attempt, p.BaseBackoff, p.MaxBackoff) + p.Timeout
+	}
+	return total
+}
+
+// Jittered wait before retry number attempt
+func (p RetryPolicy) delay(j *jitterSource, attempt int) time.Duration {
+	return j.jitter(backoffDelay(attempt, p.BaseBackoff, p.MaxBackoff))
+}
+
+// Delay before retry number attempt (0-based): base doubled each attempt, capped at max
+func backoffDelay(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	return min(d, max)
+}
+
+// Random source for retry jitter, safe for concurrent use
+type jitterSource struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// Source seeded with seed, or from the clock when seed is 0
+func newJitterSource(seed int64) *jitterSource {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &jitterSource{rng: rand.New(rand.NewSource(seed))}
+}
+
+// d with equal jitter: a random duration between d/2 and d, so clients retrying
+// together spread out instead of hitting the service in lockstep
+func (j *jitterSource) jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	half := d / 2
+	return half + time.Duration(j.rng.Int63n(int64(d-half)+1))
+}
+
+// Sleep for d, returning early with the context's error if it is cancelled
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
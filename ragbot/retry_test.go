@@ -0,0 +1,56 @@
+package ragbot
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestJitterIsReproducibleWithASeed(t *testing.T) {
+	a, b := newJitterSource(42), newJitterSource(42)
+	p := defaultPineconeRetry
+	for attempt := 0; attempt <= p.MaxRetries; attempt++ {
+		d := backoffDelay(attempt, p.BaseBackoff, p.MaxBackoff)
+		ja, jb := a.jitter(d), b.jitter(d)
+		if ja != jb {
+			t.Fatalf("attempt %d: same seed gave %v and %v", attempt, ja, jb)
+		}
+		if ja < d/2 || ja > d {
+			t.Errorf("attempt %d: jitter(%v) = %v, want between %v and %v", attempt, d, ja, d/2, d)
+		}
+	}
+}
+
+func TestSetRandSource(t *testing.T) {
+	b := &Bot{jitter: newJitterSource(0)}
+	b.SetRandSource(rand.NewSource(7))
+	first := b.jitter.jitter(time.Second)
+	b.SetRandSource(rand.NewSource(7))
+	if again := b.jitter.jitter(time.Second); again != first {
+		t.Errorf("resetting the source to the same seed gave %v then %v", first, again)
+	}
+}
+
+func TestNewDefaultsRetryPolicies(t *testing.T) {
+	b, err := New(&Config{
+		GeminiAPIKey:   "g",
+		PineconeAPIKey: "p",
+		Indexes:        map[int]string{384: "idx-384"},
+		Namespace:      "default",
+		Backend:        BackendLocal,
+		Concurrency:    1,
+		TopK:           1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Config().GeminiRetry; got != defaultGeminiRetry {
+		t.Errorf("gemini retry %+v, want the default %+v", got, defaultGeminiRetry)
+	}
+	if got := b.Config().PineconeRetry; got != defaultPineconeRetry {
+		t.Errorf("pinecone retry %+v, want the default %+v", got, defaultPineconeRetry)
+	}
+	if b.cfg.GeminiRetry.budget() <= 0 {
+		t.Error("shared embedding calls would time out at once")
+	}
+}
@@ -0,0 +1,70 @@
+package ragbot
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Cleanup of copy-pasted text applied to pair inputs before upload and to queries before
+// preprocessing, so invisible characters don't shift embeddings or end up in metadata.
+// Every step is off by default.
+type SanitizeConfig struct {
+	// Remove control characters and invisible format characters such as zero-width
+	// spaces and byte order marks. Tabs and newlines are kept for NormalizeWhitespace.
+	StripControl bool `yaml:"strip_control"`
+
+	// Trim the ends and collapse runs of whitespace, including non-breaking spaces, to one space
+	NormalizeWhitespace bool `yaml:"normalize_whitespace"`
+
+	// Unicode NFC normalization, so composed and decomposed accents embed alike
+	NFC bool `yaml:"nfc"`
+}
+
+// Whether any step is on
+func (s SanitizeConfig) enabled() bool {
+	return s.StripControl || s.NormalizeWhitespace || s.NFC
+}
+
+// Apply the enabled steps to text
+func (s SanitizeConfig) apply(text string) string {
+	out := text
+	if s.StripControl {
+		out = strings.Map(func(r rune) rune {
+			if (unicode.IsControl(r) && !unicode.IsSpace(r)) || unicode.Is(unicode.Cf, r) {
+				return -1
+			}
+			return r
+		}, out)
+	}
+	if s.NFC {
+		out = norm.NFC.String(out)
+	}
+	if s.NormalizeWhitespace {
+		out = strings.Join(strings.Fields(out), " ")
+	}
+	return out
+}
+
+// Sanitize text, logging the change in verbose mode
+func (b *Bot) sanitize(text string) string {
+	out := b.cfg.Sanitize.apply(text)
+	if b.verbose && out != text {
+		b.log.Info("🧼 sanitized input", "input", text, "sanitized", out)
+	}
+	return out
+}
+
+// pairs with sanitized inputs; pairs itself is returned when sanitizing is off
+func (b *Bot) sanitizePairs(pairs []InputOutputPair) []InputOutputPair {
+	if !b.cfg.Sanitize.enabled() {
+		return pairs
+	}
+	out := make([]InputOutputPair, len(pairs))
+	for i, pair := range pairs {
+		pair.Input = b.sanitize(pair.Input)
+		out[i] = pair
+	}
+	return out
+}
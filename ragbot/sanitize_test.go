@@ -0,0 +1,21 @@
+package ragbot
+
+import "testing"
+
+func TestSanitizeSteps(t *testing.T) {
+	raw := "\ufeffbook\u200b a  ride\x07 to cafe\u0301 \n"
+
+	all := SanitizeConfig{StripControl: true, NormalizeWhitespace: true, NFC: true}
+	if got, want := all.apply(raw), "book a ride to caf\u00e9"; got != want {
+		t.Errorf("all steps = %q, want %q", got, want)
+	}
+
+	if got := (SanitizeConfig{}).apply(raw); got != raw {
+		t.Errorf("no steps changed the text to %q", got)
+	}
+
+	strip := SanitizeConfig{StripControl: true}
+	if got, want := strip.apply(raw), "book a  ride to cafe\u0301 \n"; got != want {
+		t.Errorf("strip only = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,125 @@
+package ragbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Returned by FetchVector when the namespace has no vector with the ID
+var ErrVectorNotFound = errors.New("vector not found")
+
+// Page size for listing vector IDs; Pinecone caps list and fetch at 100
+const scanPageSize = 100
+
+// GET an index endpoint with query parameters. Non-200 responses become errors carrying the body.
+func (b *Bot) pineconeGet(ctx context.Context, dimension int, path string, params url.Values, out interface{}) error {
+	if b.local != nil {
+		return ErrLocalBackend
+	}
+	base, err := b.indexURL(dimension)
+	if err != nil {
+		return err
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", base+path+"?"+params.Encode(), nil)
+	req.Header.Add("Api-Key", b.cfg.PineconeAPIKey)
+	return b.pineconeDo(req, nil, out)
+}
+
+// One page of vector IDs in a namespace, and the token for the next page ("" on the last)
+func (b *Bot) listVectorIDs(ctx context.Context, dimension int, namespace string, paginationToken string) ([]string, string, error) {
+	params := url.Values{}
+	params.Set("namespace", namespace)
+	params.Set("limit", fmt.Sprint(scanPageSize))
+	if paginationToken != "" {
+		params.Set("paginationToken", paginationToken)
+	}
+
+	var page struct {
+		Vectors []struct {
+			ID string `json:"id"`
+		} `json:"vectors"`
+		Pagination struct {
+			Next string `json:"next"`
+		} `json:"pagination"`
+	}
+	if err := b.pineconeGet(ctx, dimension, "/vectors/list", params, &page); err != nil {
+		return nil, "", err
+	}
+
+	ids := make([]string, len(page.Vectors))
+	for i, v := range page.Vectors {
+		ids[i] = v.ID
+	}
+	return ids, page.Pagination.Next, nil
+}
+
+// Fetch vectors with their values and metadata by ID from a namespace. IDs that don't exist are left out.
+func (b *Bot) fetchVectors(ctx context.Context, dimension int, namespace string, ids []string) (map[string]Vector, error) {
+	params := url.Values{}
+	params.Set("namespace", namespace)
+	for _, id := range ids {
+		params.Add("ids", id)
+	}
+
+	var resp struct {
+		Vectors map[string]Vector `json:"vectors"`
+	}
+	if err := b.pineconeGet(ctx, dimension, "/vectors/fetch", params, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Vectors, nil
+}
+
+// Fetch a single vector with its values and metadata by ID
+func (b *Bot) fetchVector(ctx context.Context, id string, dimension int) (*Vector, error) {
+	fetched, err := b.fetchVectors(ctx, dimension, b.cfg.NamespaceFor(dimension), []string{id})
+	if err != nil {
+		return nil, err
+	}
+	v, ok := fetched[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in %s (namespace %s)", ErrVectorNotFound, id, b.cfg.Indexes[dimension], b.cfg.NamespaceFor(dimension))
+	}
+	return &v, nil
+}
+
+// Walk every vector in a dimension's index page by page, calling fn with each page
+// in listing order. Stops at the first error from Pinecone or fn.
+func (b *Bot) Scan(ctx context.Context, dimension int, fn func(page []Vector) error) error {
+	return b.ScanNamespace(ctx, dimension, b.cfg.NamespaceFor(dimension), fn)
+}
+
+// Scan a namespace other than the configured one
+func (b *Bot) ScanNamespace(ctx context.Context, dimension int, namespace string, fn func(page []Vector) error) error {
+	token := ""
+	for {
+		ids, next, err := b.listVectorIDs(ctx, dimension, namespace, token)
+		if err != nil {
+			return fmt.Errorf("failed to list vectors: %v", err)
+		}
+
+		if len(ids) > 0 {
+			fetched, err := b.fetchVectors(ctx, dimension, namespace, ids)
+			if err != nil {
+				return fmt.Errorf("failed to fetch vectors: %v", err)
+			}
+			page := make([]Vector, 0, len(ids))
+			for _, id := range ids {
+				if v, ok := fetched[id]; ok {
+					page = append(page, v)
+				}
+			}
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
@@ -0,0 +1,35 @@
+package ragbot
+
+// Spread of a set of similarity scores
+type ScoreSummary struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+}
+
+// Summarize scores; the zero summary for none
+func SummarizeScores(scores []float32) ScoreSummary {
+	if len(scores) == 0 {
+		return ScoreSummary{}
+	}
+	s := ScoreSummary{Count: len(scores), Min: float64(scores[0]), Max: float64(scores[0])}
+	var sum float64
+	for _, score := range scores {
+		v := float64(score)
+		s.Min = min(s.Min, v)
+		s.Max = max(s.Max, v)
+		sum += v
+	}
+	s.Mean = sum / float64(len(scores))
+	return s
+}
+
+// Raw scores of every returned match, in ranked order
+func (r *Response) Scores() []float32 {
+	scores := make([]float32, len(r.Matches))
+	for i, m := range r.Matches {
+		scores[i] = m.Score
+	}
+	return scores
+}
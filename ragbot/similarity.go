@@ -0,0 +1,57 @@
+package ragbot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Cosine similarity of two vectors, in [-1, 1]. A zero-magnitude vector has no
+// direction, so its similarity to anything is 0.
+func cosineSimilarity(a, b []float32) (float32, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("vector length mismatch: %d vs %d", len(a), len(b))
+	}
+
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0, nil
+	}
+	return float32(dot / (math.Sqrt(na) * math.Sqrt(nb))), nil
+}
+
+// Cosine similarity of two vectors, in [-1, 1], for callers scoring vectors themselves
+func CosineSimilarity(a, b []float32) (float32, error) {
+	return cosineSimilarity(a, b)
+}
+
+// A vector found by nearestNeighbors: its position in the searched slice and its score
+type neighbor struct {
+	Index int
+	Score float32
+}
+
+// Brute-force search for the k vectors most cosine-similar to query, best first.
+// Ties keep their order in vectors.
+func nearestNeighbors(query []float32, vectors [][]float32, k int) ([]neighbor, error) {
+	all := make([]neighbor, len(vectors))
+	for i, v := range vectors {
+		score, err := cosineSimilarity(query, v)
+		if err != nil {
+			return nil, fmt.Errorf("vector %d: %v", i, err)
+		}
+		all[i] = neighbor{Index: i, Score: score}
+	}
+	return topNeighbors(all, k), nil
+}
+
+// The k highest-scoring neighbors, best first, keeping the input order of ties
+func topNeighbors(all []neighbor, k int) []neighbor {
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Score > all[j].Score })
+	return all[:max(0, min(k, len(all)))]
+}
@@ -0,0 +1,77 @@
+package ragbot
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float32
+	}{
+		{"identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 1},
+		{"same direction", []float32{1, 2, 3}, []float32{2, 4, 6}, 1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 0},
+		{"opposite", []float32{1, -2, 3}, []float32{-1, 2, -3}, -1},
+		{"45 degrees", []float32{1, 0}, []float32{1, 1}, float32(1 / math.Sqrt2)},
+		{"zero vector", []float32{0, 0, 0}, []float32{1, 2, 3}, 0},
+		{"both zero", []float32{0, 0}, []float32{0, 0}, 0},
+		{"empty", []float32{}, []float32{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cosineSimilarity(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(float64(got-tt.want)) > 1e-6 {
+				t.Errorf("cosineSimilarity(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCosineSimilarityLengthMismatch(t *testing.T) {
+	if _, err := cosineSimilarity([]float32{1, 2}, []float32{1, 2, 3}); err == nil {
+		t.Fatal("expected an error for mismatched lengths")
+	}
+}
+
+func TestNearestNeighbors(t *testing.T) {
+	vectors := [][]float32{
+		{0, 1},  // orthogonal
+		{-1, 0}, // opposite
+		{1, 0},  // identical
+		{1, 1},  // 45 degrees
+		{0, 0},  // zero
+	}
+
+	got, err := nearestNeighbors([]float32{1, 0}, vectors, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantOrder := []int{2, 3, 0}
+	if len(got) != len(wantOrder) {
+		t.Fatalf("got %d neighbors, want %d", len(got), len(wantOrder))
+	}
+	for i, want := range wantOrder {
+		if got[i].Index != want {
+			t.Errorf("rank %d = vector %d, want %d (all: %+v)", i, got[i].Index, want, got)
+		}
+	}
+
+	// Asking for more than there are returns everything, worst last
+	all, _ := nearestNeighbors([]float32{1, 0}, vectors, 10)
+	if len(all) != len(vectors) || all[len(all)-1].Index != 1 {
+		t.Errorf("got %+v, want all %d vectors ending with the opposite one", all, len(vectors))
+	}
+}
+
+func TestNearestNeighborsLengthMismatch(t *testing.T) {
+	if _, err := nearestNeighbors([]float32{1, 0}, [][]float32{{1, 0}, {1, 0, 0}}, 1); err == nil {
+		t.Fatal("expected an error for a vector of the wrong length")
+	}
+}
@@ -0,0 +1,144 @@
+package ragbot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Vector snapshot files are gzip-compressed JSON lines: a SnapshotHeader, then one Vector
+// (ID, values and metadata) per line. Readers accept any version up to SnapshotVersion and
+// ignore fields they don't know; a format change old readers can't handle bumps the version.
+const (
+	snapshotFormat  = "chatbot-rag-vectors"
+	SnapshotVersion = 1
+)
+
+// First line of a snapshot file
+type SnapshotHeader struct {
+	Format         string    `json:"format"`
+	Version        int       `json:"version"`
+	Created        time.Time `json:"created"`
+	EmbeddingModel string    `json:"embedding_model"`
+}
+
+// Streams vectors into a snapshot file. Safe for concurrent use.
+type SnapshotWriter struct {
+	mu    sync.Mutex
+	gz    *gzip.Writer
+	enc   *json.Encoder
+	count int
+}
+
+// Start a snapshot in w, writing its header. model is the embedding model of the vectors.
+func NewSnapshotWriter(w io.Writer, model string) (*SnapshotWriter, error) {
+	gz := gzip.NewWriter(w)
+	s := &SnapshotWriter{gz: gz, enc: json.NewEncoder(gz)}
+	header := SnapshotHeader{Format: snapshotFormat, Version: SnapshotVersion, Created: time.Now().UTC(), EmbeddingModel: model}
+	if err := s.enc.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot header: %v", err)
+	}
+	return s, nil
+}
+
+// Append vectors to the snapshot
+func (s *SnapshotWriter) Write(vectors []Vector) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range vectors {
+		if err := s.enc.Encode(v); err != nil {
+			return fmt.Errorf("failed to write vector %s to snapshot: %v", v.ID, err)
+		}
+		s.count++
+	}
+	return nil
+}
+
+// Vectors written so far
+func (s *SnapshotWriter) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Finish the gzip stream. The underlying writer is left open.
+func (s *SnapshotWriter) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gz.Close()
+}
+
+// Read a snapshot, calling fn for each vector in file order
+func ReadSnapshot(r io.Reader, fn func(Vector) error) (*SnapshotHeader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a vector snapshot (expected gzip): %v", err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read snapshot header: %v", err)
+		}
+		return nil, fmt.Errorf("snapshot is empty")
+	}
+	var header SnapshotHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != snapshotFormat {
+		return nil, fmt.Errorf("not a vector snapshot: missing %q header", snapshotFormat)
+	}
+	if header.Version < 1 || header.Version > SnapshotVersion {
+		return nil, fmt.Errorf("snapshot format version %d is not supported (this build reads up to %d)", header.Version, SnapshotVersion)
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		var v Vector
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return &header, fmt.Errorf("snapshot line %d: %v", line, err)
+		}
+		if err := fn(v); err != nil {
+			return &header, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &header, fmt.Errorf("failed to read snapshot: %v", err)
+	}
+	return &header, nil
+}
+
+// Also write every vector IndexPairs embeds to w, whether or not its upload succeeds
+func (b *Bot) SetSnapshot(w *SnapshotWriter) {
+	b.snapshot = w
+}
+
+// Load the vectors in a snapshot into the local backend, so searches use the stored
+// embeddings instead of embedding every pair again. Queries are still embedded with
+// Gemini (or matched by text without a key), so the snapshot should come from the
+// configured embedding model. Returns the number of vectors loaded.
+func (b *Bot) LoadSnapshot(r io.Reader) (int, error) {
+	if b.local == nil {
+		return 0, fmt.Errorf("loading a snapshot needs the local backend")
+	}
+	loaded := 0
+	header, err := ReadSnapshot(r, func(v Vector) error {
+		if len(v.Values) == 0 {
+			return nil
+		}
+		b.local.addVector(v)
+		loaded++
+		return nil
+	})
+	if err != nil {
+		return loaded, err
+	}
+	if header.EmbeddingModel != "" && header.EmbeddingModel != b.cfg.EmbeddingModel {
+		b.log.Warn("snapshot was embedded with another model; query embeddings won't be comparable",
+			"snapshot_model", header.EmbeddingModel, "embedding_model", b.cfg.EmbeddingModel)
+	}
+	return loaded, nil
+}
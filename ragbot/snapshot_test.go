@@ -0,0 +1,68 @@
+package ragbot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	vectors := []Vector{
+		{ID: "a", Values: []float32{0.1, 0.2}, Metadata: Metadata{Input: "hello", Output: "hi", Dimension: 2}},
+		{ID: "b", Values: []float32{0.3, 0.4, 0.5}, Metadata: Metadata{Input: "bye", Output: "see you", Outputs: []string{"see you", "later"}, Dimension: 3}},
+	}
+	var buf bytes.Buffer
+	w, err := NewSnapshotWriter(&buf, "text-embedding-004")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(vectors); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Vector
+	header, err := ReadSnapshot(bytes.NewReader(buf.Bytes()), func(v Vector) error {
+		got = append(got, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSnapshot: %v", err)
+	}
+	if header.Version != SnapshotVersion || header.EmbeddingModel != "text-embedding-004" {
+		t.Errorf("header = %+v", header)
+	}
+	if !reflect.DeepEqual(got, vectors) {
+		t.Errorf("read back %+v, want %+v", got, vectors)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Backend = BackendLocal
+	b, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := b.LoadSnapshot(bytes.NewReader(buf.Bytes()))
+	if err != nil || n != 2 {
+		t.Fatalf("LoadSnapshot = %d, %v; want 2 vectors", n, err)
+	}
+	if len(b.local.pairs) != 2 || len(b.local.vectors[3]) != 1 || len(b.local.vectors[2]) != 1 {
+		t.Errorf("local store has %d pairs, vectors %v", len(b.local.pairs), b.local.vectors)
+	}
+}
+
+func TestReadSnapshotRejectsNewerVersion(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"format":"chatbot-rag-vectors","version":99}` + "\n"))
+	gz.Close()
+
+	_, err := ReadSnapshot(&buf, func(Vector) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("err = %v, want an unsupported version error", err)
+	}
+}
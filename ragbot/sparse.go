@@ -0,0 +1,67 @@
+package ragbot
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// BM25 term-frequency parameters. There is no corpus-wide IDF, so only the TF part is used.
+const (
+	bm25K1        = 1.2
+	bm25B         = 0.75
+	bm25AvgDocLen = 8.0 // typical length in tokens of a training input
+)
+
+// Sparse vector in Pinecone's {indices, values} form
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// Lowercase text and split it into letter/digit tokens
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// BM25-style term weights for text, with each token hashed into the sparse index space
+func sparseEncode(text string) SparseVector {
+	tokens := tokenize(text)
+	tf := map[uint32]float64{}
+	for _, tok := range tokens {
+		h := fnv.New32a()
+		h.Write([]byte(tok))
+		tf[h.Sum32()]++
+	}
+
+	norm := bm25K1 * (1 - bm25B + bm25B*float64(len(tokens))/bm25AvgDocLen)
+	sv := SparseVector{}
+	for idx := range tf {
+		sv.Indices = append(sv.Indices, idx)
+	}
+	sort.Slice(sv.Indices, func(i, j int) bool { return sv.Indices[i] < sv.Indices[j] })
+	for _, idx := range sv.Indices {
+		f := tf[idx]
+		sv.Values = append(sv.Values, float32(f*(bm25K1+1)/(f+norm)))
+	}
+
+	return sv
+}
+
+// Weight the dense and sparse parts of a hybrid query: alpha=1 is pure dense, alpha=0 pure sparse
+func hybridScale(dense []float32, sparse SparseVector, alpha float64) ([]float32, SparseVector) {
+	scaledDense := make([]float32, len(dense))
+	for i, v := range dense {
+		scaledDense[i] = v * float32(alpha)
+	}
+
+	scaledSparse := SparseVector{Indices: sparse.Indices, Values: make([]float32, len(sparse.Values))}
+	for i, v := range sparse.Values {
+		scaledSparse.Values[i] = v * float32(1-alpha)
+	}
+
+	return scaledDense, scaledSparse
+}
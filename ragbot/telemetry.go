@@ -0,0 +1,152 @@
+package ragbot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upper bounds in seconds of the latency histogram buckets
+var latencyBuckets = []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Cumulative latency distribution in the Prometheus histogram shape
+type histogram struct {
+	counts []int64 // per bucket in latencyBuckets, not cumulative
+	sum    float64
+	count  int64
+}
+
+func (h *histogram) observe(d time.Duration) {
+	if h.counts == nil {
+		h.counts = make([]int64, len(latencyBuckets))
+	}
+	s := d.Seconds()
+	for i, le := range latencyBuckets {
+		if s <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += s
+	h.count++
+}
+
+// Process-wide counters for a long-running bot, exported by WritePrometheus.
+// Embedding usage reuses the per-dimension counters of bulk-run RunMetrics.
+type telemetry struct {
+	mu               sync.Mutex
+	queries          int64
+	fallbacks        int64
+	embeddings       *RunMetrics
+	pineconeErrors   map[int]int64
+	embeddingLatency map[int]*histogram
+	queryLatency     map[int]*histogram
+}
+
+func newTelemetry() *telemetry {
+	return &telemetry{
+		embeddings:       newRunMetrics(),
+		pineconeErrors:   map[int]int64{},
+		embeddingLatency: map[int]*histogram{},
+		queryLatency:     map[int]*histogram{},
+	}
+}
+
+// Record one Gemini embedding call
+func (t *telemetry) recordEmbedding(dim, chars int, elapsed time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.embeddings.RecordEmbedding(dim, chars, err)
+	t.embeddings.Dimension(dim).Elapsed += elapsed
+	observe(t.embeddingLatency, dim, elapsed)
+}
+
+// Record one Pinecone query
+func (t *telemetry) recordQuery(dim int, elapsed time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.pineconeErrors[dim]++
+	}
+	observe(t.queryLatency, dim, elapsed)
+}
+
+// Record one answered query and whether it fell back to a non-confident reply
+func (t *telemetry) recordAnswer(confident bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queries++
+	if !confident {
+		t.fallbacks++
+	}
+}
+
+func observe(hs map[int]*histogram, dim int, d time.Duration) {
+	h, ok := hs[dim]
+	if !ok {
+		h = &histogram{}
+		hs[dim] = h
+	}
+	h.observe(d)
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Write the bot's counters and latency histograms in the Prometheus text exposition format
+func (b *Bot) WritePrometheus(w io.Writer) {
+	t := b.telemetry
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	header := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("chatbot_queries_total", "counter", "Queries answered.")
+	fmt.Fprintf(w, "chatbot_queries_total %d\n", t.queries)
+	header("chatbot_fallback_responses_total", "counter", "Queries answered without a confident match.")
+	fmt.Fprintf(w, "chatbot_fallback_responses_total %d\n", t.fallbacks)
+
+	header("chatbot_embedding_calls_total", "counter", "Gemini embedding calls, by dimension.")
+	for _, dim := range t.embeddings.SortedDimensions() {
+		fmt.Fprintf(w, "chatbot_embedding_calls_total{dimension=\"%d\"} %d\n", dim, t.embeddings.Dimensions[dim].EmbeddingCalls)
+	}
+	header("chatbot_embedding_errors_total", "counter", "Failed Gemini embedding calls, by dimension.")
+	for _, dim := range t.embeddings.SortedDimensions() {
+		fmt.Fprintf(w, "chatbot_embedding_errors_total{dimension=\"%d\"} %d\n", dim, t.embeddings.Dimensions[dim].FailedCalls)
+	}
+	header("chatbot_embedding_estimated_tokens_total", "counter", "Estimated Gemini input tokens embedded, by dimension.")
+	for _, dim := range t.embeddings.SortedDimensions() {
+		fmt.Fprintf(w, "chatbot_embedding_estimated_tokens_total{dimension=\"%d\"} %d\n", dim, t.embeddings.Dimensions[dim].EstimatedTokens())
+	}
+	header("chatbot_pinecone_errors_total", "counter", "Failed Pinecone queries, by dimension.")
+	for _, dim := range sortedKeys(t.pineconeErrors) {
+		fmt.Fprintf(w, "chatbot_pinecone_errors_total{dimension=\"%d\"} %d\n", dim, t.pineconeErrors[dim])
+	}
+
+	histograms := func(name, help string, hs map[int]*histogram) {
+		header(name, "histogram", help)
+		for _, dim := range sortedKeys(hs) {
+			h := hs[dim]
+			var cumulative int64
+			for i, le := range latencyBuckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(w, "%s_bucket{dimension=\"%d\",le=\"%g\"} %d\n", name, dim, le, cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket{dimension=\"%d\",le=\"+Inf\"} %d\n", name, dim, h.count)
+			fmt.Fprintf(w, "%s_sum{dimension=\"%d\"} %g\n", name, dim, h.sum)
+			fmt.Fprintf(w, "%s_count{dimension=\"%d\"} %d\n", name, dim, h.count)
+		}
+	}
+	histograms("chatbot_embedding_latency_seconds", "Gemini embedding call latency, by dimension.", t.embeddingLatency)
+	histograms("chatbot_query_latency_seconds", "Pinecone query latency, by dimension.", t.queryLatency)
+}
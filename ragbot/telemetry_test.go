@@ -0,0 +1,35 @@
+package ragbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	b := &Bot{telemetry: newTelemetry()}
+	b.telemetry.recordAnswer(true)
+	b.telemetry.recordAnswer(false)
+	b.telemetry.recordEmbedding(384, 40, 30*time.Millisecond, nil)
+	b.telemetry.recordEmbedding(384, 40, 2*time.Second, errors.New("boom"))
+	b.telemetry.recordQuery(1024, 80*time.Millisecond, errors.New("boom"))
+
+	var out strings.Builder
+	b.WritePrometheus(&out)
+	for _, want := range []string{
+		"chatbot_queries_total 2\n",
+		"chatbot_fallback_responses_total 1\n",
+		`chatbot_embedding_calls_total{dimension="384"} 2` + "\n",
+		`chatbot_embedding_errors_total{dimension="384"} 1` + "\n",
+		`chatbot_pinecone_errors_total{dimension="1024"} 1` + "\n",
+		`chatbot_embedding_latency_seconds_bucket{dimension="384",le="0.05"} 1` + "\n",
+		`chatbot_embedding_latency_seconds_bucket{dimension="384",le="2.5"} 2` + "\n",
+		`chatbot_embedding_latency_seconds_count{dimension="384"} 2` + "\n",
+		`chatbot_query_latency_seconds_bucket{dimension="1024",le="+Inf"} 1` + "\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q in:\n%s", want, out.String())
+		}
+	}
+}
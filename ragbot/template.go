@@ -0,0 +1,80 @@
+package ragbot
+
+import (
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Render a stored output as a text/template with vars, e.g. "Your cab {{.cabNumber}} is here".
+// Variables that are not supplied stay in the text as their {{.name}} placeholder. Outputs
+// without template actions, and ones that fail to parse or execute, are returned unchanged.
+func renderOutput(output string, vars map[string]string) (string, error) {
+	if !strings.Contains(output, "{{") {
+		return output, nil
+	}
+	tmpl, err := template.New("output").Option("missingkey=zero").Parse(output)
+	if err != nil {
+		return output, err
+	}
+
+	data := map[string]string{}
+	for _, name := range templateFields(tmpl.Root) {
+		data[name] = "{{." + name + "}}"
+	}
+	for k, v := range vars {
+		data[k] = v
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return output, err
+	}
+	return sb.String(), nil
+}
+
+// Names of the top-level fields (.name) referenced anywhere in a template
+func templateFields(node parse.Node) []string {
+	var names []string
+	var walk func(parse.Node)
+	walk = func(n parse.Node) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c)
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *parse.FieldNode:
+			names = append(names, n.Ident[0])
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		}
+	}
+	walk(node)
+	return names
+}
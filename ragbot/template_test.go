@@ -0,0 +1,24 @@
+package ragbot
+
+import "testing"
+
+func TestRenderOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		vars   map[string]string
+		want   string
+	}{
+		{"plain", "Your ride is booked", nil, "Your ride is booked"},
+		{"filled", "Driver {{.driverName}} in cab {{.cabNumber}}", map[string]string{"driverName": "Asha", "cabNumber": "KA01AB1234"}, "Driver Asha in cab KA01AB1234"},
+		{"missing keeps placeholder", "Driver {{.driverName}} in cab {{.cabNumber}}", map[string]string{"driverName": "Asha"}, "Driver Asha in cab {{.cabNumber}}"},
+		{"conditional", "{{if .eta}}Arriving in {{.eta}}{{else}}On the way{{end}}", map[string]string{"eta": "5 min"}, "Arriving in 5 min"},
+		{"invalid", "Broken {{.driverName", nil, "Broken {{.driverName"},
+	}
+	for _, tt := range tests {
+		got, _ := renderOutput(tt.output, tt.vars)
+		if got != tt.want {
+			t.Errorf("%s: renderOutput(%q) = %q, want %q", tt.name, tt.output, got, tt.want)
+		}
+	}
+}
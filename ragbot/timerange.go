@@ -0,0 +1,77 @@
+package ragbot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window of vector creation times, matched against Metadata.CreatedAt. A zero bound is
+// open; Since is inclusive and Until exclusive.
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// Whether neither bound is set
+func (r TimeRange) IsZero() bool {
+	return r.Since.IsZero() && r.Until.IsZero()
+}
+
+// Whether a vector created at createdAt (Unix seconds) falls in the range. Vectors
+// without created_at (0) only match an empty range.
+func (r TimeRange) Contains(createdAt int64) bool {
+	if r.IsZero() {
+		return true
+	}
+	if createdAt == 0 {
+		return false
+	}
+	return (r.Since.IsZero() || createdAt >= r.Since.Unix()) &&
+		(r.Until.IsZero() || createdAt < r.Until.Unix())
+}
+
+func (r TimeRange) String() string {
+	format := func(t time.Time) string {
+		if t.IsZero() {
+			return "(open)"
+		}
+		return t.Format(time.RFC3339)
+	}
+	return format(r.Since) + " to " + format(r.Until)
+}
+
+// Parse a range bound: an RFC 3339 time, a 2006-01-02 date (midnight local time), or a
+// duration such as 24h meaning that long before now
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want RFC 3339, YYYY-MM-DD or a duration like 24h)", s)
+}
+
+// Scan only the vectors created within r. Pinecone can't filter a listing by metadata,
+// so every vector is still fetched and the check is client-side; fn isn't called for
+// pages with nothing in range.
+func (b *Bot) ScanRange(ctx context.Context, dimension int, r TimeRange, fn func(page []Vector) error) error {
+	return b.Scan(ctx, dimension, func(page []Vector) error {
+		kept := page[:0:0]
+		for _, v := range page {
+			if r.Contains(v.Metadata.CreatedAt) {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		return fn(kept)
+	})
+}
@@ -0,0 +1,46 @@
+package ragbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeRangeContains(t *testing.T) {
+	since := time.Unix(1000, 0)
+	until := time.Unix(2000, 0)
+	tests := []struct {
+		r         TimeRange
+		createdAt int64
+		want      bool
+	}{
+		{TimeRange{}, 0, true},
+		{TimeRange{Since: since}, 1000, true},
+		{TimeRange{Since: since}, 999, false},
+		{TimeRange{Until: until}, 2000, false},
+		{TimeRange{Since: since, Until: until}, 1500, true},
+		{TimeRange{Since: since}, 0, false}, // no created_at
+	}
+	for _, tt := range tests {
+		if got := tt.r.Contains(tt.createdAt); got != tt.want {
+			t.Errorf("%v Contains(%d) = %v, want %v", tt.r, tt.createdAt, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2024-05-01T08:00:00Z": time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local),
+		"36h":                  now.Add(-36 * time.Hour),
+	}
+	for s, want := range tests {
+		got, err := ParseTimeBound(s, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseTimeBound("yesterday", now); err == nil {
+		t.Error("ParseTimeBound accepted \"yesterday\"")
+	}
+}
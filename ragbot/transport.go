@@ -0,0 +1,37 @@
+package ragbot
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Connection pool settings for the hosts the bot talks to: Gemini and every Pinecone index
+// host, which one query searches at once. http.DefaultTransport keeps only 2 idle connections
+// per host, so a worker pool above that churns through new TLS connections during bulk uploads.
+const (
+	minIdleConnsPerHost = 4
+	idleConnTimeout     = 90 * time.Second
+	tlsHandshakeTimeout = 10 * time.Second
+	dialTimeout         = 10 * time.Second
+)
+
+// HTTP client shared by every Gemini and Pinecone call, keeping enough idle connections
+// per host for concurrency parallel workers. The total isn't capped, since the number of
+// index hosts depends on the configured dimensions.
+func newHTTPClient(concurrency int) *http.Client {
+	perHost := max(concurrency, minIdleConnsPerHost)
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConnsPerHost:   perHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Transport: transport}
+}
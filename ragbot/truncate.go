@@ -0,0 +1,31 @@
+package ragbot
+
+import (
+	"fmt"
+	"math"
+)
+
+// Derive a lower-dimensional embedding from a larger Matryoshka (MRL) embedding:
+// keep the first dim values and rescale them to unit length. gemini-embedding-001
+// is trained so that such prefixes are usable embeddings on their own.
+func truncateEmbedding(full []float32, dim int) ([]float32, error) {
+	if dim > len(full) {
+		return nil, fmt.Errorf("cannot truncate %d-dimensional embedding to %d", len(full), dim)
+	}
+
+	out := make([]float32, dim)
+	copy(out, full[:dim])
+
+	var sum float64
+	for _, v := range out {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return nil, fmt.Errorf("cannot normalize zero embedding")
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range out {
+		out[i] /= norm
+	}
+	return out, nil
+}
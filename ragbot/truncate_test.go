@@ -0,0 +1,149 @@
+package ragbot
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
+
+// Fake Gemini with MRL behaviour: a request for n dimensions returns the first n values
+// of one fixed 1024-dimensional embedding, unnormalized like the real API below 3072
+func mrlGemini() http.HandlerFunc {
+	rng := rand.New(rand.NewSource(1))
+	full := make([]float32, 1024)
+	for i := range full {
+		full[i] = float32(rng.NormFloat64())
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			OutputDimensionality int `json:"outputDimensionality"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"embedding": map[string]interface{}{"values": full[:req.OutputDimensionality]},
+		})
+	}
+}
+
+func TestIndexTruncatesOneCallPerInput(t *testing.T) {
+	var calls atomic.Int32
+	mrl := mrlGemini()
+	upserted := map[int][]float32{}
+	var mu sync.Mutex
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			mrl(w, r)
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Vectors []Vector `json:"vectors"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			for _, v := range req.Vectors {
+				upserted[len(v.Values)] = v.Values
+			}
+			mu.Unlock()
+			w.Write([]byte(`{}`))
+		},
+	)
+	b.cfg.Indexes = map[int]string{384: "idx-384", 512: "idx-512", 1024: "idx-1024"}
+	b.cfg.TruncateEmbeddings = true
+
+	report, err := b.Index(context.Background(), []InputOutputPair{{Input: "Book my ride for tomorrow", Output: "Booked"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("%d Gemini calls, want one for the input", calls.Load())
+	}
+	if got := report.Metrics.Totals().EmbeddingCalls; got != 1 || report.Metrics.Dimension(1024).EmbeddingCalls != 1 {
+		t.Errorf("metrics record %d calls (%d at 1024), want the one call under 1024", got, report.Metrics.Dimension(1024).EmbeddingCalls)
+	}
+
+	for _, dim := range []int{384, 512} {
+		// The fake serves MRL prefixes, so a direct call at dim returns the prefix of length dim
+		direct, err := b.getEmbedding(context.Background(), "Book my ride for tomorrow", dim, TaskRetrievalDocument)
+		if err != nil {
+			t.Fatal(err)
+		}
+		truncated := upserted[dim]
+		if len(truncated) != dim {
+			t.Fatalf("dim %d: upserted %d values", dim, len(truncated))
+		}
+		if sim, _ := cosineSimilarity(direct, truncated); sim < 0.999 {
+			t.Errorf("dim %d: cosine(direct, indexed) = %f, want >= 0.999", dim, sim)
+		}
+		if n := math.Sqrt(dot(truncated, truncated)); math.Abs(n-1) > 1e-5 {
+			t.Errorf("dim %d: indexed norm = %f, want 1", dim, n)
+		}
+	}
+}
+
+func TestTruncateEmbeddingRejectsLargerDimension(t *testing.T) {
+	if _, err := truncateEmbedding(make([]float32, 384), 512); err == nil {
+		t.Fatal("expected an error truncating 384 to 512")
+	}
+}
+
+func TestIndexEmbedsEachInputOnce(t *testing.T) {
+	var calls, upserted atomic.Int32
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			var req struct {
+				OutputDimensionality int `json:"outputDimensionality"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			w.Write(embeddingBody(req.OutputDimensionality))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Vectors []Vector `json:"vectors"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			upserted.Add(int32(len(req.Vectors)))
+			w.Write([]byte(`{}`))
+		},
+	)
+	b.cfg.Indexes = map[int]string{384: "idx-384", 512: "idx-512"}
+	b.cfg.Concurrency = 2
+	pairs := []InputOutputPair{
+		{Input: "cancel my ride", Output: "Done"},
+		{Input: "cancel my ride", Output: "Cancelled"}, // same chunk
+		{Input: "where is my cab", Output: "On the way"},
+		{Input: "cancel my ride", Output: "Your ride is cancelled"}, // later chunk
+	}
+
+	if _, err := b.Index(context.Background(), pairs); err != nil {
+		t.Fatal(err)
+	}
+	// The three "cancel my ride" pairs share an ID, so they become one one-to-many vector
+	if calls.Load() != 4 || upserted.Load() != 4 {
+		t.Errorf("%d Gemini calls and %d vectors, want 2 inputs x 2 dimensions = 4 calls for 4 vectors", calls.Load(), upserted.Load())
+	}
+
+	calls.Store(0)
+	b.cfg.TruncateEmbeddings = true
+	if _, err := b.Index(context.Background(), pairs); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("with truncation: %d Gemini calls, want one per distinct input", calls.Load())
+	}
+}
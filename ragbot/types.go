@@ -0,0 +1,90 @@
+package ragbot
+
+import "encoding/json"
+
+type InputOutputPair struct {
+	Input  string
+	Output string
+
+	// Every acceptable response for a one-to-many pair, Output being the first; nil for a
+	// pair with a single response. Answer picks one per Config.OutputSelection.
+	Outputs []string
+}
+
+type Vector struct {
+	ID           string        `json:"id"`
+	Values       []float32     `json:"values"`
+	SparseValues *SparseVector `json:"sparseValues,omitempty"`
+	Metadata     Metadata      `json:"metadata"`
+}
+
+type EmbeddingResponse struct {
+	Embedding struct {
+		Values []float32 `json:"values"`
+	} `json:"embedding"`
+}
+
+// Metadata stored with every vector, written on upsert and read back by queries, fetch and scan
+type Metadata struct {
+	Input       string   `json:"input"`
+	Output      string   `json:"output"`
+	Outputs     []string `json:"outputs,omitempty"` // InputOutputPair.Outputs, for one-to-many pairs
+	Dimension   int      `json:"dimension"`
+	PairID      int      `json:"pair_id"`    // position of the pair in the source file, AddedPairID for Add
+	CreatedAt   int64    `json:"created_at"` // Unix seconds
+	InputLen    int      `json:"input_len"`
+	OutputLen   int      `json:"output_len"`
+	ContentHash string   `json:"content_hash,omitempty"`
+	IDScheme    string   `json:"id_scheme,omitempty"`
+	Intent      string   `json:"intent,omitempty"`
+
+	// Output was cut to fit Config.MaxMetadataBytes; OutputLen still gives the full length
+	OutputTruncated bool `json:"output_truncated,omitempty"`
+
+	// Namespace the match came from, set by the search; not stored in Pinecone
+	Namespace string `json:"-"`
+}
+
+// Pinecone stores every metadata number as a float and returns whole numbers as e.g. 384.0,
+// which encoding/json refuses to put in an int, so numeric fields are decoded as floats
+func (m *Metadata) UnmarshalJSON(data []byte) error {
+	type plain Metadata
+	var raw struct {
+		*plain
+		Dimension float64 `json:"dimension"`
+		PairID    float64 `json:"pair_id"`
+		CreatedAt float64 `json:"created_at"`
+		InputLen  float64 `json:"input_len"`
+		OutputLen float64 `json:"output_len"`
+	}
+	raw.plain = (*plain)(m)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	m.Dimension = int(raw.Dimension)
+	m.PairID = int(raw.PairID)
+	m.CreatedAt = int64(raw.CreatedAt)
+	m.InputLen = int(raw.InputLen)
+	m.OutputLen = int(raw.OutputLen)
+	return nil
+}
+
+// One match from a Pinecone query
+type Match struct {
+	ID       string   `json:"id"`
+	Score    float32  `json:"score"`
+	Metadata Metadata `json:"metadata"`
+
+	// Stored vector, only returned when Config.IncludeValues is set
+	Values []float32 `json:"values,omitempty"`
+}
+
+// Query interface to search similar inputs and get appropriate responses
+type QueryResult struct {
+	Matches []Match `json:"matches"`
+}
+
+// Input and outputs stored in the vector's metadata; missing fields are empty
+func (v Vector) Pair() InputOutputPair {
+	return InputOutputPair{Input: v.Metadata.Input, Output: v.Metadata.Output, Outputs: v.Metadata.Outputs}
+}
@@ -0,0 +1,34 @@
+package ragbot
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMetadataRoundTrip(t *testing.T) {
+	want := Metadata{Input: "hi", Output: "hello", Outputs: []string{"hello", "hey"}, Dimension: 384, PairID: 7, CreatedAt: 1700000000, InputLen: 2, OutputLen: 5, Intent: "greeting"}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Metadata
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %+v, want %+v", got, want)
+	}
+}
+
+func TestMetadataFloatNumbers(t *testing.T) {
+	// Pinecone returns stored numbers as floats
+	data := `{"input":"hi","output":"hello","dimension":384.0,"pair_id":7.0,"created_at":1.7e9,"input_len":2.0,"output_len":5.0}`
+	var got Metadata
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Dimension != 384 || got.PairID != 7 || got.CreatedAt != 1700000000 || got.Input != "hi" {
+		t.Errorf("decoded %+v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package ragbot
+
+import (
+	"errors"
+	"strings"
+)
+
+// Problems found in a pair's content. Pairs with any of them are rejected by Index
+// and reported by the diagnose command.
+var (
+	ErrMissingField     = errors.New("missing input or output")
+	ErrSameInputOutput  = errors.New("input and output are the same")
+	ErrConcatenatedPair = errors.New(`looks like a concatenated "Similar Input: ... System Response: ..." string`)
+)
+
+// Check a pair for known corruption, returning nil if it looks fine
+func CheckPair(pair InputOutputPair) error {
+	input, output := strings.TrimSpace(pair.Input), strings.TrimSpace(pair.Output)
+	switch {
+	case input == "" || output == "" || hasEmptyOutput(pair.Outputs):
+		return ErrMissingField
+	case isConcatenated(input) || isConcatenated(output):
+		return ErrConcatenatedPair
+	case input == output:
+		return ErrSameInputOutput
+	}
+	return nil
+}
+
+func hasEmptyOutput(outputs []string) bool {
+	for _, o := range outputs {
+		if strings.TrimSpace(o) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether text is the output of an old formatting bug that glued the
+// "Similar Input:" and "System Response:" labels of a search result together
+func isConcatenated(text string) bool {
+	return strings.Contains(text, "Similar Input:") && strings.Contains(text, "System Response:")
+}
@@ -0,0 +1,28 @@
+package ragbot
+
+import "testing"
+
+func TestCheckPair(t *testing.T) {
+	tests := []struct {
+		name string
+		pair InputOutputPair
+		want error
+	}{
+		{"valid", InputOutputPair{Input: "Cancel my ride", Output: "Your ride has been cancelled."}, nil},
+		{"missing input", InputOutputPair{Input: "", Output: "Your ride has been cancelled."}, ErrMissingField},
+		{"missing output", InputOutputPair{Input: "Cancel my ride", Output: "  "}, ErrMissingField},
+		{"same input and output", InputOutputPair{Input: "Cancel my ride", Output: "Cancel my ride"}, ErrSameInputOutput},
+		{"same after trimming", InputOutputPair{Input: "yes ", Output: " yes"}, ErrSameInputOutput},
+		{"concatenated labels", InputOutputPair{Input: "Similar Input: System Response:", Output: "Similar Input: System Response:"}, ErrConcatenatedPair},
+		{"concatenated output", InputOutputPair{Input: "Book", Output: "Similar Input: Book\nSystem Response: When?"}, ErrConcatenatedPair},
+		{"label word alone is fine", InputOutputPair{Input: "What is a System Response:", Output: "The bot's reply."}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPair(tt.pair); got != tt.want {
+				t.Errorf("CheckPair(%+v) = %v, want %v", tt.pair, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package ragbot
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Number of leading and trailing components of each embedding shown in verbose mode
+const verboseVectorEdge = 5
+
+// Placeholder logged instead of credentials
+const redacted = "[REDACTED]"
+
+// Log raw embeddings and request payloads (with credentials redacted) for debugging
+func (b *Bot) SetVerbose(verbose bool) {
+	b.verbose = verbose
+}
+
+// Request URL with the Gemini "key" parameter redacted
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	q := u.Query()
+	if q.Has("key") {
+		q.Set("key", redacted)
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// Whether a header carries credentials: Api-Key, Authorization, Proxy-Authorization,
+// cookies, and anything named like a key, token or secret
+func sensitiveHeader(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range []string{"key", "auth", "token", "secret", "cookie", "password"} {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Request headers with Api-Key and any other credentials redacted
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name := range h {
+		if sensitiveHeader(name) {
+			out[name] = redacted
+		} else {
+			out[name] = h.Get(name)
+		}
+	}
+	return out
+}
+
+// In verbose mode, log a request about to be sent with its serialized body
+func (b *Bot) logRequest(req *http.Request, body []byte) {
+	if !b.verbose {
+		return
+	}
+	b.log.Info("🔬 request", "method", req.Method, "url", redactURL(req.URL.String()),
+		"headers", redactHeaders(req.Header), "payload", string(body))
+}
+
+// In verbose mode, log the edges of an embedding returned by Gemini
+func (b *Bot) logEmbedding(dimension int, taskType string, values []float32) {
+	if !b.verbose {
+		return
+	}
+	head, tail := values, []float32(nil)
+	if len(values) > 2*verboseVectorEdge {
+		head, tail = values[:verboseVectorEdge], values[len(values)-verboseVectorEdge:]
+	}
+	b.log.Info("🔬 embedding", "dim", dimension, "task_type", taskType, "len", len(values), "first", head, "last", tail)
+}
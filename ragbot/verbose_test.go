@@ -0,0 +1,91 @@
+package ragbot
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVerboseLogsRedactCredentials(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"matches":[]}`))
+		},
+	)
+	var logs bytes.Buffer
+	b.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	b.SetVerbose(true)
+
+	if _, err := b.searchSimilar(context.Background(), "cancel my ride", 384, 3, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := logs.String()
+	for _, secret := range []string{b.cfg.GeminiAPIKey, b.cfg.PineconeAPIKey} {
+		if strings.Contains(out, secret) {
+			t.Errorf("verbose logs leak credential %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, redacted) {
+		t.Errorf("expected redacted credentials in verbose logs:\n%s", out)
+	}
+	if !strings.Contains(out, "topK") || !strings.Contains(out, "first") {
+		t.Errorf("expected the Pinecone payload and embedding values in verbose logs:\n%s", out)
+	}
+}
+
+func TestVerboseOffByDefault(t *testing.T) {
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"embedding":{"values":[0.1,0.2,0.3]}}`))
+		},
+		failPinecone(t),
+	)
+	var logs bytes.Buffer
+	b.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	b.getEmbedding(context.Background(), "cancel my ride", 3, TaskRetrievalQuery)
+	if strings.Contains(logs.String(), "🔬") {
+		t.Errorf("verbose output logged while verbose is off:\n%s", logs.String())
+	}
+}
+
+func TestOutboundHeaders(t *testing.T) {
+	var geminiUA, proxyAuth, pineconeUA, pineconeExtra string
+	b := newTestBot(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			geminiUA, proxyAuth = r.Header.Get("User-Agent"), r.Header.Get("Proxy-Authorization")
+			w.Write(embeddingBody(384))
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			pineconeUA, pineconeExtra = r.Header.Get("User-Agent"), r.Header.Get("X-Team")
+			w.Write([]byte(`{"matches":[]}`))
+		},
+	)
+	b.cfg.GeminiHeaders = map[string]string{"Proxy-Authorization": "Bearer proxy-secret"}
+	b.cfg.PineconeHeaders = map[string]string{"X-Team": "support"}
+	var logs bytes.Buffer
+	b.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	b.SetVerbose(true)
+
+	if _, err := b.searchSimilar(context.Background(), "cancel my ride", 384, 3, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "chatbot-rag-go/" + Version
+	if geminiUA != want || pineconeUA != want {
+		t.Errorf("User-Agent = %q (Gemini), %q (Pinecone), want %q", geminiUA, pineconeUA, want)
+	}
+	if proxyAuth != "Bearer proxy-secret" || pineconeExtra != "support" {
+		t.Errorf("extra headers not sent: Proxy-Authorization %q, X-Team %q", proxyAuth, pineconeExtra)
+	}
+	if strings.Contains(logs.String(), "proxy-secret") {
+		t.Errorf("verbose logs leak the proxy credential:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+)
+
+// Fill a new dimension's index by re-embedding the inputs stored in an existing one
+func runReindex(args []string) {
+	fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+	from := fs.Int("from", 1024, "dimension of the existing index to read from")
+	to := fs.Int("to", 0, "dimension of the index to populate (required)")
+	parseFlags(fs, args)
+
+	if *to == 0 {
+		logger.Error("--to is required")
+		os.Exit(2)
+	}
+	if !loadConfig() {
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := bot.Reindex(ctx, *from, *to)
+	if report != nil {
+		logger.Info("📊 Reindex summary", "scanned", report.Scanned, "uploaded", report.Uploaded, "skipped", report.Skipped)
+		d := report.Metrics.Dimension(*to)
+		logger.Info("💰 Embedding usage", "dim", *to, "calls", d.EmbeddingCalls, "failed", d.FailedCalls,
+			"chars", d.CharsEmbedded, "est_tokens", d.EstimatedTokens(), "est_cost_usd", d.EstimatedCost())
+	}
+	if err != nil {
+		logger.Error("reindex failed", "err", err)
+		os.Exit(1)
+	}
+	logger.Info("🎉 Reindex complete", "from", *from, "to", *to)
+}
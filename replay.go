@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+)
+
+// A query answered by the server, as logged under queryLogMessage
+type loggedQuery struct {
+	Query  string            `json:"query"`
+	Vars   map[string]string `json:"vars,omitempty"`
+	Answer string            `json:"answer"`
+}
+
+// A replayed query whose answer differs from the logged one
+type replayChange struct {
+	loggedQuery
+	NewAnswer string `json:"new_answer"`
+}
+
+type replayReport struct {
+	Changed   []replayChange `json:"changed"`
+	Unchanged int            `json:"unchanged"`
+	Failed    int            `json:"failed"`
+}
+
+// key=value pairs of a log line written by slog's text handler. Quoted values are
+// unquoted; parsing stops at anything malformed.
+func parseLogfmt(line string) map[string]string {
+	fields := map[string]string{}
+	for {
+		line = strings.TrimLeft(line, " ")
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 {
+			return fields
+		}
+		key, rest := line[:eq], line[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return fields
+			}
+			value, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+		} else {
+			end := strings.IndexByte(rest, ' ')
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		fields[key] = value
+		line = rest
+	}
+}
+
+// The query in a log line, from either --log-format; false for other lines
+func parseQueryLogLine(line string) (loggedQuery, bool) {
+	fields := map[string]string{}
+	if strings.HasPrefix(line, "{") {
+		var entry map[string]interface{}
+		if json.Unmarshal([]byte(line), &entry) != nil {
+			return loggedQuery{}, false
+		}
+		for k, v := range entry {
+			if s, ok := v.(string); ok {
+				fields[k] = s
+			}
+		}
+	} else {
+		fields = parseLogfmt(line)
+	}
+	if fields["msg"] != queryLogMessage {
+		return loggedQuery{}, false
+	}
+
+	q := loggedQuery{Query: fields["query"], Answer: fields["answer"]}
+	if vars := fields["vars"]; vars != "" {
+		if json.Unmarshal([]byte(vars), &q.Vars) != nil {
+			return loggedQuery{}, false
+		}
+	}
+	return q, q.Query != ""
+}
+
+// Logged queries in r, the latest entry for each query and variables, in the order
+// each was first asked
+func readQueryLog(r io.Reader, queries []loggedQuery, seen map[string]int) ([]loggedQuery, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		q, ok := parseQueryLogLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		key := queryCacheKey(queryRequest{Query: q.Query, Variables: q.Vars})
+		if i, ok := seen[key]; ok {
+			queries[i] = q
+			continue
+		}
+		seen[key] = len(queries)
+		queries = append(queries, q)
+	}
+	return queries, scanner.Err()
+}
+
+// Re-run every query the server logged and flag the ones whose answer changed, e.g.
+// after tuning thresholds or re-embedding. Exits 1 when any answer changed.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: chatbot replay [flags] <server log file>... (- for stdin)")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var queries []loggedQuery
+	seen := map[string]int{}
+	for _, name := range fs.Args() {
+		r, err := openQueries(name)
+		if err != nil {
+			logger.Error("replay failed", "err", err)
+			os.Exit(1)
+		}
+		queries, err = readQueryLog(r, queries, seen)
+		r.Close()
+		if err != nil {
+			logger.Error("failed to read log", "file", name, "err", err)
+			os.Exit(1)
+		}
+	}
+	if len(queries) == 0 {
+		logger.Warn("no logged queries found; the server logs each answer as " + strconv.Quote(queryLogMessage))
+		return
+	}
+
+	// Logged queries were answered by the server, so answer them again the way it would
+	useServeDimensions()
+	if !loadConfig() {
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger.Info("🔁 Replaying logged queries", "queries", len(queries))
+	report := replayReport{Changed: []replayChange{}}
+	for _, q := range queries {
+		if ctx.Err() != nil {
+			break
+		}
+		resp, err := bot.AnswerWith(ctx, q.Query, q.Vars)
+		if err != nil {
+			logger.Warn("replayed query failed", "query", q.Query, "err", err)
+			report.Failed++
+			continue
+		}
+		if resp.Answer == q.Answer {
+			report.Unchanged++
+			continue
+		}
+		report.Changed = append(report.Changed, replayChange{loggedQuery: q, NewAnswer: resp.Answer})
+	}
+
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		for _, c := range report.Changed {
+			fmt.Printf("~ %s\n  - %s\n  + %s\n", c.Query, c.Answer, c.NewAnswer)
+		}
+		fmt.Printf("%d changed, %d unchanged, %d failed\n", len(report.Changed), report.Unchanged, report.Failed)
+	}
+	if len(report.Changed) > 0 {
+		os.Exit(1)
+	}
+}
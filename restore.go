@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"geminivectortest/ragbot"
+)
+
+// Vectors per upsert request when restoring a snapshot
+const restoreBatchSize = 100
+
+// Upload the vectors of a snapshot written by upload --snapshot to their indexes,
+// without calling Gemini
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	onlyDim := fs.Int("dim", 0, "only restore vectors of this dimension (0 restores every configured dimension)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: chatbot restore [flags] <snapshot.jsonl.gz>")
+		fs.PrintDefaults()
+	}
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if !loadConfig() {
+		return
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		logger.Error("failed to open snapshot", "err", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	batches := map[int][]ragbot.Vector{}
+	uploaded, skipped := map[int]int{}, map[int]int{}
+	flush := func(dim int) error {
+		if len(batches[dim]) == 0 {
+			return nil
+		}
+		if err := bot.Upsert(ctx, batches[dim], dim); err != nil {
+			return fmt.Errorf("dimension %d: %v", dim, err)
+		}
+		uploaded[dim] += len(batches[dim])
+		batches[dim] = batches[dim][:0]
+		return nil
+	}
+	header, err := ragbot.ReadSnapshot(f, func(v ragbot.Vector) error {
+		dim := len(v.Values)
+		if _, ok := cfg.Indexes[dim]; !ok || (*onlyDim != 0 && dim != *onlyDim) {
+			skipped[dim]++
+			return nil
+		}
+		batches[dim] = append(batches[dim], v)
+		if len(batches[dim]) >= restoreBatchSize {
+			return flush(dim)
+		}
+		return nil
+	})
+	if err == nil {
+		for _, dim := range cfg.Dimensions() {
+			if err = flush(dim); err != nil {
+				break
+			}
+		}
+	}
+	if header != nil {
+		logger.Info("📦 Snapshot", "created", header.Created, "embedding_model", header.EmbeddingModel)
+	}
+	for dim, n := range skipped {
+		logger.Warn("skipped vectors of a dimension not being restored", "dim", dim, "vectors", n)
+	}
+	for _, dim := range cfg.Dimensions() {
+		if uploaded[dim] > 0 {
+			logger.Info("✅ Restored vectors", "dim", dim, "index", cfg.Indexes[dim], "vectors", uploaded[dim])
+		}
+	}
+	if err != nil {
+		logger.Error("restore failed", "err", err)
+		os.Exit(1)
+	}
+	logger.Info("🎉 Restore complete")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"geminivectortest/ragbot"
+)
+
+// Number of histogram buckets printed by --score-stats
+const scoreHistogramBins = 10
+
+// Scores seen across a test or batch run, collected when --score-stats is set
+var (
+	scoreStats      bool
+	collectedScores []float32
+)
+
+// Remember a response's scores for the end-of-run distribution
+func recordScores(resp *ragbot.Response) {
+	if scoreStats {
+		collectedScores = append(collectedScores, resp.Scores()...)
+	}
+}
+
+// Print a summary and text histogram of every score collected during the run
+func printScoreDistribution(w io.Writer) {
+	s := ragbot.SummarizeScores(collectedScores)
+	fmt.Fprintln(w, "📈 Score distribution")
+	if s.Count == 0 {
+		fmt.Fprintln(w, "   no scores collected")
+		return
+	}
+	fmt.Fprintf(w, "   matches=%d min=%.3f max=%.3f mean=%.3f\n", s.Count, s.Min, s.Max, s.Mean)
+
+	width := (s.Max - s.Min) / scoreHistogramBins
+	counts := make([]int, scoreHistogramBins)
+	for _, score := range collectedScores {
+		bin := scoreHistogramBins - 1
+		if width > 0 {
+			bin = min(int((float64(score)-s.Min)/width), scoreHistogramBins-1)
+		}
+		counts[bin]++
+	}
+
+	most := 0
+	for _, c := range counts {
+		most = max(most, c)
+	}
+	for i, c := range counts {
+		lo := s.Min + float64(i)*width
+		bar := strings.Repeat("█", (c*40+most-1)/most)
+		fmt.Fprintf(w, "   %.3f-%.3f | %-40s %d\n", lo, lo+width, bar, c)
+	}
+}
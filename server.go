@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
+
+	"geminivectortest/ragbot"
+)
+
+// Delay between streamed chunks, simulating an LLM typing out its answer
+const streamChunkDelay = 30 * time.Millisecond
+
+// Body of POST /query and POST /query/stream
+type queryRequest struct {
+	Query     string            `json:"query"`
+	Variables map[string]string `json:"variables"` // template variables for the answer
+}
+
+// Read the query from a JSON body ({"query": "...", "variables": {...}}) or the q URL parameter
+func readQuery(r *http.Request) (queryRequest, error) {
+	if q := r.URL.Query().Get("q"); q != "" {
+		return queryRequest{Query: q}, nil
+	}
+	if r.Method != http.MethodPost {
+		return queryRequest{}, fmt.Errorf("missing q parameter")
+	}
+	var body queryRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return queryRequest{}, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	if strings.TrimSpace(body.Query) == "" {
+		return queryRequest{}, fmt.Errorf("query must not be empty")
+	}
+	return body, nil
+}
+
+// Server answer cache, nil when disabled with --cache-size 0
+var answerCache *queryCache
+
+// Log message of the entry written for every answered query, read back by "replay"
+const queryLogMessage = "💬 Query answered"
+
+// Answer a request and log the query with its answer for "replay"
+func answer(ctx context.Context, req queryRequest) (*ragbot.Response, error) {
+	resp, err := cachedAnswer(ctx, req)
+	if err == nil {
+		attrs := []any{"query", req.Query, "answer", resp.Answer, "confident", resp.Confident}
+		if len(req.Variables) > 0 {
+			vars, _ := json.Marshal(req.Variables)
+			attrs = append(attrs, "vars", string(vars))
+		}
+		logger.Info(queryLogMessage, attrs...)
+	}
+	return resp, err
+}
+
+// Answer a request, from answerCache when possible. Responses with failed dimensions
+// aren't cached so a transient error isn't repeated until the entry expires.
+func cachedAnswer(ctx context.Context, req queryRequest) (*ragbot.Response, error) {
+	if answerCache == nil {
+		return bot.AnswerWith(ctx, req.Query, req.Variables)
+	}
+	key := queryCacheKey(req)
+	if resp, ok := answerCache.get(key); ok {
+		logger.Debug("answer cache hit", "query", req.Query)
+		return resp, nil
+	}
+	resp, err := bot.AnswerWith(ctx, req.Query, req.Variables)
+	if err == nil && len(resp.Errors) == 0 {
+		answerCache.put(key, resp)
+	}
+	return resp, err
+}
+
+// GET /metrics: query, error and latency metrics in the Prometheus text format
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	bot.WritePrometheus(w)
+	if answerCache == nil {
+		return
+	}
+	fmt.Fprintln(w, "# HELP chatbot_answer_cache_hits_total Queries answered from the answer cache.")
+	fmt.Fprintln(w, "# TYPE chatbot_answer_cache_hits_total counter")
+	fmt.Fprintf(w, "chatbot_answer_cache_hits_total %d\n", answerCache.hits.Load())
+	fmt.Fprintln(w, "# HELP chatbot_answer_cache_misses_total Queries not found in the answer cache.")
+	fmt.Fprintln(w, "# TYPE chatbot_answer_cache_misses_total counter")
+	fmt.Fprintf(w, "chatbot_answer_cache_misses_total %d\n", answerCache.misses.Load())
+	fmt.Fprintln(w, "# HELP chatbot_answer_cache_entries Answers currently held in the cache.")
+	fmt.Fprintln(w, "# TYPE chatbot_answer_cache_entries gauge")
+	fmt.Fprintf(w, "chatbot_answer_cache_entries %d\n", answerCache.len())
+}
+
+// GET /healthz: liveness, with the dimensions queries are answered from. With a fallback
+// chain they are its dimensions in priority order, also listed under fallback_chain.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	health := map[string]interface{}{
+		"status":         "ok",
+		"version":        ragbot.Version,
+		"dimensions":     cfg.SearchDimensions(),
+		"auto_dimension": cfg.AutoDimension.Enabled,
+	}
+	if len(cfg.FallbackChain) > 0 {
+		health["dimensions"] = cfg.FallbackChain
+		health["fallback_chain"] = cfg.FallbackChain
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+// Write v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ResponseWriter noting whether the response was started, so a recovered panic only
+// writes an error response when nothing was sent yet
+type trackingWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *trackingWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *trackingWriter) Write(p []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *trackingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		f.Flush()
+	}
+}
+
+// Recover from a panic in next, e.g. a nil dereference on a malformed Pinecone response,
+// logging it with the stack and answering 500 instead of crashing the server.
+// http.ErrAbortHandler is re-raised so net/http aborts the response as intended.
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &trackingWriter{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logger.Error("handler panicked", "method", r.Method, "path", r.URL.Path, "panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+			if !tw.started {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			}
+		}()
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// POST /query: answer a query as one JSON object
+func handleQuery(w http.ResponseWriter, r *http.Request) {
+	req, err := readQuery(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	resp, err := answer(r.Context(), req)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, answerJSON(resp))
+}
+
+// Split an answer into word chunks, each keeping its trailing whitespace so the
+// chunks concatenate back to the original text
+func streamChunks(text string) []string {
+	var chunks []string
+	start := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == ' ' || text[i] == '\n' {
+			// Keep runs of whitespace with the word before them
+			for i+1 < len(text) && (text[i+1] == ' ' || text[i+1] == '\n') {
+				i++
+			}
+			chunks = append(chunks, text[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		chunks = append(chunks, text[start:])
+	}
+	return chunks
+}
+
+// Write one server-sent event and flush it to the client
+func writeEvent(w http.ResponseWriter, f http.Flusher, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+		return err
+	}
+	f.Flush()
+	return nil
+}
+
+// GET or POST /query/stream: stream the answer as text/event-stream, the way a streaming
+// LLM API would. Each chunk is a data event {"delta": "..."}; a "done" event carries the
+// full response, and an "error" event replaces it when the search fails.
+func handleQueryStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	req, err := readQuery(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	resp, err := answer(ctx, req)
+	if err != nil {
+		writeEvent(w, flusher, "error", map[string]string{"error": err.Error()})
+		return
+	}
+
+	for _, chunk := range streamChunks(resp.Answer) {
+		if err := writeEvent(w, flusher, "", map[string]string{"delta": chunk}); err != nil {
+			// Client went away
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(streamChunkDelay):
+		}
+	}
+
+	writeEvent(w, flusher, "done", answerJSON(resp))
+}
+
+// Machine-readable answer returned by the server
+type AnswerJSON struct {
+	Query        string              `json:"query"`
+	Answer       string              `json:"answer"`
+	Confident    bool                `json:"confident"`
+	Ambiguous    bool                `json:"ambiguous"`
+	Matches      []JSONMatch         `json:"matches"`
+	Alternatives []JSONMatch         `json:"alternatives,omitempty"`
+	ScoreStats   ragbot.ScoreSummary `json:"score_stats"`
+	Errors       map[int]string      `json:"errors,omitempty"`
+}
+
+func answerJSON(resp *ragbot.Response) AnswerJSON {
+	return AnswerJSON{
+		Query:        resp.Query,
+		Answer:       resp.Answer,
+		Confident:    resp.Confident,
+		Ambiguous:    resp.Ambiguous,
+		Matches:      jsonMatches(resp.Matches),
+		Alternatives: jsonMatches(resp.Alternatives),
+		ScoreStats:   ragbot.SummarizeScores(resp.Scores()),
+		Errors:       jsonErrors(resp.Errors),
+	}
+}
+
+// Keep connections to Gemini and Pinecone warm by calling bot.Warm every interval until ctx ends
+func keepWarm(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		warmCtx, cancel := context.WithTimeout(ctx, interval)
+		start := time.Now()
+		if err := bot.Warm(warmCtx); err != nil && ctx.Err() == nil {
+			logger.Warn("warm-up failed", "err", err)
+		} else {
+			logger.Debug("connections warmed", "elapsed", time.Since(start))
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Search serve_dimensions when set, as the server does, leaving query_dimensions to the CLI.
+// Call before loadConfig.
+func useServeDimensions() {
+	configOverrides = append(configOverrides, func(c *ragbot.Config) {
+		if len(c.ServeDimensions) > 0 {
+			c.QueryDimensions = c.ServeDimensions
+		}
+	})
+}
+
+// Serve answers over HTTP until interrupted
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen address")
+	warmupInterval := fs.Duration("warmup-interval", 0, "ping Gemini and Pinecone this often to keep connections warm, e.g. 45s (0 disables)")
+	cacheSize := fs.Int("cache-size", 1000, "answers kept in the in-memory answer cache (0 disables)")
+	cacheTTL := fs.Duration("cache-ttl", 5*time.Minute, "how long a cached answer is served before querying again")
+	parseFlags(fs, args)
+
+	useServeDimensions()
+	if !loadConfig() {
+		return
+	}
+	if *cacheSize > 0 && *cacheTTL > 0 {
+		answerCache = newQueryCache(*cacheSize, *cacheTTL)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", handleQuery)
+	mux.HandleFunc("/query/stream", handleQueryStream)
+	mux.HandleFunc("/metrics", handleMetrics)
+	mux.HandleFunc("/healthz", handleHealthz)
+	srv := &http.Server{Addr: *addr, Handler: recoverPanics(mux)}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	// Idle keep-alive connections close after 90s, so an interval below that keeps them open
+	if *warmupInterval > 0 {
+		go keepWarm(ctx, *warmupInterval)
+	}
+
+	logger.Info("🌐 Serving queries", "addr", *addr, "dimensions", cfg.SearchDimensions())
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Error("server failed", "err", err)
+		os.Exit(1)
+	}
+}
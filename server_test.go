@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"geminivectortest/ragbot"
+)
+
+// Point bot at fake Gemini and Pinecone servers; queries counts Pinecone searches
+func useFakeServices(t *testing.T, queries *atomic.Int32) {
+	t.Helper()
+	useServices(t, fakeGemini, func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		w.Write([]byte(`{"matches":[{"id":"cab","score":0.9,"metadata":{"input":"Where is my cab","output":"On its way"}}]}`))
+	})
+}
+
+// Fake Gemini answering every request with an all-ones embedding of the requested size
+func fakeGemini(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		OutputDimensionality int `json:"outputDimensionality"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	values := make([]float32, req.OutputDimensionality)
+	for i := range values {
+		values[i] = 1
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"embedding": map[string]interface{}{"values": values}})
+}
+
+// Point bot at test servers running the given Gemini and Pinecone handlers
+func useServices(t *testing.T, gemini, pinecone http.HandlerFunc) {
+	t.Helper()
+	g := httptest.NewServer(gemini)
+	p := httptest.NewServer(pinecone)
+	t.Cleanup(func() {
+		g.Close()
+		p.Close()
+	})
+
+	c := ragbot.DefaultConfig()
+	c.GeminiAPIKey, c.PineconeAPIKey = "test-gemini-key", "test-pinecone-key"
+	c.GeminiBaseURL, c.PineconeBaseURL = g.URL, p.URL
+	b, err := ragbot.New(c)
+	if err != nil {
+		t.Fatalf("ragbot.New: %v", err)
+	}
+	bot = b
+}
+
+func TestAnswerCacheMissThenHit(t *testing.T) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	var queries atomic.Int32
+	useFakeServices(t, &queries)
+	answerCache = newQueryCache(10, time.Minute)
+	t.Cleanup(func() { answerCache = nil })
+
+	first, err := answer(context.Background(), queryRequest{Query: "Where is my cab"})
+	if err != nil {
+		t.Fatalf("miss: %v", err)
+	}
+	searched := queries.Load()
+	if searched == 0 || first.Best == nil || first.Best.Output != "On its way" {
+		t.Fatalf("miss: %d Pinecone queries, best %+v; want the match from Pinecone", searched, first.Best)
+	}
+
+	second, err := answer(context.Background(), queryRequest{Query: "  where IS my cab "})
+	if err != nil {
+		t.Fatalf("hit: %v", err)
+	}
+	if queries.Load() != searched || second != first {
+		t.Errorf("hit searched Pinecone again (%d queries, was %d) or returned a different response", queries.Load(), searched)
+	}
+	if answerCache.hits.Load() != 1 || answerCache.misses.Load() != 1 {
+		t.Errorf("hits %d, misses %d; want 1 and 1", answerCache.hits.Load(), answerCache.misses.Load())
+	}
+}
+
+func TestRecoverPanicsKeepsServing(t *testing.T) {
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		var resp *struct{ Answer string }
+		w.Write([]byte(resp.Answer)) // nil dereference
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	srv := httptest.NewServer(recoverPanics(mux))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/panic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]string
+	json.NewDecoder(resp.Body).Decode(&body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError || body["error"] == "" {
+		t.Errorf("panicking handler: status %d, body %v, want 500 with an error", resp.StatusCode, body)
+	}
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL + "/ok")
+		if err != nil {
+			t.Fatalf("server stopped serving after a panic: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("after a panic: status %d, want 200", resp.StatusCode)
+		}
+	}
+}
+
+func TestHealthzReportsFallbackChain(t *testing.T) {
+	c := ragbot.DefaultConfig()
+	cfg = c
+	t.Cleanup(func() { cfg = nil })
+	health := func() map[string]interface{} {
+		rec := httptest.NewRecorder()
+		handleHealthz(rec, httptest.NewRequest("GET", "/healthz", nil))
+		var body map[string]interface{}
+		json.NewDecoder(rec.Body).Decode(&body)
+		return body
+	}
+
+	if body := health(); body["fallback_chain"] != nil {
+		t.Errorf("healthz without a chain reported %v", body["fallback_chain"])
+	}
+	c.FallbackChain = []int{1024, 384}
+	body := health()
+	if fmt.Sprint(body["fallback_chain"]) != "[1024 384]" || fmt.Sprint(body["dimensions"]) != "[1024 384]" {
+		t.Errorf("healthz dimensions %v, fallback_chain %v; want the chain in priority order", body["dimensions"], body["fallback_chain"])
+	}
+}
+
+func TestUseServeDimensions(t *testing.T) {
+	saved := configOverrides
+	t.Cleanup(func() { configOverrides = saved })
+	configOverrides = nil
+	useServeDimensions()
+
+	c := ragbot.DefaultConfig()
+	c.QueryDimensions, c.ServeDimensions = []int{1024}, []int{384}
+	for _, override := range configOverrides {
+		override(c)
+	}
+	if !reflect.DeepEqual(c.QueryDimensions, []int{384}) {
+		t.Errorf("query dimensions %v, want serve_dimensions [384]", c.QueryDimensions)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"geminivectortest/ragbot"
+)
+
+// --since and --until for commands that walk stored vectors
+type timeRangeFlags struct {
+	since *string
+	until *string
+}
+
+func addTimeRangeFlags(fs *flag.FlagSet) *timeRangeFlags {
+	return &timeRangeFlags{
+		since: fs.String("since", "", "only vectors created at or after this time: RFC 3339, YYYY-MM-DD, a duration ago like 24h, or last-run"),
+		until: fs.String("until", "", "only vectors created before this time, in the same formats as --since"),
+	}
+}
+
+// The range the flags select; empty when neither is set. "--since last-run" covers the
+// most recent upload recorded in output_logs.
+func (f *timeRangeFlags) resolve() (ragbot.TimeRange, error) {
+	var r ragbot.TimeRange
+	now := time.Now()
+	if *f.since == "last-run" {
+		run, err := lastRunRange()
+		if err != nil {
+			return r, err
+		}
+		r = run
+	} else if *f.since != "" {
+		t, err := ragbot.ParseTimeBound(*f.since, now)
+		if err != nil {
+			return r, fmt.Errorf("--since: %v", err)
+		}
+		r.Since = t
+	}
+	if *f.until != "" {
+		t, err := ragbot.ParseTimeBound(*f.until, now)
+		if err != nil {
+			return r, fmt.Errorf("--until: %v", err)
+		}
+		r.Until = t
+	}
+	return r, nil
+}
+
+// Start and end of the newest upload with a JSON processing log
+func lastRunRange() (ragbot.TimeRange, error) {
+	files, _ := filepath.Glob("output_logs/processing_log_*.json")
+	if len(files) == 0 {
+		return ragbot.TimeRange{}, fmt.Errorf("no processing logs in output_logs to find the last run")
+	}
+	// processing_log_<unix>.json sorts by time while the timestamps have the same length
+	sort.Strings(files)
+	newest := files[len(files)-1]
+
+	data, err := os.ReadFile(newest)
+	if err != nil {
+		return ragbot.TimeRange{}, fmt.Errorf("failed to read %s: %v", newest, err)
+	}
+	var record ProcessingLog
+	if err := json.Unmarshal(data, &record); err != nil {
+		return ragbot.TimeRange{}, fmt.Errorf("failed to parse %s: %v", newest, err)
+	}
+	if record.Started.IsZero() {
+		return ragbot.TimeRange{}, fmt.Errorf("%s doesn't record when the run started", newest)
+	}
+	// created_at has whole seconds, so extend the end past the log's own second
+	return ragbot.TimeRange{Since: record.Started.Truncate(time.Second), Until: record.Timestamp.Add(time.Second)}, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"geminivectortest/ragbot"
+)
+
+// Distinct stored inputs in one index, optionally only those with an intent. With limit > 0
+// a uniform random sample of that many is kept while scanning (reservoir sampling), so the
+// whole index is never held in memory; limit 0 returns every input.
+func suggestInputs(ctx context.Context, dimension int, intent string, limit int) ([]string, error) {
+	var picked []string
+	seen := map[string]bool{}
+	err := bot.Scan(ctx, dimension, func(page []ragbot.Vector) error {
+		for _, v := range page {
+			input := strings.TrimSpace(v.Metadata.Input)
+			if input == "" || seen[input] {
+				continue
+			}
+			if intent != "" && !strings.EqualFold(v.Metadata.Intent, intent) {
+				continue
+			}
+			seen[input] = true
+			switch {
+			case limit == 0 || len(picked) < limit:
+				picked = append(picked, input)
+			default:
+				// Keep each of the len(seen) inputs so far with probability limit/len(seen)
+				if i := rand.Intn(len(seen)); i < limit {
+					picked[i] = input
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(picked)
+	return picked, nil
+}
+
+// List example questions the bot knows, e.g. for a "try asking..." prompt
+func runSuggest(args []string) {
+	fs := flag.NewFlagSet("suggest", flag.ExitOnError)
+	dim := fs.Int("dim", 1024, "dimension of the index to read inputs from")
+	limit := fs.Int("limit", 10, "number of inputs to sample (0 lists all)")
+	intent := fs.String("intent", "", "only suggest inputs stored with this intent")
+	fs.BoolVar(&jsonOutput, "json", false, "print the inputs as a JSON array")
+	parseFlags(fs, args)
+
+	if !loadConfig() {
+		return
+	}
+	if _, ok := cfg.Indexes[*dim]; !ok {
+		logger.Error("no index configured for dimension", "dim", *dim, "indexes", cfg.Indexes)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	inputs, err := suggestInputs(ctx, *dim, *intent, *limit)
+	if err != nil {
+		logger.Error("suggest failed", "err", err)
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		if inputs == nil {
+			inputs = []string{}
+		}
+		data, _ := json.MarshalIndent(inputs, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if len(inputs) == 0 {
+		fmt.Println("No stored inputs found")
+		return
+	}
+	fmt.Println("💡 Try asking:")
+	for _, input := range inputs {
+		fmt.Printf("  • %s\n", input)
+	}
+}